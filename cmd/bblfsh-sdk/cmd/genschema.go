@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/bblfsh/sdk/v3/uast/uastschema"
+)
+
+const GenSchemaCommandDescription = "" +
+	"Generate JSON Schema or protobuf definitions for registered UAST types"
+
+type GenSchemaCommand struct {
+	Args struct {
+		Namespaces []string `positional-arg-name:"namespace(s)" description:"UAST namespace(s) to export; all registered namespaces by default"`
+	} `positional-args:"yes"`
+	Format  string `long:"format" short:"f" default:"json" choice:"json" choice:"proto" description:"Output format"`
+	Package string `long:"package" short:"p" default:"uast" description:"Name of the protobuf package"`
+	Output  string `long:"out" short:"o" description:"Output file; stdout by default"`
+}
+
+func (c *GenSchemaCommand) Execute(args []string) error {
+	return writeGenerated(c.Output, func(w io.Writer) error {
+		switch c.Format {
+		case "json":
+			return uastschema.WriteJSONSchema(w, c.Args.Namespaces...)
+		case "proto":
+			return uastschema.WriteProto(w, c.Package, c.Args.Namespaces...)
+		}
+		return fmt.Errorf("unsupported format: %q", c.Format)
+	})
+}
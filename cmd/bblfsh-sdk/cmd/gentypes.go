@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/bblfsh/sdk/v3/uast/uastgen"
+)
+
+const GenTypesCommandDescription = "" +
+	"Generate typed Go accessors for registered UAST types"
+
+type GenTypesCommand struct {
+	Args struct {
+		Namespaces []string `positional-arg-name:"namespace(s)" description:"UAST namespace(s) to generate; all registered namespaces by default"`
+	} `positional-args:"yes"`
+	Package string `long:"package" short:"p" default:"uastview" description:"Name of the generated Go package"`
+	Output  string `long:"out" short:"o" description:"Output file; stdout by default"`
+}
+
+func (c *GenTypesCommand) Execute(args []string) error {
+	return writeGenerated(c.Output, func(w io.Writer) error {
+		return uastgen.Generate(w, c.Package, c.Args.Namespaces...)
+	})
+}
+
+// writeGenerated runs the generator and writes its output to a given file, or to stdout if the file is not set.
+// Nothing is written if the generator fails.
+func writeGenerated(out string, gen func(w io.Writer) error) error {
+	buf := bytes.NewBuffer(nil)
+	if err := gen(buf); err != nil {
+		return err
+	}
+	if out == "" {
+		_, err := buf.WriteTo(os.Stdout)
+		return err
+	}
+	return ioutil.WriteFile(out, buf.Bytes(), 0644)
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+	"github.com/bblfsh/sdk/v3/uast/nodes/nodesproto"
+	"github.com/bblfsh/sdk/v3/uast/stats"
+)
+
+const StatsCommandDescription = "" +
+	"Read UAST files (yaml, json, msgpack, proto, sexp) and print tree statistics"
+
+type StatsCommand struct {
+	Args struct {
+		Files []string `positional-arg-name:"file(s)" required:"true" description:"File(s) with UAST"`
+	} `positional-args:"yes"`
+	From string `long:"from" description:"Input format (yaml, json, msgpack, proto, sexp); detected from the file extension or the content if not set"`
+	Top  int  `long:"top" short:"n" default:"10" description:"Number of types and subtrees to print"`
+	JSON bool `long:"json" description:"Print statistics in JSON format"`
+}
+
+func (c *StatsCommand) Execute(args []string) error {
+	var last error
+	for _, name := range c.Args.Files {
+		if err := c.processFile(name); err != nil {
+			log.Printf("error processing %v: %v", name, err)
+			last = err
+		}
+	}
+	return last
+}
+
+func (c *StatsCommand) processFile(name string) error {
+	ast, err := readUASTFile(name, c.From)
+	if err != nil {
+		return err
+	}
+	st := stats.ComputeWith(ast, stats.Options{Largest: c.Top})
+	if c.JSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			File string `json:"file"`
+			*stats.Stats
+		}{File: name, Stats: st})
+	}
+	return c.printStats(os.Stdout, name, st)
+}
+
+// readUASTFile reads a UAST from a file in a given format. If the format is not set, it is detected from
+// the file extension, or from the file content for unknown extensions: binary graphs are detected by
+// the magic number, and other files are assumed to be in YAML.
+func readUASTFile(name, format string) (nodes.Node, error) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	if f, err := formatOf(name, format); err == nil {
+		format = f
+	} else if nodesproto.IsGraph(data) {
+		format = "proto"
+	} else {
+		format = "yaml"
+	}
+	ast, _, err := decodeUAST(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode %s uast: %v", format, err)
+	}
+	return ast, nil
+}
+
+func (c *StatsCommand) printStats(w io.Writer, name string, st *stats.Stats) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s:\n", name)
+	fmt.Fprintf(tw, "nodes:\t%d\t(objects: %d, arrays: %d, values: %d, nils: %d)\n",
+		st.Nodes, st.Objects, st.Arrays, st.Values, st.Nils)
+	fmt.Fprintf(tw, "max depth:\t%d\n", st.MaxDepth)
+	fmt.Fprintf(tw, "size:\t%d bytes\n", st.Bytes)
+
+	fmt.Fprintln(tw, "\ntypes:\tcount\tbytes")
+	types := make([]string, 0, len(st.Types))
+	for typ := range st.Types {
+		types = append(types, typ)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		a, b := types[i], types[j]
+		if st.Types[a] != st.Types[b] {
+			return st.Types[a] > st.Types[b]
+		}
+		return a < b
+	})
+	if c.Top > 0 && len(types) > c.Top {
+		types = types[:c.Top]
+	}
+	for _, typ := range types {
+		name := typ
+		if name == "" {
+			name = "<none>"
+		}
+		fmt.Fprintf(tw, "  %s\t%d\t%d\n", name, st.Types[typ], st.TypeBytes[typ])
+	}
+
+	fmt.Fprintln(tw, "\nroles:\tcount")
+	roles := make([]string, 0, len(st.Roles))
+	byName := make(map[string]int, len(st.Roles))
+	for r, cnt := range st.Roles {
+		roles = append(roles, r.String())
+		byName[r.String()] = cnt
+	}
+	sort.Strings(roles)
+	for _, r := range roles {
+		fmt.Fprintf(tw, "  %s\t%d\n", r, byName[r])
+	}
+
+	fmt.Fprintln(tw, "\nfan-out:\tcount")
+	fan := make([]int, 0, len(st.FanOut))
+	for n := range st.FanOut {
+		fan = append(fan, n)
+	}
+	sort.Ints(fan)
+	for _, n := range fan {
+		fmt.Fprintf(tw, "  %d\t%d\n", n, st.FanOut[n])
+	}
+
+	if len(st.Largest) != 0 {
+		fmt.Fprintln(tw, "\nlargest subtrees:\tnodes\tbytes\ttype")
+		for _, s := range st.Largest {
+			fmt.Fprintf(tw, "  %q\t%d\t%d\t%s\n", s.Path.String(), s.Nodes, s.Bytes, s.Type)
+		}
+	}
+	fmt.Fprintln(tw)
+	return tw.Flush()
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+	"github.com/bblfsh/sdk/v3/uast/nodes/nodesproto"
+)
+
+func TestReadUASTFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "uast_")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	exp := nodes.Object{
+		"@type": nodes.String("node"),
+		"k":     nodes.Array{nodes.String("a"), nodes.Int(1)},
+	}
+	graph := writeGraph(t, nodesproto.WriteOptions{})
+
+	for _, c := range []struct {
+		name   string
+		format string
+		data   string
+		err    string
+	}{
+		{name: "tree.pb", data: string(graph)},
+		{name: "tree.uast", data: string(graph)},
+		{name: "tree.uast", data: "'@type': node\nk: [a, 1]\n"},
+		{name: "tree.json", data: `{"@type": "node", "k": ["a", 1]}`},
+		{name: "tree.txt", format: "json", data: `{"@type": "node", "k": ["a", 1]}`},
+		{name: "truncated.uast", data: string(graph[:len(graph)-1]), err: "cannot decode proto uast"},
+		{name: "invalid.json", data: `{"@type": `, err: "cannot decode json uast"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			name := filepath.Join(dir, c.name)
+			err := ioutil.WriteFile(name, []byte(c.data), 0644)
+			require.NoError(t, err)
+
+			ast, err := readUASTFile(name, c.format)
+			if c.err != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), c.err)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, nodes.Equal(exp, ast), "%v", ast)
+		})
+	}
+}
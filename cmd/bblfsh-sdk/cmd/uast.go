@@ -0,0 +1,285 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bblfsh/sdk/v3/uast"
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+	"github.com/bblfsh/sdk/v3/uast/nodes/nodesjson"
+	"github.com/bblfsh/sdk/v3/uast/nodes/nodesmsgpack"
+	"github.com/bblfsh/sdk/v3/uast/nodes/nodesproto"
+	"github.com/bblfsh/sdk/v3/uast/uastsexp"
+	"github.com/bblfsh/sdk/v3/uast/uastyaml"
+)
+
+const UASTCommandDescription = "" +
+	"Tools for working with binary UAST files"
+
+type UASTCommand struct{}
+
+const UASTInspectCommandDescription = "" +
+	"Validate binary UAST files and print format and size statistics"
+
+type UASTInspectCommand struct {
+	Args struct {
+		Files []string `positional-arg-name:"file(s)" required:"true" description:"File(s) with UAST in binary format"`
+	} `positional-args:"yes"`
+	Dump bool `long:"dump" description:"Dump the raw graph in JSON format"`
+}
+
+func (c *UASTInspectCommand) Execute(args []string) error {
+	var last error
+	for _, name := range c.Args.Files {
+		if err := c.inspectFile(os.Stdout, name); err != nil {
+			log.Printf("error inspecting %v: %v", name, err)
+			last = err
+		}
+	}
+	return last
+}
+
+func (c *UASTInspectCommand) inspectFile(w io.Writer, name string) error {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	defer tw.Flush()
+
+	fmt.Fprintf(tw, "%s:\n", name)
+	fmt.Fprintf(tw, "size:\t%d bytes\n", len(data))
+	h, err := nodesproto.ReadHeader(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(tw, "version:\t%d\n", h.Version)
+	fmt.Fprintf(tw, "compressed:\t%v\n", h.Compressed)
+
+	g, errs, err := nodesproto.ReadRawLenient(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(tw, "root:\t%d\n", g.Root)
+	fmt.Fprintf(tw, "metadata:\t%d\n", g.Meta)
+	fmt.Fprintf(tw, "last id:\t%d\n", g.Last)
+
+	var objs, arrs, vals int
+	for _, n := range g.Nodes {
+		switch {
+		case n.Kind == nodes.KindObject:
+			objs++
+		case n.Kind == nodes.KindArray:
+			arrs++
+		default:
+			vals++
+		}
+	}
+	fmt.Fprintf(tw, "nodes:\t%d\t(objects: %d, arrays: %d, values: %d)\n", len(g.Nodes), objs, arrs, vals)
+
+	errs = append(errs, g.Validate()...)
+	if len(errs) == 0 {
+		if err := printEncoderStats(tw, data); err != nil {
+			fmt.Fprintf(tw, "error:\t%v\n", err)
+		}
+	}
+	for _, e := range errs {
+		fmt.Fprintf(tw, "problem:\t%v\n", e)
+	}
+	if c.Dump {
+		tw.Flush()
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(g); err != nil {
+			return err
+		}
+	}
+	if len(errs) != 0 {
+		return fmt.Errorf("found %d problem(s) in the graph", len(errs))
+	}
+	return nil
+}
+
+// printEncoderStats decodes the graph and encodes it again to collect the encoder statistics.
+func printEncoderStats(w io.Writer, data []byte) error {
+	dag, err := nodesproto.ReadDAG(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	nodesproto.MapSize, nodesproto.ArrSize, nodesproto.ValSize, nodesproto.DelimSize = 0, 0, 0, 0
+	nodesproto.KeysCnt, nodesproto.KeysFromCnt, nodesproto.DupsCnt = 0, 0, 0
+
+	// write the tree only, so the counters do not include the metadata
+	buf := bytes.NewBuffer(nil)
+	if err = nodesproto.WriteWith(buf, dag, nodesproto.WriteOptions{}); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "re-encoded:\t%d bytes\t(objects: %d, arrays: %d, values: %d, delimiters: %d)\n",
+		buf.Len(), nodesproto.MapSize, nodesproto.ArrSize, nodesproto.ValSize, nodesproto.DelimSize)
+	fmt.Fprintf(w, "object keys:\t%d\t(own: %d, shared: %d)\n",
+		nodesproto.KeysCnt+nodesproto.KeysFromCnt, nodesproto.KeysCnt, nodesproto.KeysFromCnt)
+	fmt.Fprintf(w, "deduplicated:\t%d\n", nodesproto.DupsCnt)
+	return nil
+}
+
+const UASTConvertCommandDescription = "" +
+	"Convert UAST files between formats (yaml, json, msgpack, proto, sexp)"
+
+type UASTConvertCommand struct {
+	Args struct {
+		Input  string `positional-arg-name:"input" required:"true" description:"Input file"`
+		Output string `positional-arg-name:"output" description:"Output file; stdout is used if not set"`
+	} `positional-args:"yes"`
+	From string `long:"from" description:"Input format (yaml, json, msgpack, proto, sexp); detected from the file extension if not set"`
+	To   string `long:"to" description:"Output format (yaml, json, msgpack, proto, sexp); detected from the file extension if not set, or yaml for stdout"`
+
+	Migrate bool `long:"migrate" description:"Upgrade the tree to the current UAST schema version"`
+	Schema  int  `long:"schema" description:"Schema version of the input; if not set, it is read from the metadata of binary files, and the current version is assumed for other formats"`
+}
+
+// schemaVersion returns the schema version of the input tree.
+func (c *UASTConvertCommand) schemaVersion(meta nodes.Node, format string) (int, error) {
+	if c.Schema != 0 {
+		if c.Schema < 0 {
+			return 0, fmt.Errorf("invalid schema version: %d", c.Schema)
+		}
+		return c.Schema, nil
+	}
+	if format != "proto" {
+		// text formats do not store the schema version
+		return uast.SchemaVersion, nil
+	}
+	return uast.SchemaVersionOf(meta)
+}
+
+// schemaMeta returns a copy of the metadata with a given schema version.
+func schemaMeta(meta nodes.Node, vers int) (nodes.Object, error) {
+	obj, ok := meta.(nodes.Object)
+	if meta != nil && !ok {
+		return nil, fmt.Errorf("unsupported metadata: expected an object, got %v", nodes.KindOf(meta))
+	}
+	obj = uast.SchemaMeta(obj)
+	obj[uast.KeySchemaVersion] = nodes.Int(vers)
+	return obj, nil
+}
+
+// uastFormats maps file extensions to UAST formats.
+var uastFormats = map[string]string{
+	".yml":     "yaml",
+	".yaml":    "yaml",
+	".json":    "json",
+	".msgpack": "msgpack",
+	".mp":      "msgpack",
+	".pb":      "proto",
+	".bin":     "proto",
+	".sexp":    "sexp",
+}
+
+func formatOf(name, format string) (string, error) {
+	if format != "" {
+		return format, nil
+	}
+	ext := strings.ToLower(filepath.Ext(name))
+	if f, ok := uastFormats[ext]; ok {
+		return f, nil
+	}
+	return "", fmt.Errorf("cannot detect format of %q; specify it explicitly", name)
+}
+
+func (c *UASTConvertCommand) Execute(args []string) error {
+	from, err := formatOf(c.Args.Input, c.From)
+	if err != nil {
+		return err
+	}
+	to := c.To
+	if to == "" && c.Args.Output == "" {
+		to = "yaml"
+	} else if to, err = formatOf(c.Args.Output, to); err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(c.Args.Input)
+	if err != nil {
+		return err
+	}
+	ast, meta, err := decodeUAST(data, from)
+	if err != nil {
+		return fmt.Errorf("cannot decode %s: %v", c.Args.Input, err)
+	}
+	if c.Migrate || to == "proto" {
+		vers, err := c.schemaVersion(meta, from)
+		if err != nil {
+			return err
+		}
+		if c.Migrate {
+			ast, err = uast.Migrate(ast, vers)
+			if err != nil {
+				return err
+			}
+			vers = uast.SchemaVersion
+		}
+		// binary graphs always record the schema version of the tree
+		meta, err = schemaMeta(meta, vers)
+		if err != nil {
+			return err
+		}
+	}
+	var w io.Writer = os.Stdout
+	if c.Args.Output != "" {
+		f, err := os.Create(c.Args.Output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	return encodeUAST(w, ast, meta, to)
+}
+
+// decodeUAST decodes the tree in a given format. Metadata is only returned for binary graphs.
+func decodeUAST(data []byte, format string) (ast, meta nodes.Node, err error) {
+	switch format {
+	case "yaml":
+		ast, err = uastyaml.Unmarshal(data)
+	case "json":
+		ast, err = nodesjson.Unmarshal(data)
+	case "msgpack":
+		ast, err = nodesmsgpack.Unmarshal(data)
+	case "proto":
+		ast, meta, err = nodesproto.ReadWithMeta(bytes.NewReader(data))
+	case "sexp":
+		ast, err = uastsexp.Unmarshal(data)
+	default:
+		err = fmt.Errorf("unsupported format: %q", format)
+	}
+	return ast, meta, err
+}
+
+// encodeUAST encodes the tree in a given format. Metadata is only written to binary graphs.
+func encodeUAST(w io.Writer, ast, meta nodes.Node, format string) error {
+	switch format {
+	case "yaml":
+		enc := uastyaml.NewEncoder(w)
+		// preserve the tree as-is
+		enc.ForceRoles(false)
+		return enc.Encode(ast)
+	case "json":
+		enc := nodesjson.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(ast)
+	case "msgpack":
+		return nodesmsgpack.NewEncoder(w).Encode(ast)
+	case "proto":
+		return nodesproto.WriteWithMeta(w, ast, meta)
+	case "sexp":
+		return uastsexp.NewEncoder(w).Encode(ast)
+	}
+	return fmt.Errorf("unsupported format: %q", format)
+}
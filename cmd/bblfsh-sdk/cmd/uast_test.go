@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+	"github.com/bblfsh/sdk/v3/uast/nodes/nodesproto"
+	"github.com/bblfsh/sdk/v3/uast/nodes/nodesproto/pio"
+)
+
+func writeGraph(t testing.TB, opt nodesproto.WriteOptions) []byte {
+	buf := bytes.NewBuffer(nil)
+	err := nodesproto.WriteWith(buf, nodes.Object{
+		"@type": nodes.String("node"),
+		"k":     nodes.Array{nodes.String("a"), nodes.Int(1)},
+	}, opt)
+	require.NoError(t, err)
+	return buf.Bytes()
+}
+
+func TestUASTInspect(t *testing.T) {
+	dir, err := ioutil.TempDir("", "uast_")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// graph with a duplicate node, written without checks
+	dup := bytes.NewBuffer(nil)
+	dup.WriteString("\x00bgr\x01\x00\x00\x00")
+	pw := pio.NewWriter(dup)
+	for _, m := range []proto.Message{
+		&nodesproto.GraphHeader{Root: 1, LastId: 2},
+		&nodesproto.Node{Id: 1, Values: []uint64{2}},
+		&nodesproto.Node{Id: 2, Value: &nodesproto.Node_Int{Int: 1}},
+		&nodesproto.Node{Id: 2, Value: &nodesproto.Node_Int{Int: 2}},
+	} {
+		_, err = pw.WriteMsg(m)
+		require.NoError(t, err)
+	}
+
+	v1 := writeGraph(t, nodesproto.WriteOptions{})
+	v2 := writeGraph(t, nodesproto.WriteOptions{Version: nodesproto.Version2})
+	v2[len(v2)-1]++
+
+	for _, c := range []struct {
+		name    string
+		data    []byte
+		problem string
+	}{
+		{name: "valid", data: v1},
+		{name: "truncated", data: v1[:len(v1)-1], problem: "truncated node after id"},
+		{name: "checksum", data: v2, problem: "checksum mismatch in nodes section"},
+		{name: "duplicate", data: dup.Bytes(), problem: "duplicate node with id 2"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			name := filepath.Join(dir, c.name+".pb")
+			err := ioutil.WriteFile(name, c.data, 0644)
+			require.NoError(t, err)
+
+			buf := bytes.NewBuffer(nil)
+			cmd := &UASTInspectCommand{}
+			err = cmd.inspectFile(buf, name)
+			if c.problem == "" {
+				require.NoError(t, err)
+				require.Contains(t, buf.String(), "re-encoded:")
+				return
+			}
+			require.Error(t, err)
+			require.Contains(t, buf.String(), "problem:")
+			require.Contains(t, buf.String(), c.problem)
+		})
+	}
+}
@@ -24,6 +24,13 @@ func main() {
 	parser.AddCommand("push", cmd.PushCommandDescription, "", &cmd.PushCommand{})
 	parser.AddCommand("ast2gv", cmd.Ast2GraphvizCommandDescription, "", &cmd.Ast2GraphvizCommand{})
 	parser.AddCommand("request", cmd.RequestCommandDescription, "", &cmd.RequestCommand{})
+	parser.AddCommand("stats", cmd.StatsCommandDescription, "", &cmd.StatsCommand{})
+	parser.AddCommand("gen-types", cmd.GenTypesCommandDescription, "", &cmd.GenTypesCommand{})
+	parser.AddCommand("gen-schema", cmd.GenSchemaCommandDescription, "", &cmd.GenSchemaCommand{})
+
+	uast, _ := parser.AddCommand("uast", cmd.UASTCommandDescription, "", &cmd.UASTCommand{})
+	uast.AddCommand("inspect", cmd.UASTInspectCommandDescription, "", &cmd.UASTInspectCommand{})
+	uast.AddCommand("convert", cmd.UASTConvertCommandDescription, "", &cmd.UASTConvertCommand{})
 
 	if _, err := parser.Parse(); err != nil {
 		if _, ok := err.(*flags.Error); ok {
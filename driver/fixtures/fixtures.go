@@ -294,23 +294,9 @@ func (s *Suite) testFixturesUAST(t *testing.T, mode driver.Mode, suf string, bla
 				}
 			}
 			if mode >= driver.ModeSemantic {
-				nodes.WalkPreOrder(ua, func(n nodes.Node) bool {
-					typ := uast.TypeOf(n)
-					if typ == "" {
-						return true
-					}
-					rv, err := uast.NewValue(typ)
-					if uast.ErrTypeNotRegistered.Is(err) {
-						return true // skip unregistered native types
-					} else if err != nil {
-						t.Error(err)
-						return true
-					}
-					if err := uast.NodeAs(n, rv); err != nil {
-						t.Errorf("type check failed for %q: %v", typ, err)
-					}
-					return true
-				})
+				for _, err := range uast.Validate(ua) {
+					t.Error(err)
+				}
 			}
 			if len(s.VerifyTokens) != 0 && mode == driver.ModeAnnotated {
 				for _, v := range s.VerifyTokens {
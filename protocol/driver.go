@@ -17,6 +17,7 @@ import (
 
 	"github.com/bblfsh/sdk/v3/driver"
 	"github.com/bblfsh/sdk/v3/driver/manifest"
+	"github.com/bblfsh/sdk/v3/uast"
 	"github.com/bblfsh/sdk/v3/uast/nodes"
 	"github.com/bblfsh/sdk/v3/uast/nodes/nodesproto"
 )
@@ -183,7 +184,8 @@ func (s *driverServer) Parse(rctx context.Context, req *ParseRequest) (*ParseRes
 	defer dsp.Finish()
 
 	buf := bytes.NewBuffer(nil)
-	err = nodesproto.WriteTo(buf, n)
+	// record the schema version, so clients can migrate the tree
+	err = nodesproto.WriteWithMeta(buf, n, uast.SchemaMeta(nil))
 	if err != nil {
 		return nil, err // unknown error = server failure
 	}
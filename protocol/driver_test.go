@@ -1,6 +1,7 @@
 package protocol
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"net"
@@ -13,7 +14,9 @@ import (
 
 	"github.com/bblfsh/sdk/v3/driver"
 	"github.com/bblfsh/sdk/v3/driver/manifest"
+	"github.com/bblfsh/sdk/v3/uast"
 	"github.com/bblfsh/sdk/v3/uast/nodes"
+	"github.com/bblfsh/sdk/v3/uast/nodes/nodesproto"
 )
 
 var _ driver.Driver = (*driverMock)(nil)
@@ -111,3 +114,14 @@ func TestDriverError(t *testing.T) {
 		})
 	}
 }
+
+func TestParseSchemaVersion(t *testing.T) {
+	s := &driverServer{d: &driverMock{uast: defaultUAST()}}
+	resp, err := s.Parse(context.Background(), &ParseRequest{Content: "test"})
+	require.NoError(t, err)
+
+	ast, meta, err := nodesproto.ReadWithMeta(bytes.NewReader(resp.Uast))
+	require.NoError(t, err)
+	require.True(t, nodes.Equal(defaultUAST(), ast))
+	require.True(t, nodes.Equal(uast.SchemaMeta(nil), meta))
+}
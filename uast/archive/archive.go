@@ -0,0 +1,558 @@
+// Package archive implements a container format that stores UASTs of multiple files.
+//
+// An archive consists of a header, a sequence of encoded trees, a dictionary and an index:
+//
+//	header:     magic "\x00bua" (4 bytes), version (uint32, little-endian)
+//	trees:      encoded trees, one for each entry
+//	dictionary: a list of all unique values and object keys used in all the trees
+//	index:      a list of entries, each with a file path, metadata, a UAST schema version and a location of the tree
+//	footer:     offsets of the dictionary and the index (uint64, little-endian), magic "\x00bua"
+//
+// Since values are shared across all trees in the archive, it's more compact than storing each tree
+// separately. The index allows to read a tree for a specific path without decoding other trees.
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/bblfsh/sdk/v3/uast"
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+	"gopkg.in/src-d/go-errors.v1"
+)
+
+const (
+	magic      = "\x00bua"
+	version    = 0x1
+	headerSize = 8
+	footerSize = 8 + 8 + len(magic)
+
+	// maxDepth is the maximal nesting depth of trees in the archive.
+	maxDepth = 10000
+)
+
+// tags used in the tree and dictionary encoding
+const (
+	tagNil = byte(iota)
+	tagObject
+	tagArray
+	tagValue
+
+	tagString
+	tagInt
+	tagUint
+	tagFloat
+	tagBool
+)
+
+var (
+	// ErrNotFound is returned when an archive has no entry for a given path.
+	ErrNotFound = errors.NewKind("archive: entry not found: %q")
+	// ErrDuplicate is returned when adding an entry with the same path twice.
+	ErrDuplicate = errors.NewKind("archive: duplicate entry: %q")
+	// ErrClosed is returned when writing to an archive that was already closed.
+	ErrClosed = errors.NewKind("archive: writer is closed")
+)
+
+// Entry describes a single file in the archive.
+type Entry struct {
+	// Path of the source file.
+	Path string
+	// Language of the source file.
+	Language string
+	// DriverVersion is the version of the driver that produced the UAST.
+	DriverVersion string
+	// Hash is the hash of the source file content. The hash function is defined by the application.
+	Hash string
+	// SchemaVersion is the UAST schema version of the tree (see uast.SchemaVersion).
+	// Writer.Add sets it to the current version if it's zero.
+	SchemaVersion int
+
+	off, size uint64 // location of the encoded tree
+}
+
+// Writer writes trees to an archive. The archive is finalized on Close.
+type Writer struct {
+	w   *bufio.Writer
+	off uint64
+	err error
+
+	dict  []nodes.Value
+	vals  map[nodes.Value]uint64
+	index []Entry
+	paths map[string]struct{}
+	buf   bytes.Buffer
+}
+
+// NewWriter creates a new archive writer. The caller should call Close to write the index.
+func NewWriter(w io.Writer) *Writer {
+	aw := &Writer{
+		w:     bufio.NewWriter(w),
+		vals:  make(map[nodes.Value]uint64),
+		paths: make(map[string]struct{}),
+	}
+	var hdr [headerSize]byte
+	copy(hdr[:], magic)
+	binary.LittleEndian.PutUint32(hdr[4:], version)
+	aw.write(hdr[:])
+	return aw
+}
+
+func (w *Writer) write(p []byte) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = w.w.Write(p)
+	w.off += uint64(len(p))
+}
+
+// Add writes a tree for a given entry to the archive. Entry paths must be unique.
+func (w *Writer) Add(e Entry, root nodes.External) error {
+	if w.err != nil {
+		return w.err
+	}
+	if _, ok := w.paths[e.Path]; ok {
+		return ErrDuplicate.New(e.Path)
+	}
+	if e.SchemaVersion == 0 {
+		e.SchemaVersion = uast.SchemaVersion
+	} else if e.SchemaVersion < 0 {
+		return fmt.Errorf("%s: invalid schema version: %d", e.Path, e.SchemaVersion)
+	}
+	w.buf.Reset()
+	if err := w.encode(root, 0); err != nil {
+		return fmt.Errorf("%s: %v", e.Path, err)
+	}
+	e.off, e.size = w.off, uint64(w.buf.Len())
+	w.write(w.buf.Bytes())
+	if w.err != nil {
+		return w.err
+	}
+	w.paths[e.Path] = struct{}{}
+	w.index = append(w.index, e)
+	return nil
+}
+
+func (w *Writer) uvarint(buf *bytes.Buffer, v uint64) {
+	var p [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(p[:], v)
+	buf.Write(p[:n])
+}
+
+func (w *Writer) str(buf *bytes.Buffer, s string) {
+	w.uvarint(buf, uint64(len(s)))
+	buf.WriteString(s)
+}
+
+// value returns an index of the value in the dictionary.
+func (w *Writer) value(v nodes.Value) uint64 {
+	if i, ok := w.vals[v]; ok {
+		return i
+	}
+	i := uint64(len(w.dict))
+	w.dict = append(w.dict, v)
+	w.vals[v] = i
+	return i
+}
+
+// encode writes the tree in pre-order to the buffer.
+func (w *Writer) encode(n nodes.External, depth int) error {
+	if depth > maxDepth {
+		return fmt.Errorf("maximum nesting depth exceeded")
+	}
+	buf := &w.buf
+	switch kind := nodes.KindOf(n); kind {
+	case nodes.KindNil:
+		buf.WriteByte(tagNil)
+	case nodes.KindObject:
+		obj, ok := n.(nodes.ExternalObject)
+		if !ok {
+			return fmt.Errorf("node is an object, but an interface implementation is missing: %T", n)
+		}
+		keys := obj.Keys()
+		buf.WriteByte(tagObject)
+		w.uvarint(buf, uint64(len(keys)))
+		for _, k := range keys {
+			v, _ := obj.ValueAt(k)
+			w.uvarint(buf, w.value(nodes.String(k)))
+			if err := w.encode(v, depth+1); err != nil {
+				return err
+			}
+		}
+	case nodes.KindArray:
+		arr, ok := n.(nodes.ExternalArray)
+		if !ok {
+			return fmt.Errorf("node is an array, but an interface implementation is missing: %T", n)
+		}
+		sz := arr.Size()
+		buf.WriteByte(tagArray)
+		w.uvarint(buf, uint64(sz))
+		for i := 0; i < sz; i++ {
+			if err := w.encode(arr.ValueAt(i), depth+1); err != nil {
+				return err
+			}
+		}
+	default:
+		if !kind.In(nodes.KindsValues) {
+			return fmt.Errorf("unsupported node type: %T", n)
+		}
+		buf.WriteByte(tagValue)
+		w.uvarint(buf, w.value(n.Value()))
+	}
+	return nil
+}
+
+// Close writes the dictionary and the index to the archive. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	buf := &w.buf
+
+	// dictionary
+	buf.Reset()
+	dictOff := w.off
+	w.uvarint(buf, uint64(len(w.dict)))
+	for _, v := range w.dict {
+		switch v := v.(type) {
+		case nodes.String:
+			buf.WriteByte(tagString)
+			w.str(buf, string(v))
+		case nodes.Int:
+			buf.WriteByte(tagInt)
+			var p [binary.MaxVarintLen64]byte
+			n := binary.PutVarint(p[:], int64(v))
+			buf.Write(p[:n])
+		case nodes.Uint:
+			buf.WriteByte(tagUint)
+			w.uvarint(buf, uint64(v))
+		case nodes.Float:
+			buf.WriteByte(tagFloat)
+			var p [8]byte
+			binary.LittleEndian.PutUint64(p[:], math.Float64bits(float64(v)))
+			buf.Write(p[:])
+		case nodes.Bool:
+			buf.WriteByte(tagBool)
+			if v {
+				buf.WriteByte(1)
+			} else {
+				buf.WriteByte(0)
+			}
+		default:
+			return fmt.Errorf("unsupported value type: %T", v)
+		}
+	}
+	w.write(buf.Bytes())
+
+	// index
+	buf.Reset()
+	indexOff := w.off
+	w.uvarint(buf, uint64(len(w.index)))
+	for _, e := range w.index {
+		w.str(buf, e.Path)
+		w.str(buf, e.Language)
+		w.str(buf, e.DriverVersion)
+		w.str(buf, e.Hash)
+		w.uvarint(buf, uint64(e.SchemaVersion))
+		w.uvarint(buf, e.off)
+		w.uvarint(buf, e.size)
+	}
+	w.write(buf.Bytes())
+
+	// footer
+	var foot [footerSize]byte
+	binary.LittleEndian.PutUint64(foot[0:], dictOff)
+	binary.LittleEndian.PutUint64(foot[8:], indexOff)
+	copy(foot[16:], magic)
+	w.write(foot[:])
+	if w.err != nil {
+		return w.err
+	}
+	w.err = w.w.Flush()
+	if w.err != nil {
+		return w.err
+	}
+	w.err = ErrClosed.New()
+	return nil
+}
+
+// Reader provides random access to trees stored in the archive.
+type Reader struct {
+	r       io.ReaderAt
+	dict    []nodes.Value
+	entries []Entry
+	paths   map[string]int
+}
+
+// NewReader opens an archive for reading. Only the dictionary and the index are read into memory.
+func NewReader(r io.ReaderAt, size int64) (*Reader, error) {
+	if size < int64(headerSize+footerSize) {
+		return nil, fmt.Errorf("archive: file is too short")
+	}
+	var hdr [headerSize]byte
+	if _, err := r.ReadAt(hdr[:], 0); err != nil {
+		return nil, err
+	}
+	if string(hdr[:4]) != magic {
+		return nil, fmt.Errorf("archive: not an archive file")
+	}
+	if vers := binary.LittleEndian.Uint32(hdr[4:]); vers != version {
+		return nil, fmt.Errorf("archive: unsupported version: %x", vers)
+	}
+	var foot [footerSize]byte
+	if _, err := r.ReadAt(foot[:], size-int64(footerSize)); err != nil {
+		return nil, err
+	}
+	if string(foot[16:]) != magic {
+		return nil, fmt.Errorf("archive: invalid footer")
+	}
+	dictOff := binary.LittleEndian.Uint64(foot[0:])
+	indexOff := binary.LittleEndian.Uint64(foot[8:])
+	end := uint64(size) - uint64(footerSize)
+	if dictOff < headerSize || dictOff > indexOff || indexOff > end {
+		return nil, fmt.Errorf("archive: invalid footer")
+	}
+	data := make([]byte, end-dictOff)
+	if _, err := r.ReadAt(data, int64(dictOff)); err != nil {
+		return nil, err
+	}
+	ar := &Reader{r: r, paths: make(map[string]int)}
+	if err := ar.readDict(bytes.NewReader(data[:indexOff-dictOff])); err != nil {
+		return nil, fmt.Errorf("archive: cannot read dictionary: %v", err)
+	}
+	if err := ar.readIndex(bytes.NewReader(data[indexOff-dictOff:]), dictOff); err != nil {
+		return nil, fmt.Errorf("archive: cannot read index: %v", err)
+	}
+	return ar, nil
+}
+
+func readString(r *bytes.Reader) (string, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return "", err
+	}
+	if n > uint64(r.Len()) {
+		return "", io.ErrUnexpectedEOF
+	}
+	b := make([]byte, n)
+	if _, err = io.ReadFull(r, b); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (ar *Reader) readDict(r *bytes.Reader) error {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return err
+	}
+	if n > uint64(r.Len()) {
+		return io.ErrUnexpectedEOF
+	}
+	ar.dict = make([]nodes.Value, 0, n)
+	for i := uint64(0); i < n; i++ {
+		tag, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		var v nodes.Value
+		switch tag {
+		case tagString:
+			s, err := readString(r)
+			if err != nil {
+				return err
+			}
+			v = nodes.String(s)
+		case tagInt:
+			x, err := binary.ReadVarint(r)
+			if err != nil {
+				return err
+			}
+			v = nodes.Int(x)
+		case tagUint:
+			x, err := binary.ReadUvarint(r)
+			if err != nil {
+				return err
+			}
+			v = nodes.Uint(x)
+		case tagFloat:
+			var p [8]byte
+			if _, err = io.ReadFull(r, p[:]); err != nil {
+				return err
+			}
+			v = nodes.Float(math.Float64frombits(binary.LittleEndian.Uint64(p[:])))
+		case tagBool:
+			b, err := r.ReadByte()
+			if err != nil {
+				return err
+			}
+			v = nodes.Bool(b != 0)
+		default:
+			return fmt.Errorf("unexpected value tag: %d", tag)
+		}
+		ar.dict = append(ar.dict, v)
+	}
+	return nil
+}
+
+func (ar *Reader) readIndex(r *bytes.Reader, maxOff uint64) error {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return err
+	}
+	if n > uint64(r.Len()) {
+		return io.ErrUnexpectedEOF
+	}
+	ar.entries = make([]Entry, 0, n)
+	for i := uint64(0); i < n; i++ {
+		var e Entry
+		for _, p := range []*string{&e.Path, &e.Language, &e.DriverVersion, &e.Hash} {
+			if *p, err = readString(r); err != nil {
+				return err
+			}
+		}
+		vers, err := binary.ReadUvarint(r)
+		if err != nil {
+			return err
+		} else if vers == 0 || vers > math.MaxInt32 {
+			return fmt.Errorf("invalid schema version for %q: %d", e.Path, vers)
+		}
+		e.SchemaVersion = int(vers)
+		if e.off, err = binary.ReadUvarint(r); err != nil {
+			return err
+		}
+		if e.size, err = binary.ReadUvarint(r); err != nil {
+			return err
+		}
+		// check each value separately to avoid an overflow
+		if e.off < headerSize || e.off > maxOff || e.size > maxOff-e.off {
+			return fmt.Errorf("invalid tree location for %q", e.Path)
+		}
+		if _, ok := ar.paths[e.Path]; ok {
+			return ErrDuplicate.New(e.Path)
+		}
+		ar.paths[e.Path] = len(ar.entries)
+		ar.entries = append(ar.entries, e)
+	}
+	return nil
+}
+
+// Len returns the number of entries in the archive.
+func (ar *Reader) Len() int {
+	return len(ar.entries)
+}
+
+// Entries returns all entries of the archive, sorted by path.
+func (ar *Reader) Entries() []Entry {
+	out := make([]Entry, len(ar.entries))
+	copy(out, ar.entries)
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Path < out[j].Path
+	})
+	return out
+}
+
+// Lookup finds an entry for a given path.
+func (ar *Reader) Lookup(path string) (Entry, bool) {
+	i, ok := ar.paths[path]
+	if !ok {
+		return Entry{}, false
+	}
+	return ar.entries[i], true
+}
+
+// Tree reads a tree for a given path. It returns ErrNotFound if there is no such entry.
+func (ar *Reader) Tree(path string) (nodes.Node, error) {
+	e, ok := ar.Lookup(path)
+	if !ok {
+		return nil, ErrNotFound.New(path)
+	}
+	data := make([]byte, e.size)
+	if _, err := ar.r.ReadAt(data, int64(e.off)); err != nil {
+		return nil, err
+	}
+	r := bytes.NewReader(data)
+	n, err := ar.decode(r, 0)
+	if err != nil {
+		return nil, fmt.Errorf("archive: %s: %v", path, err)
+	}
+	if r.Len() != 0 {
+		return nil, fmt.Errorf("archive: %s: unexpected data after the tree", path)
+	}
+	return n, nil
+}
+
+func (ar *Reader) dictValue(r *bytes.Reader) (nodes.Value, error) {
+	i, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if i >= uint64(len(ar.dict)) {
+		return nil, fmt.Errorf("value %d is not in the dictionary", i)
+	}
+	return ar.dict[i], nil
+}
+
+func (ar *Reader) decode(r *bytes.Reader, depth int) (nodes.Node, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("maximum nesting depth exceeded")
+	}
+	tag, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch tag {
+	case tagNil:
+		return nil, nil
+	case tagValue:
+		return ar.dictValue(r)
+	case tagObject:
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		if n > uint64(r.Len()) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		obj := make(nodes.Object, n)
+		for i := uint64(0); i < n; i++ {
+			k, err := ar.dictValue(r)
+			if err != nil {
+				return nil, err
+			}
+			key, ok := k.(nodes.String)
+			if !ok {
+				return nil, fmt.Errorf("only string keys are supported")
+			}
+			v, err := ar.decode(r, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			obj[string(key)] = v
+		}
+		return obj, nil
+	case tagArray:
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		if n > uint64(r.Len()) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		arr := make(nodes.Array, 0, n)
+		for i := uint64(0); i < n; i++ {
+			v, err := ar.decode(r, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		return arr, nil
+	}
+	return nil, fmt.Errorf("unexpected node tag: %d", tag)
+}
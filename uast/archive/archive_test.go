@@ -0,0 +1,245 @@
+package archive
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/bblfsh/sdk/v3/uast"
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+	"github.com/stretchr/testify/require"
+)
+
+var archiveCases = []struct {
+	entry Entry
+	tree  nodes.Node
+}{
+	{
+		entry: Entry{Path: "main.go", Language: "go", DriverVersion: "v2.1.0", Hash: "abc"},
+		tree: nodes.Object{
+			"@type": nodes.String("File"),
+			"Body": nodes.Array{
+				nodes.Object{
+					"@type": nodes.String("Ident"),
+					"Name":  nodes.String("main"),
+				},
+				nil,
+				nodes.Int(-1),
+				nodes.Uint(2),
+				nodes.Float(3.5),
+				nodes.Bool(true),
+			},
+			"Empty": nodes.Object{},
+		},
+	},
+	{
+		entry: Entry{Path: "a/b.py", Language: "python", DriverVersion: "v2.0.0", Hash: "def", SchemaVersion: 1},
+		tree: nodes.Object{
+			"@type": nodes.String("File"),
+			"Body": nodes.Array{
+				nodes.Object{
+					"@type": nodes.String("Ident"),
+					"Name":  nodes.String("main"),
+				},
+			},
+		},
+	},
+	{
+		entry: Entry{Path: "empty.txt"},
+	},
+}
+
+func TestArchive(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	w := NewWriter(buf)
+	for _, c := range archiveCases {
+		err := w.Add(c.entry, c.tree)
+		require.NoError(t, err)
+	}
+	err := w.Add(archiveCases[0].entry, nil)
+	require.True(t, ErrDuplicate.Is(err))
+
+	err = w.Close()
+	require.NoError(t, err)
+	err = w.Add(Entry{Path: "new"}, nil)
+	require.True(t, ErrClosed.Is(err))
+
+	data := buf.Bytes()
+	r, err := NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	require.Equal(t, len(archiveCases), r.Len())
+	// keys and values are shared between trees
+	require.Len(t, r.dict, 11)
+
+	var paths []string
+	for _, e := range r.Entries() {
+		paths = append(paths, e.Path)
+	}
+	require.Equal(t, []string{"a/b.py", "empty.txt", "main.go"}, paths)
+
+	// read in reverse order to check random access
+	for i := len(archiveCases) - 1; i >= 0; i-- {
+		c := archiveCases[i]
+		e, ok := r.Lookup(c.entry.Path)
+		require.True(t, ok)
+		require.Equal(t, c.entry.Language, e.Language)
+		require.Equal(t, c.entry.DriverVersion, e.DriverVersion)
+		require.Equal(t, c.entry.Hash, e.Hash)
+		require.Equal(t, uast.SchemaVersion, e.SchemaVersion)
+
+		tree, err := r.Tree(c.entry.Path)
+		require.NoError(t, err)
+		require.Equal(t, c.tree, tree)
+	}
+
+	_, err = r.Tree("missing")
+	require.True(t, ErrNotFound.Is(err))
+}
+
+func TestArchiveInvalid(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	w := NewWriter(buf)
+	require.NoError(t, w.Add(archiveCases[0].entry, archiveCases[0].tree))
+	require.NoError(t, w.Close())
+
+	data := buf.Bytes()
+	_, err := NewReader(bytes.NewReader(data[:len(data)-1]), int64(len(data)-1))
+	require.Error(t, err)
+
+	data = append([]byte{}, data...)
+	data[0] = 'x'
+	_, err = NewReader(bytes.NewReader(data), int64(len(data)))
+	require.Error(t, err)
+}
+
+// rawArchive builds an archive with a single entry pointing to a given location. The dictionary has a
+// single string "k" and an integer 1.
+func rawArchive(tree []byte, off, size uint64) []byte {
+	buf := bytes.NewBuffer(nil)
+	uvarint := func(v uint64) {
+		var p [binary.MaxVarintLen64]byte
+		buf.Write(p[:binary.PutUvarint(p[:], v)])
+	}
+	var u32 [4]byte
+	binary.LittleEndian.PutUint32(u32[:], version)
+	buf.WriteString(magic)
+	buf.Write(u32[:])
+	buf.Write(tree)
+
+	dictOff := uint64(buf.Len())
+	uvarint(2)
+	buf.Write([]byte{tagString, 1, 'k', tagInt, 2})
+
+	indexOff := uint64(buf.Len())
+	uvarint(1)
+	buf.Write([]byte{1, 'a', 0, 0, 0})
+	uvarint(uast.SchemaVersion)
+	uvarint(off)
+	uvarint(size)
+
+	var u64 [8]byte
+	binary.LittleEndian.PutUint64(u64[:], dictOff)
+	buf.Write(u64[:])
+	binary.LittleEndian.PutUint64(u64[:], indexOff)
+	buf.Write(u64[:])
+	buf.WriteString(magic)
+	return buf.Bytes()
+}
+
+func TestArchiveMalicious(t *testing.T) {
+	deep := bytes.Repeat([]byte{tagArray, 1}, maxDepth+1)
+	deep = append(deep, tagNil)
+
+	for _, c := range []struct {
+		name string
+		data []byte
+		err  string // error from NewReader
+		terr string // error from Tree
+	}{
+		{name: "valid", data: rawArchive([]byte{tagObject, 1, 0, tagValue, 1}, headerSize, 5)},
+		{name: "size overflow", data: rawArchive([]byte{tagNil}, headerSize, math.MaxUint64), err: "invalid tree location"},
+		{name: "offset overflow", data: rawArchive([]byte{tagNil}, math.MaxUint64-1, 2), err: "invalid tree location"},
+		{name: "past the index", data: rawArchive([]byte{tagNil}, headerSize, 2), err: "invalid tree location"},
+		{name: "deep", data: rawArchive(deep, headerSize, uint64(len(deep))), terr: "maximum nesting depth exceeded"},
+		{name: "truncated array", data: rawArchive([]byte{tagArray, 2, tagNil}, headerSize, 3), terr: "unexpected EOF"},
+		{name: "truncated object", data: rawArchive([]byte{tagObject, 1, 0}, headerSize, 3), terr: "EOF"},
+		{name: "missing value", data: rawArchive([]byte{tagValue, 2}, headerSize, 2), terr: "not in the dictionary"},
+		{name: "int key", data: rawArchive([]byte{tagObject, 1, 1, tagNil}, headerSize, 4), terr: "only string keys"},
+		{name: "invalid tag", data: rawArchive([]byte{0xff}, headerSize, 1), terr: "unexpected node tag"},
+		{name: "trailing data", data: rawArchive([]byte{tagNil, tagNil}, headerSize, 2), terr: "unexpected data"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			ar, err := NewReader(bytes.NewReader(c.data), int64(len(c.data)))
+			if c.err != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), c.err)
+				return
+			}
+			require.NoError(t, err)
+			_, err = ar.Tree("a")
+			if c.terr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), c.terr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestArchiveDepth(t *testing.T) {
+	deep := func(depth int) nodes.Node {
+		var n nodes.Node
+		for i := 0; i < depth; i++ {
+			n = nodes.Array{n}
+		}
+		return n
+	}
+
+	buf := bytes.NewBuffer(nil)
+	w := NewWriter(buf)
+	err := w.Add(Entry{Path: "a"}, deep(maxDepth+1))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "maximum nesting depth exceeded")
+
+	// the writer is still usable
+	tree := deep(maxDepth)
+	require.NoError(t, w.Add(Entry{Path: "a"}, tree))
+	require.NoError(t, w.Close())
+
+	ar, err := NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	out, err := ar.Tree("a")
+	require.NoError(t, err)
+	require.True(t, nodes.Equal(tree, out))
+}
+
+func TestArchiveCorrupted(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	w := NewWriter(buf)
+	for _, c := range archiveCases {
+		require.NoError(t, w.Add(c.entry, c.tree))
+	}
+	require.NoError(t, w.Close())
+	data := buf.Bytes()
+
+	// the footer is read from the end of the file, thus corrupt the data instead of cutting it
+	for i := headerSize; i < len(data)-footerSize; i++ {
+		bad := append([]byte{}, data...)
+		for j := i; j < len(bad)-footerSize; j++ {
+			bad[j] = 0xff
+		}
+		ar, err := NewReader(bytes.NewReader(bad), int64(len(bad)))
+		if err != nil {
+			continue
+		}
+		for _, e := range ar.Entries() {
+			_, _ = ar.Tree(e.Path)
+		}
+	}
+	for i := 0; i < len(data); i++ {
+		_, err := NewReader(bytes.NewReader(data[:i]), int64(i))
+		require.Error(t, err, "size: %d", i)
+	}
+}
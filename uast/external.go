@@ -0,0 +1,335 @@
+package uast
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+)
+
+// AsExternal exposes a Go value as an external UAST node without converting it to nodes.Node.
+//
+// The value is accessed lazily via reflection, following the same rules as ToNode: structs and maps
+// must be registered via RegisterPackage, field names are taken from "uast" or "json" struct tags, and
+// the KeyType field is derived from TypeOf. Slices are exposed as arrays, and values that already
+// implement nodes.External are returned as-is.
+//
+// The returned node reflects the current state of the Go value, thus the value must not be modified
+// while the node is in use. Since the tree is traversed lazily, accessing a child of an unsupported
+// type will panic. Use ToNode to convert and validate the whole tree at once.
+func AsExternal(o interface{}) (nodes.External, error) {
+	if o == nil {
+		return nil, nil
+	}
+	return asExternal(reflect.ValueOf(o))
+}
+
+func asExternal(rv reflect.Value) (nodes.External, error) {
+	rt := rv.Type()
+	for rt.Kind() == reflect.Interface || rt.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+		rt = rv.Type()
+	}
+	if rt.ConvertibleTo(reflNodeExt) {
+		return rv.Interface().(nodes.External), nil
+	}
+	switch rt.Kind() {
+	case reflect.Int, reflect.Int64, reflect.Int32, reflect.Int16, reflect.Int8:
+		return nodes.Int(rv.Int()), nil
+	case reflect.Uint, reflect.Uint64, reflect.Uint32, reflect.Uint16, reflect.Uint8:
+		return nodes.Uint(rv.Uint()), nil
+	case reflect.Float64, reflect.Float32:
+		return nodes.Float(rv.Float()), nil
+	case reflect.Bool:
+		return nodes.Bool(rv.Bool()), nil
+	case reflect.String:
+		return nodes.String(rv.String()), nil
+	case reflect.Slice:
+		return reflectArray{rv: rv}, nil
+	case reflect.Struct, reflect.Map:
+		name := typeOf(rt)
+		if name.NS == "" {
+			return nil, fmt.Errorf("type %v is not registered", rt)
+		}
+		typ := nodes.String(name.String())
+		if rt.Kind() == reflect.Map {
+			if rt.Key() != reflString {
+				return nil, fmt.Errorf("unsupported map key type: %v", rt.Key())
+			}
+			return reflectMap{rv: rv, typ: typ}, nil
+		}
+		desc, err := structDescOf(rt)
+		if err != nil {
+			return nil, err
+		}
+		return reflectStruct{rv: rv, typ: typ, desc: desc}, nil
+	}
+	return nil, fmt.Errorf("unsupported type: %v", rt)
+}
+
+func mustExternal(rv reflect.Value) nodes.External {
+	n, err := asExternal(rv)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// isNilValue checks if the value will be represented as a nil node.
+func isNilValue(rv reflect.Value) bool {
+	for rv.Kind() == reflect.Interface || rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return true
+		}
+		rv = rv.Elem()
+	}
+	return false
+}
+
+// sameValue checks if both values refer to the same memory location.
+// Structs can only be compared if they are addressable.
+func sameValue(a, b reflect.Value) bool {
+	if a.Type() != b.Type() {
+		return false
+	}
+	switch a.Kind() {
+	case reflect.Map:
+		return a.Pointer() == b.Pointer()
+	case reflect.Slice:
+		return a.Pointer() == b.Pointer() && a.Len() == b.Len()
+	case reflect.Struct:
+		return a.CanAddr() && b.CanAddr() && a.UnsafeAddr() == b.UnsafeAddr()
+	}
+	return false
+}
+
+// structField describes a single field of a struct exposed as an object.
+type structField struct {
+	fieldDesc
+	Index []int // see reflect.Value.FieldByIndex
+}
+
+// structDesc is a cached list of struct fields sorted by name.
+type structDesc struct {
+	fields []structField
+}
+
+var structDescs struct {
+	sync.RWMutex
+	m map[reflect.Type]*structDesc
+}
+
+// structDescOf returns a cached description of struct fields.
+func structDescOf(rt reflect.Type) (*structDesc, error) {
+	structDescs.RLock()
+	d, ok := structDescs.m[rt]
+	structDescs.RUnlock()
+	if ok {
+		return d, nil
+	}
+	byName := make(map[string]structField)
+	if err := collectFields(byName, rt, nil); err != nil {
+		return nil, err
+	}
+	d = &structDesc{fields: make([]structField, 0, len(byName))}
+	for _, f := range byName {
+		d.fields = append(d.fields, f)
+	}
+	sort.Slice(d.fields, func(i, j int) bool {
+		return d.fields[i].Name < d.fields[j].Name
+	})
+	structDescs.Lock()
+	if structDescs.m == nil {
+		structDescs.m = make(map[reflect.Type]*structDesc)
+	}
+	structDescs.m[rt] = d
+	structDescs.Unlock()
+	return d, nil
+}
+
+// collectFields adds all exported fields of the struct to the map. It follows the same rules as structToNode.
+func collectFields(byName map[string]structField, rt reflect.Type, index []int) error {
+	for i := 0; i < rt.NumField(); i++ {
+		ft := rt.Field(i)
+		if ft.PkgPath != "" {
+			continue // unexported
+		}
+		idx := make([]int, len(index)+1)
+		copy(idx, index)
+		idx[len(index)] = i
+		if ft.Anonymous {
+			if err := collectFields(byName, ft.Type, idx); err != nil {
+				return err
+			}
+			continue
+		}
+		d, err := getFieldDesc(ft)
+		if err != nil {
+			return fmt.Errorf("type %s: %v", rt.Name(), err)
+		}
+		if d.Name == KeyType {
+			continue
+		}
+		byName[d.Name] = structField{fieldDesc: d, Index: idx}
+	}
+	return nil
+}
+
+var (
+	_ nodes.ExternalObject = reflectStruct{}
+	_ nodes.ExternalObject = reflectMap{}
+	_ nodes.ExternalArray  = reflectArray{}
+)
+
+// reflectStruct exposes a registered Go struct as an object.
+type reflectStruct struct {
+	rv   reflect.Value
+	typ  nodes.String
+	desc *structDesc
+}
+
+func (reflectStruct) Kind() nodes.Kind {
+	return nodes.KindObject
+}
+
+func (reflectStruct) Value() nodes.Value {
+	return nil
+}
+
+func (o reflectStruct) SameAs(n nodes.External) bool {
+	o2, ok := n.(reflectStruct)
+	return ok && sameValue(o.rv, o2.rv)
+}
+
+// omitted checks if the field should be skipped.
+func (o reflectStruct) omitted(f *structField) bool {
+	return f.OmitEmpty && isNilValue(o.rv.FieldByIndex(f.Index))
+}
+
+func (o reflectStruct) Size() int {
+	sz := 1 // type
+	for i := range o.desc.fields {
+		if !o.omitted(&o.desc.fields[i]) {
+			sz++
+		}
+	}
+	return sz
+}
+
+func (o reflectStruct) Keys() []string {
+	keys := make([]string, 0, len(o.desc.fields)+1)
+	typed := false
+	for i := range o.desc.fields {
+		f := &o.desc.fields[i]
+		if !typed && KeyType < f.Name {
+			keys = append(keys, KeyType)
+			typed = true
+		}
+		if !o.omitted(f) {
+			keys = append(keys, f.Name)
+		}
+	}
+	if !typed {
+		keys = append(keys, KeyType)
+	}
+	return keys
+}
+
+func (o reflectStruct) ValueAt(key string) (nodes.External, bool) {
+	if key == KeyType {
+		return o.typ, true
+	}
+	fields := o.desc.fields
+	i := sort.Search(len(fields), func(i int) bool {
+		return fields[i].Name >= key
+	})
+	if i >= len(fields) || fields[i].Name != key {
+		return nil, false
+	}
+	f := &fields[i]
+	if o.omitted(f) {
+		return nil, false
+	}
+	return mustExternal(o.rv.FieldByIndex(f.Index)), true
+}
+
+// reflectMap exposes a registered Go map as an object.
+type reflectMap struct {
+	rv  reflect.Value
+	typ nodes.String
+}
+
+func (reflectMap) Kind() nodes.Kind {
+	return nodes.KindObject
+}
+
+func (reflectMap) Value() nodes.Value {
+	return nil
+}
+
+func (o reflectMap) SameAs(n nodes.External) bool {
+	o2, ok := n.(reflectMap)
+	return ok && sameValue(o.rv, o2.rv)
+}
+
+func (o reflectMap) Size() int {
+	sz := o.rv.Len()
+	if o.rv.MapIndex(reflect.ValueOf(KeyType)).IsValid() {
+		return sz
+	}
+	return sz + 1
+}
+
+func (o reflectMap) Keys() []string {
+	keys := make([]string, 0, o.rv.Len()+1)
+	keys = append(keys, KeyType)
+	for _, k := range o.rv.MapKeys() {
+		if s := k.String(); s != KeyType {
+			keys = append(keys, s)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (o reflectMap) ValueAt(key string) (nodes.External, bool) {
+	v := o.rv.MapIndex(reflect.ValueOf(key))
+	if !v.IsValid() {
+		if key == KeyType {
+			return o.typ, true
+		}
+		return nil, false
+	}
+	return mustExternal(v), true
+}
+
+// reflectArray exposes a Go slice as an array.
+type reflectArray struct {
+	rv reflect.Value
+}
+
+func (reflectArray) Kind() nodes.Kind {
+	return nodes.KindArray
+}
+
+func (reflectArray) Value() nodes.Value {
+	return nil
+}
+
+func (a reflectArray) SameAs(n nodes.External) bool {
+	a2, ok := n.(reflectArray)
+	return ok && sameValue(a.rv, a2.rv)
+}
+
+func (a reflectArray) Size() int {
+	return a.rv.Len()
+}
+
+func (a reflectArray) ValueAt(i int) nodes.External {
+	return mustExternal(a.rv.Index(i))
+}
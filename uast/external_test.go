@@ -0,0 +1,66 @@
+package uast_test
+
+import (
+	"testing"
+
+	. "github.com/bblfsh/sdk/v3/uast"
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+	"github.com/bblfsh/sdk/v3/uast/uastyaml"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAsExternal(t *testing.T) {
+	for _, c := range casesToNode {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := AsExternal(c.obj)
+			require.NoError(t, err)
+			require.True(t, nodes.Equal(c.exp, got), "%#v", got)
+			require.Equal(t, nodes.HashOf(c.exp), nodes.HashOf(got))
+
+			n, err := nodes.ToNode(got, nil)
+			require.NoError(t, err)
+			require.Equal(t, c.exp, n)
+
+			exp, err := uastyaml.Marshal(c.exp)
+			require.NoError(t, err)
+			data, err := uastyaml.Marshal(got)
+			require.NoError(t, err)
+			require.Equal(t, string(exp), string(data))
+		})
+	}
+}
+
+func TestAsExternalLazy(t *testing.T) {
+	arg := &Argument{
+		Name: &Identifier{Name: "a"},
+		Type: Identifier{Name: "int"},
+	}
+	n, err := AsExternal(arg)
+	require.NoError(t, err)
+	require.Equal(t, "uast:Argument", TypeOf(n))
+
+	obj, ok := n.(nodes.ExternalObject)
+	require.True(t, ok)
+	require.Equal(t, []string{
+		KeyPos, KeyType, "Init", "MapVariadic", "Name", "Receiver", "Type", "Variadic",
+	}, obj.Keys())
+	require.Equal(t, 8, obj.Size())
+
+	v, ok := obj.ValueAt("Init")
+	require.True(t, ok)
+	require.Nil(t, v)
+
+	// the node reflects changes in the Go value
+	arg.Name.Name = "b"
+	name, _ := obj.ValueAt("Name")
+	v, _ = name.(nodes.ExternalObject).ValueAt("Name")
+	require.Equal(t, nodes.String("b"), v)
+
+	// addressable values keep their identity
+	n2, err := AsExternal(arg)
+	require.NoError(t, err)
+	require.True(t, nodes.Same(n, n2))
+
+	_, err = AsExternal(struct{}{})
+	require.Error(t, err)
+}
@@ -0,0 +1,456 @@
+// Package arena implements a compact immutable representation of UAST trees.
+//
+// In contrast to nodes.Object, which is a separate Go map for each node, all nodes of
+// the tree are stored in a few flat slices: object keys and string values are interned into
+// a single string table, children references are stored in a shared slice and numeric values
+// are stored in a typed array. This significantly reduces the memory usage and the number
+// of allocations required to store large trees.
+package arena
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+	"github.com/bblfsh/sdk/v3/uast/nodes/nodesproto"
+)
+
+// ref is a reference to a node in the tree. Zero value is reserved for nil nodes,
+// other values are indexes in the node list, shifted by one.
+type ref uint32
+
+const nilRef = ref(0)
+
+// kind is a compact representation of nodes.Kind.
+type kind uint8
+
+const (
+	kindObject kind = iota
+	kindArray
+	kindString
+	kindInt
+	kindUint
+	kindFloat
+	kindBool
+)
+
+// node is a single tree node.
+//
+// For objects, refs[off:off+size] contains string indexes of the keys, and refs[off+size:off+2*size]
+// contains references to the values. For arrays, refs[off:off+size] contains references to elements.
+// For strings, off is an index in the string table, for other numeric values it's an index in the nums
+// array. Boolean values store their value in off.
+type node struct {
+	kind kind
+	off  uint32
+	size uint32
+}
+
+// Tree is a compact immutable tree. It is safe for concurrent use.
+type Tree struct {
+	strs  []string
+	nodes []node
+	refs  []uint32
+	nums  []uint64
+	root  ref
+}
+
+// Root returns the root node of the tree.
+func (t *Tree) Root() nodes.External {
+	return t.external(t.root)
+}
+
+// Len returns the number of non-nil nodes stored in the tree.
+func (t *Tree) Len() int {
+	return len(t.nodes)
+}
+
+// Strings returns the number of unique strings stored in the tree, including object keys.
+func (t *Tree) Strings() int {
+	return len(t.strs)
+}
+
+func (t *Tree) node(r ref) *node {
+	return &t.nodes[r-1]
+}
+
+// external returns an External node implementation for a given reference.
+func (t *Tree) external(r ref) nodes.External {
+	if r == nilRef {
+		return nil
+	}
+	n := t.node(r)
+	switch n.kind {
+	case kindObject:
+		return object{t: t, r: r}
+	case kindArray:
+		return array{t: t, r: r}
+	case kindString:
+		return nodes.String(t.strs[n.off])
+	case kindInt:
+		return nodes.Int(int64(t.nums[n.off]))
+	case kindUint:
+		return nodes.Uint(t.nums[n.off])
+	case kindFloat:
+		return nodes.Float(math.Float64frombits(t.nums[n.off]))
+	case kindBool:
+		return nodes.Bool(n.off != 0)
+	}
+	panic(fmt.Errorf("unexpected node kind: %v", n.kind))
+}
+
+var (
+	_ nodes.ExternalObject = object{}
+	_ nodes.ExternalArray  = array{}
+)
+
+type object struct {
+	t *Tree
+	r ref
+}
+
+func (object) Kind() nodes.Kind {
+	return nodes.KindObject
+}
+
+func (object) Value() nodes.Value {
+	return nil
+}
+
+func (o object) SameAs(n nodes.External) bool {
+	o2, ok := n.(object)
+	return ok && o == o2
+}
+
+func (o object) Size() int {
+	return int(o.t.node(o.r).size)
+}
+
+func (o object) keys() []uint32 {
+	n := o.t.node(o.r)
+	return o.t.refs[n.off : n.off+n.size]
+}
+
+func (o object) values() []uint32 {
+	n := o.t.node(o.r)
+	return o.t.refs[n.off+n.size : n.off+2*n.size]
+}
+
+func (o object) Keys() []string {
+	keys := o.keys()
+	out := make([]string, len(keys))
+	for i, k := range keys {
+		out[i] = o.t.strs[k]
+	}
+	return out
+}
+
+func (o object) ValueAt(key string) (nodes.External, bool) {
+	keys := o.keys()
+	i := sort.Search(len(keys), func(i int) bool {
+		return o.t.strs[keys[i]] >= key
+	})
+	if i >= len(keys) || o.t.strs[keys[i]] != key {
+		return nil, false
+	}
+	return o.t.external(ref(o.values()[i])), true
+}
+
+type array struct {
+	t *Tree
+	r ref
+}
+
+func (array) Kind() nodes.Kind {
+	return nodes.KindArray
+}
+
+func (array) Value() nodes.Value {
+	return nil
+}
+
+func (a array) SameAs(n nodes.External) bool {
+	a2, ok := n.(array)
+	return ok && a == a2
+}
+
+func (a array) Size() int {
+	return int(a.t.node(a.r).size)
+}
+
+func (a array) ValueAt(i int) nodes.External {
+	n := a.t.node(a.r)
+	if i < 0 || i >= int(n.size) {
+		return nil
+	}
+	return a.t.external(ref(a.t.refs[int(n.off)+i]))
+}
+
+// builder incrementally constructs a tree.
+type builder struct {
+	t    *Tree
+	strs map[string]uint32
+	nums map[uint64]uint32
+	// stack stores references to children of nodes that are being built
+	stack []ref
+}
+
+func newBuilder() *builder {
+	return &builder{
+		t:    &Tree{},
+		strs: make(map[string]uint32),
+		nums: make(map[uint64]uint32),
+	}
+}
+
+func (b *builder) str(s string) uint32 {
+	if i, ok := b.strs[s]; ok {
+		return i
+	}
+	i := uint32(len(b.t.strs))
+	b.t.strs = append(b.t.strs, s)
+	b.strs[s] = i
+	return i
+}
+
+func (b *builder) num(v uint64) uint32 {
+	if i, ok := b.nums[v]; ok {
+		return i
+	}
+	i := uint32(len(b.t.nums))
+	b.t.nums = append(b.t.nums, v)
+	b.nums[v] = i
+	return i
+}
+
+func (b *builder) add(n node) (ref, error) {
+	if len(b.t.nodes) >= math.MaxUint32-1 {
+		return nilRef, fmt.Errorf("too many nodes")
+	}
+	b.t.nodes = append(b.t.nodes, n)
+	return ref(len(b.t.nodes)), nil
+}
+
+func (b *builder) addValue(v nodes.Value) (ref, error) {
+	switch v := v.(type) {
+	case nil:
+		return nilRef, nil
+	case nodes.String:
+		return b.add(node{kind: kindString, off: b.str(string(v))})
+	case nodes.Int:
+		return b.add(node{kind: kindInt, off: b.num(uint64(v))})
+	case nodes.Uint:
+		return b.add(node{kind: kindUint, off: b.num(uint64(v))})
+	case nodes.Float:
+		return b.add(node{kind: kindFloat, off: b.num(math.Float64bits(float64(v)))})
+	case nodes.Bool:
+		off := uint32(0)
+		if v {
+			off = 1
+		}
+		return b.add(node{kind: kindBool, off: off})
+	}
+	return nilRef, fmt.Errorf("unsupported value type: %T", v)
+}
+
+// addObject adds an object node with given keys and values. Keys must be sorted.
+func (b *builder) addObject(keys []string, vals []ref) (ref, error) {
+	off := uint32(len(b.t.refs))
+	for _, k := range keys {
+		b.t.refs = append(b.t.refs, b.str(k))
+	}
+	for _, v := range vals {
+		b.t.refs = append(b.t.refs, uint32(v))
+	}
+	return b.add(node{kind: kindObject, off: off, size: uint32(len(keys))})
+}
+
+func (b *builder) addArray(vals []ref) (ref, error) {
+	off := uint32(len(b.t.refs))
+	for _, v := range vals {
+		b.t.refs = append(b.t.refs, uint32(v))
+	}
+	return b.add(node{kind: kindArray, off: off, size: uint32(len(vals))})
+}
+
+// finish sets the root of the tree and releases unused memory.
+func (b *builder) finish(root ref) *Tree {
+	t := b.t
+	t.root = root
+	t.strs = append([]string(nil), t.strs...)
+	t.nodes = append([]node(nil), t.nodes...)
+	t.refs = append([]uint32(nil), t.refs...)
+	t.nums = append([]uint64(nil), t.nums...)
+	return t
+}
+
+// Build creates a compact tree from an existing tree.
+func Build(root nodes.External) (*Tree, error) {
+	b := newBuilder()
+	r, err := b.build(root)
+	if err != nil {
+		return nil, err
+	}
+	return b.finish(r), nil
+}
+
+// build adds the node with all its children to the tree. Children are stored before the parent.
+func (b *builder) build(n nodes.External) (ref, error) {
+	switch kind := nodes.KindOf(n); kind {
+	case nodes.KindNil:
+		return nilRef, nil
+	case nodes.KindObject:
+		obj, ok := n.(nodes.ExternalObject)
+		if !ok {
+			return nilRef, fmt.Errorf("node is an object, but an interface implementation is missing: %T", n)
+		}
+		keys := obj.Keys()
+		if !sort.StringsAreSorted(keys) {
+			keys = append([]string{}, keys...)
+			sort.Strings(keys)
+		}
+		start := len(b.stack)
+		for _, k := range keys {
+			v, _ := obj.ValueAt(k)
+			r, err := b.build(v)
+			if err != nil {
+				return nilRef, err
+			}
+			b.stack = append(b.stack, r)
+		}
+		r, err := b.addObject(keys, b.stack[start:])
+		b.stack = b.stack[:start]
+		return r, err
+	case nodes.KindArray:
+		arr, ok := n.(nodes.ExternalArray)
+		if !ok {
+			return nilRef, fmt.Errorf("node is an array, but an interface implementation is missing: %T", n)
+		}
+		start := len(b.stack)
+		sz := arr.Size()
+		for i := 0; i < sz; i++ {
+			r, err := b.build(arr.ValueAt(i))
+			if err != nil {
+				return nilRef, err
+			}
+			b.stack = append(b.stack, r)
+		}
+		r, err := b.addArray(b.stack[start:])
+		b.stack = b.stack[:start]
+		return r, err
+	default:
+		return b.addValue(n.Value())
+	}
+}
+
+// Read reads a binary graph in nodesproto format and stores it as a compact tree.
+//
+// Nodes that are referenced multiple times in the graph are stored only once.
+// If the graph is cyclic, an error is returned.
+func Read(r io.Reader) (*Tree, error) {
+	g, err := nodesproto.ReadRaw(r)
+	if err != nil {
+		return nil, err
+	}
+	return FromRaw(g)
+}
+
+// FromRaw creates a compact tree from a raw nodesproto graph. See Read for details.
+func FromRaw(g *nodesproto.RawGraph) (*Tree, error) {
+	c := &rawConverter{
+		b:    newBuilder(),
+		g:    g,
+		refs: make(map[uint64]ref, len(g.Nodes)),
+		busy: make(map[uint64]struct{}),
+	}
+	r, err := c.convert(g.Root)
+	if err != nil {
+		return nil, err
+	}
+	return c.b.finish(r), nil
+}
+
+type rawConverter struct {
+	b    *builder
+	g    *nodesproto.RawGraph
+	refs map[uint64]ref      // converted nodes
+	busy map[uint64]struct{} // nodes that are being converted; used to detect cycles
+}
+
+func (c *rawConverter) convert(id uint64) (ref, error) {
+	if id == 0 {
+		return nilRef, nil
+	}
+	if r, ok := c.refs[id]; ok {
+		return r, nil
+	}
+	if _, ok := c.busy[id]; ok {
+		return nilRef, fmt.Errorf("cycle detected at node %d", id)
+	}
+	n, ok := c.g.Nodes[id]
+	if !ok {
+		return nilRef, fmt.Errorf("node %d is not defined", id)
+	}
+	c.busy[id] = struct{}{}
+	defer delete(c.busy, id)
+
+	var (
+		r   ref
+		err error
+	)
+	switch n.Kind {
+	case nodes.KindObject:
+		if len(n.Keys) != len(n.Values) {
+			return nilRef, fmt.Errorf("node %d: number of keys and values doesn't match", id)
+		}
+		type field struct {
+			key string
+			val uint64
+		}
+		fields := make([]field, len(n.Keys))
+		for i, kid := range n.Keys {
+			k, ok := c.g.Nodes[kid]
+			if !ok {
+				return nilRef, fmt.Errorf("node %d: key %d is not defined", id, kid)
+			}
+			s, ok := k.Value.(nodes.String)
+			if !ok {
+				return nilRef, fmt.Errorf("node %d: key %d is not a string", id, kid)
+			}
+			fields[i] = field{key: string(s), val: n.Values[i]}
+		}
+		sort.Slice(fields, func(i, j int) bool {
+			return fields[i].key < fields[j].key
+		})
+		keys := make([]string, len(fields))
+		start := len(c.b.stack)
+		for i, f := range fields {
+			keys[i] = f.key
+			if r, err = c.convert(f.val); err != nil {
+				return nilRef, err
+			}
+			c.b.stack = append(c.b.stack, r)
+		}
+		r, err = c.b.addObject(keys, c.b.stack[start:])
+		c.b.stack = c.b.stack[:start]
+	case nodes.KindArray:
+		start := len(c.b.stack)
+		for _, v := range n.Values {
+			if r, err = c.convert(v); err != nil {
+				return nilRef, err
+			}
+			c.b.stack = append(c.b.stack, r)
+		}
+		r, err = c.b.addArray(c.b.stack[start:])
+		c.b.stack = c.b.stack[:start]
+	default:
+		r, err = c.b.addValue(n.Value)
+	}
+	if err != nil {
+		return nilRef, err
+	}
+	c.refs[id] = r
+	return r, nil
+}
@@ -0,0 +1,165 @@
+package arena
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+	"github.com/bblfsh/sdk/v3/uast/nodes/nodesproto"
+	"github.com/bblfsh/sdk/v3/uast/nodes/nodestest"
+	"github.com/stretchr/testify/require"
+)
+
+var treeCases = []struct {
+	name string
+	in   nodes.Node
+}{
+	{name: "nil"},
+	{name: "value", in: nodes.String("a")},
+	{name: "empty object", in: nodes.Object{}},
+	{name: "empty array", in: nodes.Array{}},
+	{
+		name: "all kinds",
+		in: nodes.Object{
+			"@type": nodes.String("node"),
+			"arr": nodes.Array{
+				nodes.String("A"), nil, nodes.Bool(true), nodes.Bool(false),
+			},
+			"int":   nodes.Int(-42),
+			"uint":  nodes.Uint(42),
+			"float": nodes.Float(4.2),
+			"nil":   nil,
+			"obj": nodes.Object{
+				"@type": nodes.String("node"),
+				"int":   nodes.Int(42),
+			},
+		},
+	},
+}
+
+func TestBuild(t *testing.T) {
+	for _, c := range treeCases {
+		t.Run(c.name, func(t *testing.T) {
+			tr, err := Build(c.in)
+			require.NoError(t, err)
+			require.True(t, nodes.Equal(c.in, tr.Root()))
+
+			n, err := nodes.ToNode(tr.Root(), nil)
+			require.NoError(t, err)
+			require.Equal(t, c.in, n)
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	for _, c := range treeCases {
+		if c.in == nil {
+			continue
+		}
+		t.Run(c.name, func(t *testing.T) {
+			buf := bytes.NewBuffer(nil)
+			err := nodesproto.WriteTo(buf, c.in)
+			require.NoError(t, err)
+
+			tr, err := Read(buf)
+			require.NoError(t, err)
+			require.True(t, nodes.Equal(c.in, tr.Root()))
+		})
+	}
+}
+
+func TestTree(t *testing.T) {
+	tr, err := Build(treeCases[len(treeCases)-1].in)
+	require.NoError(t, err)
+	require.Equal(t, 12, tr.Len())
+	// keys and string values share the same table
+	require.Equal(t, 9, tr.Strings())
+
+	root := tr.Root().(nodes.ExternalObject)
+	require.Equal(t, []string{"@type", "arr", "float", "int", "nil", "obj", "uint"}, root.Keys())
+
+	v, ok := root.ValueAt("nil")
+	require.True(t, ok)
+	require.Nil(t, v)
+	_, ok = root.ValueAt("missing")
+	require.False(t, ok)
+
+	o1, _ := root.ValueAt("obj")
+	o2, _ := root.ValueAt("obj")
+	require.True(t, nodes.Same(o1, o2))
+	arr, _ := root.ValueAt("arr")
+	require.False(t, nodes.Same(o1, arr))
+}
+
+func walkAll(n nodes.External) int {
+	cnt := 0
+	nodes.WalkPreOrderExt(n, func(n nodes.External) bool {
+		cnt++
+		return true
+	})
+	return cnt
+}
+
+func BenchmarkBuild(b *testing.B) {
+	tree := nodestest.GenTree(5, 6)
+	buf := bytes.NewBuffer(nil)
+	if err := nodesproto.WriteTo(buf, tree); err != nil {
+		b.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	b.Run("map", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, err := nodesproto.ReadTree(bytes.NewReader(data))
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("arena", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, err := Read(bytes.NewReader(data))
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("arena from node", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, err := Build(tree)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkWalk(b *testing.B) {
+	tree := nodestest.GenTree(5, 6)
+	tr, err := Build(tree)
+	if err != nil {
+		b.Fatal(err)
+	}
+	exp := walkAll(tree)
+
+	b.Run("map", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if walkAll(tree) != exp {
+				b.Fatal("unexpected count")
+			}
+		}
+	})
+	b.Run("arena", func(b *testing.B) {
+		b.ReportAllocs()
+		root := tr.Root()
+		for i := 0; i < b.N; i++ {
+			if walkAll(root) != exp {
+				b.Fatal("unexpected count")
+			}
+		}
+	})
+}
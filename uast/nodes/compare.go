@@ -0,0 +1,240 @@
+package nodes
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// kindRank returns the position of a node kind in the total order defined by Compare.
+func kindRank(k Kind) int {
+	switch k {
+	case KindNil:
+		return 0
+	case KindBool:
+		return 1
+	case KindInt, KindUint, KindFloat:
+		return 2
+	case KindString:
+		return 3
+	case KindArray:
+		return 4
+	case KindObject:
+		return 5
+	}
+	return 6
+}
+
+// Compare defines a total order for nodes. It returns -1 if a < b, 0 if a == b and +1 if a > b.
+//
+// Nodes of different kinds are sorted in the following order: nil, Bool, numbers (Int, Uint, Float),
+// String, Array, Object. Numbers are compared by value regardless of their type. If an Int or Uint is
+// numerically equal to a Float, the integer is sorted first. NaN is sorted before all other numbers.
+//
+// Arrays are compared element by element, and a shorter array is sorted first if it's a prefix of
+// another array. Objects are compared in the same way, as a list of key-value pairs sorted by key.
+//
+// Compare returns 0 for nodes that are considered equal by Equal.
+func Compare(a, b External) int {
+	ka, kb := KindOf(a), KindOf(b)
+	if ra, rb := kindRank(ka), kindRank(kb); ra != rb {
+		if ra < rb {
+			return -1
+		}
+		return +1
+	}
+	switch ka {
+	case KindNil:
+		return 0
+	case KindBool:
+		va, _ := a.Value().(Bool)
+		vb, _ := b.Value().(Bool)
+		if va == vb {
+			return 0
+		} else if !va {
+			return -1
+		}
+		return +1
+	case KindString:
+		va, _ := a.Value().(String)
+		vb, _ := b.Value().(String)
+		return strings.Compare(string(va), string(vb))
+	case KindInt, KindUint, KindFloat:
+		return compareNumbers(a.Value(), b.Value())
+	case KindArray:
+		return compareArrays(a, b)
+	case KindObject:
+		return compareObjects(a, b)
+	}
+	return 0
+}
+
+// Less reports whether a is less than b. See Compare for details.
+func Less(a, b External) bool {
+	return Compare(a, b) < 0
+}
+
+// Sort sorts a list of nodes according to the order defined by Compare.
+// The sort is stable.
+func Sort(list []External) {
+	sort.SliceStable(list, func(i, j int) bool {
+		return Compare(list[i], list[j]) < 0
+	})
+}
+
+// SortNodes sorts a list of nodes according to the order defined by Compare.
+// The sort is stable.
+func SortNodes(list []Node) {
+	sort.SliceStable(list, func(i, j int) bool {
+		return Compare(list[i], list[j]) < 0
+	})
+}
+
+func cmpInt(a, b int64) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return +1
+	}
+	return 0
+}
+
+func cmpUint(a, b uint64) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return +1
+	}
+	return 0
+}
+
+// compareNumbers compares two numeric values.
+func compareNumbers(a, b Value) int {
+	switch a := a.(type) {
+	case Int:
+		switch b := b.(type) {
+		case Int:
+			return cmpInt(int64(a), int64(b))
+		case Uint:
+			if a < 0 {
+				return -1
+			}
+			return cmpUint(uint64(a), uint64(b))
+		case Float:
+			return compareIntFloat(a, b)
+		}
+	case Uint:
+		switch b := b.(type) {
+		case Int:
+			return -compareNumbers(b, a)
+		case Uint:
+			return cmpUint(uint64(a), uint64(b))
+		case Float:
+			return compareUintFloat(a, b)
+		}
+	case Float:
+		switch b := b.(type) {
+		case Int:
+			return -compareIntFloat(b, a)
+		case Uint:
+			return -compareUintFloat(b, a)
+		case Float:
+			fa, fb := float64(a), float64(b)
+			if na, nb := math.IsNaN(fa), math.IsNaN(fb); na || nb {
+				if na && nb {
+					return 0
+				} else if na {
+					return -1
+				}
+				return +1
+			}
+			if fa < fb {
+				return -1
+			} else if fa > fb {
+				return +1
+			}
+			return 0
+		}
+	}
+	return 0
+}
+
+// compareIntFloat compares an integer to a float. Integers are sorted before equal floats.
+func compareIntFloat(a Int, b Float) int {
+	f := float64(b)
+	if math.IsNaN(f) {
+		return +1
+	}
+	if fa := float64(a); fa < f {
+		return -1
+	} else if fa > f {
+		return +1
+	}
+	// float representations are equal, but the integer might not fit into a float exactly
+	if f >= math.MinInt64 && f < math.MaxInt64 {
+		if c := cmpInt(int64(a), int64(f)); c != 0 {
+			return c
+		}
+	}
+	return -1
+}
+
+// compareUintFloat compares an unsigned integer to a float. Integers are sorted before equal floats.
+func compareUintFloat(a Uint, b Float) int {
+	f := float64(b)
+	if math.IsNaN(f) {
+		return +1
+	}
+	if fa := float64(a); fa < f {
+		return -1
+	} else if fa > f {
+		return +1
+	}
+	if f >= 0 && f < math.MaxUint64 {
+		if c := cmpUint(uint64(a), uint64(f)); c != 0 {
+			return c
+		}
+	}
+	return -1
+}
+
+func compareArrays(a, b External) int {
+	aa, _ := a.(ExternalArray)
+	ab, _ := b.(ExternalArray)
+	var sa, sb int
+	if aa != nil {
+		sa = aa.Size()
+	}
+	if ab != nil {
+		sb = ab.Size()
+	}
+	for i := 0; i < sa && i < sb; i++ {
+		if c := Compare(aa.ValueAt(i), ab.ValueAt(i)); c != 0 {
+			return c
+		}
+	}
+	return cmpInt(int64(sa), int64(sb))
+}
+
+func compareObjects(a, b External) int {
+	oa, _ := a.(ExternalObject)
+	ob, _ := b.(ExternalObject)
+	var ka, kb []string
+	if oa != nil {
+		ka = oa.Keys()
+	}
+	if ob != nil {
+		kb = ob.Keys()
+	}
+	for i := 0; i < len(ka) && i < len(kb); i++ {
+		if c := strings.Compare(ka[i], kb[i]); c != 0 {
+			return c
+		}
+		va, _ := oa.ValueAt(ka[i])
+		vb, _ := ob.ValueAt(kb[i])
+		if c := Compare(va, vb); c != 0 {
+			return c
+		}
+	}
+	return cmpInt(int64(len(ka)), int64(len(kb)))
+}
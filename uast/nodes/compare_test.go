@@ -0,0 +1,76 @@
+package nodes
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareOrder(t *testing.T) {
+	// all nodes are listed in ascending order
+	sorted := []External{
+		nil,
+		Bool(false),
+		Bool(true),
+		Float(math.NaN()),
+		Float(math.Inf(-1)),
+		Int(-5),
+		Float(-1.5),
+		Int(0),
+		Float(0),
+		Uint(1),
+		Float(1.5),
+		Int(math.MaxInt64),
+		Uint(math.MaxUint64),
+		String(""),
+		String("a"),
+		String("b"),
+		Array{},
+		Array{Int(1)},
+		Array{Int(1), Int(2)},
+		Array{Int(2)},
+		Object{},
+		Object{"a": Int(1)},
+		Object{"a": Int(1), "b": nil},
+		Object{"a": Int(2)},
+		Object{"b": Int(0)},
+	}
+	for i, a := range sorted {
+		for j, b := range sorted {
+			exp := 0
+			if i < j {
+				exp = -1
+			} else if i > j {
+				exp = +1
+			}
+			require.Equal(t, exp, Compare(a, b), "%v vs %v", a, b)
+		}
+	}
+
+	list := make([]External, len(sorted))
+	for i := range sorted {
+		list[i] = sorted[len(sorted)-1-i]
+	}
+	Sort(list)
+	require.Equal(t, len(sorted), len(list))
+	for i := range list {
+		require.Equal(t, 0, Compare(sorted[i], list[i]))
+	}
+}
+
+func TestCompareEqual(t *testing.T) {
+	for _, c := range casesEqual {
+		n2 := c.n2
+		if n2 == nil {
+			n2 = c.n1
+		}
+		if c.exp {
+			require.Equal(t, 0, Compare(c.n1, n2), c.name)
+		} else if KindOf(c.n1) == KindOf(n2) {
+			require.NotEqual(t, 0, Compare(c.n1, n2), c.name)
+		}
+	}
+	require.Equal(t, 0, Compare(Int(1), Uint(1)))
+	require.True(t, Less(Int(-1), Uint(0)))
+}
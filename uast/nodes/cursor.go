@@ -0,0 +1,194 @@
+package nodes
+
+import "fmt"
+
+// Cursor describes a node encountered during ApplyCursor.
+// Information about the node and its parent is available via Node, Parent, Key and Index methods.
+//
+// Replace, Delete, InsertBefore and InsertAfter methods can be used to modify the tree.
+// Cursor is only valid during the callback call.
+type Cursor struct {
+	a      *applier
+	parent Node
+	key    string
+	index  int
+	node   Node
+
+	changed bool
+	deleted bool
+	before  []Node
+	after   []Node
+}
+
+// Node returns the current node.
+func (c *Cursor) Node() Node {
+	return c.node
+}
+
+// Parent returns the parent of the current node. It returns nil for the root node.
+//
+// The parent node is the one from the original tree, it does not reflect any changes made to it.
+func (c *Cursor) Parent() Node {
+	return c.parent
+}
+
+// Key returns a field name of the current node in the parent object.
+// It returns an empty string if the parent is not an object.
+func (c *Cursor) Key() string {
+	return c.key
+}
+
+// Index returns an index of the current node in the parent array.
+// It returns -1 if the parent is not an array.
+func (c *Cursor) Index() int {
+	return c.index
+}
+
+// Path returns the path of the current node in the original tree.
+func (c *Cursor) Path() Path {
+	return c.a.path.Join()
+}
+
+// Replace replaces the current node with n.
+//
+// If called from the pre-order callback, children of the new node will be walked instead of the old ones.
+func (c *Cursor) Replace(n Node) {
+	if c.deleted {
+		panic(fmt.Errorf("node at %v was deleted", c.Path()))
+	}
+	c.node = n
+	c.changed = true
+}
+
+// Delete removes the current node from its parent object or array.
+// If called for the root node, the result of ApplyCursor will be nil.
+//
+// Children of the deleted node will not be walked, and the post-order callback will not be called for it.
+func (c *Cursor) Delete() {
+	c.deleted = true
+}
+
+// InsertBefore inserts n before the current node in its parent array.
+// It panics if the parent is not an array. ApplyCursor does not walk n.
+func (c *Cursor) InsertBefore(n Node) {
+	if _, ok := c.parent.(Array); !ok {
+		panic(fmt.Errorf("InsertBefore: parent of %v is not an array", c.Path()))
+	}
+	c.before = append(c.before, n)
+}
+
+// InsertAfter inserts n after the current node in its parent array.
+// It panics if the parent is not an array. ApplyCursor does not walk n.
+func (c *Cursor) InsertAfter(n Node) {
+	if _, ok := c.parent.(Array); !ok {
+		panic(fmt.Errorf("InsertAfter: parent of %v is not an array", c.Path()))
+	}
+	// nodes inserted later must be closer to the current node
+	c.after = append([]Node{n}, c.after...)
+}
+
+// ApplyCursor traverses the tree recursively, calling pre and post functions for each node.
+// Either of functions may be nil. Object fields are visited in the order of sorted keys.
+//
+// If pre returns false, no children of the current node are traversed, and post is not called for it.
+// If post returns false, the traversal is stopped, and ApplyCursor returns immediately.
+//
+// ApplyCursor returns a new root node and a flag that indicates if the tree was changed or not.
+// The original tree is never modified; only nodes along the modified paths are copied.
+func ApplyCursor(root Node, pre, post func(c *Cursor) bool) (Node, bool) {
+	a := &applier{pre: pre, post: post}
+	c := &Cursor{a: a, index: -1, node: root}
+	a.apply(c)
+	if c.deleted {
+		return nil, true
+	}
+	return c.node, c.changed
+}
+
+type applier struct {
+	pre, post func(c *Cursor) bool
+	path      Path
+	abort     bool
+}
+
+func (a *applier) apply(c *Cursor) {
+	if a.pre != nil && !a.pre(c) {
+		return
+	}
+	if c.deleted {
+		return
+	}
+	if n, ok := a.applyChildren(c.node); ok {
+		c.node = n
+		c.changed = true
+	}
+	if a.abort || c.deleted {
+		return
+	}
+	if a.post != nil && !a.post(c) {
+		a.abort = true
+	}
+}
+
+func (a *applier) applyChildren(n Node) (Node, bool) {
+	switch n := n.(type) {
+	case Object:
+		var out Object
+		for _, k := range n.Keys() {
+			if a.abort {
+				break
+			}
+			c := &Cursor{a: a, parent: n, key: k, index: -1, node: n[k]}
+			a.path = append(a.path, k)
+			a.apply(c)
+			a.path = a.path[:len(a.path)-1]
+			if !c.deleted && !c.changed {
+				continue
+			}
+			if out == nil {
+				out = n.CloneObject()
+			}
+			if c.deleted {
+				delete(out, k)
+			} else {
+				out[k] = c.node
+			}
+		}
+		if out == nil {
+			return n, false
+		}
+		return out, true
+	case Array:
+		var out Array
+		for i, v := range n {
+			if a.abort {
+				if out != nil {
+					out = append(out, n[i:]...)
+				}
+				break
+			}
+			c := &Cursor{a: a, parent: n, index: i, node: v}
+			a.path = append(a.path, i)
+			a.apply(c)
+			a.path = a.path[:len(a.path)-1]
+			modified := c.deleted || c.changed || len(c.before) != 0 || len(c.after) != 0
+			if out == nil {
+				if !modified {
+					continue
+				}
+				out = make(Array, 0, len(n))
+				out = append(out, n[:i]...)
+			}
+			out = append(out, c.before...)
+			if !c.deleted {
+				out = append(out, c.node)
+			}
+			out = append(out, c.after...)
+		}
+		if out == nil {
+			return n, false
+		}
+		return out, true
+	}
+	return n, false
+}
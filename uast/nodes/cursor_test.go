@@ -0,0 +1,113 @@
+package nodes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyCursor(t *testing.T) {
+	untouched := Object{"name": String("b")}
+	root := Object{
+		"body": Array{
+			Object{"name": String("a"), "drop": Bool(true)},
+			untouched,
+			String("comment"),
+			Int(1),
+		},
+		"kind": String("block"),
+	}
+	orig := root.Clone()
+
+	var paths []string
+	out, changed := ApplyCursor(root, func(c *Cursor) bool {
+		paths = append(paths, c.Path().String())
+		switch n := c.Node().(type) {
+		case String:
+			if n == "comment" {
+				c.Delete()
+			} else if c.Key() == "kind" {
+				c.Replace(String("BLOCK"))
+			}
+		case Int:
+			c.InsertBefore(Int(0))
+			c.InsertAfter(Int(3))
+			c.InsertAfter(Int(2))
+		}
+		if c.Key() == "drop" {
+			c.Delete()
+		}
+		return true
+	}, func(c *Cursor) bool {
+		if n, ok := c.Node().(Int); ok {
+			require.Equal(t, 3, c.Index())
+			c.Replace(n * 10)
+		}
+		return true
+	})
+	require.True(t, changed)
+	require.Equal(t, Object{
+		"body": Array{
+			Object{"name": String("a")},
+			untouched,
+			Int(0), Int(10), Int(2), Int(3),
+		},
+		"kind": String("BLOCK"),
+	}, out)
+	require.Equal(t, orig, root, "original tree was modified")
+	require.Equal(t, []string{
+		"", "/body", "/body/0", "/body/0/drop", "/body/0/name",
+		"/body/1", "/body/1/name", "/body/2", "/body/3", "/kind",
+	}, paths)
+
+	// unchanged nodes must be reused
+	require.True(t, Same(untouched, out.(Object)["body"].(Array)[1]))
+}
+
+func TestApplyCursorSkipAndAbort(t *testing.T) {
+	root := Array{
+		Object{"k": Int(1)},
+		Object{"k": Int(2)},
+		Object{"k": Int(3)},
+	}
+	out, changed := ApplyCursor(root, func(c *Cursor) bool {
+		if c.Index() == 0 {
+			// skip the first object
+			return false
+		}
+		return true
+	}, func(c *Cursor) bool {
+		if v, ok := c.Node().(Int); ok {
+			c.Replace(v + 1)
+			// stop after the first change
+			return false
+		}
+		return true
+	})
+	require.True(t, changed)
+	require.Equal(t, Array{
+		Object{"k": Int(1)},
+		Object{"k": Int(3)},
+		Object{"k": Int(3)},
+	}, out)
+
+	out, changed = ApplyCursor(root, nil, nil)
+	require.False(t, changed)
+	require.True(t, Same(root, out))
+
+	out, changed = ApplyCursor(root, func(c *Cursor) bool {
+		c.Delete()
+		return true
+	}, nil)
+	require.True(t, changed)
+	require.Nil(t, out)
+
+	require.Panics(t, func() {
+		ApplyCursor(root, func(c *Cursor) bool {
+			if c.Key() == "k" {
+				c.InsertAfter(Int(0))
+			}
+			return true
+		}, nil)
+	})
+}
@@ -0,0 +1,301 @@
+// Package diff computes structural differences between two trees and allows to replay them.
+package diff
+
+import (
+	"strconv"
+
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+)
+
+// Op is a type of the edit operation.
+type Op int
+
+const (
+	// Insert adds a new field to an object or a new element to an array at a given path.
+	// Elements of the array at this index or after it are shifted to the right.
+	Insert = Op(iota + 1)
+	// Delete removes an object field or an array element at a given path.
+	Delete
+	// Replace sets a new value at the given path.
+	Replace
+	// Move removes an array element at From path and inserts it at a given path.
+	// Both paths must point to the same array.
+	Move
+)
+
+func (op Op) String() string {
+	switch op {
+	case Insert:
+		return "insert"
+	case Delete:
+		return "delete"
+	case Replace:
+		return "replace"
+	case Move:
+		return "move"
+	}
+	return "Op(" + strconv.Itoa(int(op)) + ")"
+}
+
+// Change is a single edit operation.
+type Change struct {
+	Op Op
+	// Path is the location of the node the operation applies to.
+	Path nodes.Path
+	// From is the source location for the Move operation.
+	From nodes.Path
+	// Value is a new node for Insert and Replace operations.
+	Value nodes.Node
+}
+
+// Patch is an ordered list of changes.
+//
+// Path in each change assumes that all previous changes were already applied to the tree.
+type Patch []Change
+
+// Changes computes an edit script that transforms tree a to tree b.
+//
+// Unchanged subtrees are matched by their hashes (see nodes.HashOf). Elements of an array are
+// aligned using the longest common subsequence, and the elements that changed their position
+// are reported as Move operations. Moves are only detected within the same array.
+func Changes(a, b nodes.Node) Patch {
+	h := nodes.NewHasher()
+	h.Cache = nodes.NewHashCache()
+	d := &differ{hasher: h}
+	d.diff(nil, a, b)
+	return d.out
+}
+
+type differ struct {
+	hasher *nodes.Hasher
+	out    Patch
+}
+
+func (d *differ) hash(n nodes.Node) nodes.Hash {
+	return d.hasher.HashOf(n)
+}
+
+func (d *differ) emit(c Change) {
+	d.out = append(d.out, c)
+}
+
+func (d *differ) diff(path nodes.Path, a, b nodes.Node) {
+	if nodes.KindOf(a) != nodes.KindOf(b) {
+		d.emit(Change{Op: Replace, Path: path, Value: b})
+		return
+	}
+	switch a := a.(type) {
+	case nil:
+		return
+	case nodes.Object:
+		if d.hash(a) == d.hash(b) {
+			return
+		}
+		d.diffObject(path, a, b.(nodes.Object))
+	case nodes.Array:
+		if d.hash(a) == d.hash(b) {
+			return
+		}
+		d.diffArray(path, a, b.(nodes.Array))
+	default:
+		if !nodes.NodeEqual(a, b) {
+			d.emit(Change{Op: Replace, Path: path, Value: b})
+		}
+	}
+}
+
+func (d *differ) diffObject(path nodes.Path, a, b nodes.Object) {
+	for _, k := range a.Keys() {
+		if _, ok := b[k]; !ok {
+			d.emit(Change{Op: Delete, Path: path.Join(k)})
+		}
+	}
+	for _, k := range b.Keys() {
+		bv := b[k]
+		av, ok := a[k]
+		if !ok {
+			d.emit(Change{Op: Insert, Path: path.Join(k), Value: bv})
+			continue
+		}
+		d.diff(path.Join(k), av, bv)
+	}
+}
+
+// token identifies an element of the array that is being edited.
+type token struct {
+	src int // index in the source array, or -1 for new elements
+	dst int // index in the destination array, or -1 for deleted elements
+}
+
+func (d *differ) diffArray(path nodes.Path, a, b nodes.Array) {
+	ha := make([]nodes.Hash, len(a))
+	for i, v := range a {
+		ha[i] = d.hash(v)
+	}
+	hb := make([]nodes.Hash, len(b))
+	for i, v := range b {
+		hb[i] = d.hash(v)
+	}
+	// src2dst and dst2src store the final mapping between elements of both arrays
+	src2dst := make([]int, len(a))
+	for i := range src2dst {
+		src2dst[i] = -1
+	}
+	dst2src := make([]int, len(b))
+	for i := range dst2src {
+		dst2src[i] = -1
+	}
+	// align unchanged elements first
+	anchors := lcs(ha, hb)
+	for _, m := range anchors {
+		src2dst[m[0]], dst2src[m[1]] = m[1], m[0]
+	}
+	// detect unchanged elements that were moved
+	free := make(map[nodes.Hash][]int)
+	for i := range a {
+		if src2dst[i] < 0 {
+			free[ha[i]] = append(free[ha[i]], i)
+		}
+	}
+	moved := make(map[int]bool)
+	for j := range b {
+		if dst2src[j] >= 0 {
+			continue
+		}
+		if l := free[hb[j]]; len(l) != 0 {
+			i := l[0]
+			free[hb[j]] = l[1:]
+			src2dst[i], dst2src[j] = j, i
+			moved[j] = true
+		}
+	}
+	// pair remaining elements between the same anchors, so they can be diffed recursively
+	var paired []int
+	pi, pj := 0, 0
+	for k := 0; k <= len(anchors); k++ {
+		ei, ej := len(a), len(b)
+		if k < len(anchors) {
+			ei, ej = anchors[k][0], anchors[k][1]
+		}
+		for pi < ei && pj < ej {
+			if src2dst[pi] >= 0 {
+				pi++
+				continue
+			} else if dst2src[pj] >= 0 {
+				pj++
+				continue
+			}
+			if nodes.KindOf(a[pi]) == nodes.KindOf(b[pj]) {
+				src2dst[pi], dst2src[pj] = pj, pi
+				paired = append(paired, pj)
+			}
+			pi++
+			pj++
+		}
+		pi, pj = ei+1, ej+1
+	}
+
+	// working copy of the array state
+	cur := make([]token, 0, len(a))
+	for i := range a {
+		cur = append(cur, token{src: i, dst: src2dst[i]})
+	}
+	// delete elements in reverse order, so indexes in the patch match the original ones
+	for i := len(a) - 1; i >= 0; i-- {
+		if cur[i].dst < 0 {
+			d.emit(Change{Op: Delete, Path: path.Join(i)})
+			cur = append(cur[:i], cur[i+1:]...)
+		}
+	}
+	indexOf := func(dst int) int {
+		for i, t := range cur {
+			if t.dst == dst {
+				return i
+			}
+		}
+		return -1
+	}
+	// place moved elements right after their closest predecessor in the destination array
+	for j := range b {
+		if !moved[j] {
+			continue
+		}
+		from := indexOf(j)
+		t := cur[from]
+		cur = append(cur[:from], cur[from+1:]...)
+		to := 0
+		for k := j - 1; k >= 0; k-- {
+			if dst2src[k] < 0 {
+				continue // new element
+			}
+			if i := indexOf(k); i >= 0 {
+				to = i + 1
+				break
+			}
+		}
+		cur = append(cur, token{})
+		copy(cur[to+1:], cur[to:])
+		cur[to] = t
+		if from != to {
+			d.emit(Change{Op: Move, From: path.Join(from), Path: path.Join(to)})
+		}
+	}
+	// all existing elements are in order now, insert new ones
+	for j := range b {
+		if dst2src[j] < 0 {
+			d.emit(Change{Op: Insert, Path: path.Join(j), Value: b[j]})
+		}
+	}
+	// finally, diff elements that were changed in place
+	for _, j := range paired {
+		d.diff(path.Join(j), a[dst2src[j]], b[j])
+	}
+}
+
+// lcs returns index pairs of the longest common subsequence of two hash slices.
+func lcs(a, b []nodes.Hash) [][2]int {
+	// trim common prefix and suffix to reduce the size of the table
+	pref := 0
+	for pref < len(a) && pref < len(b) && a[pref] == b[pref] {
+		pref++
+	}
+	suff := 0
+	for suff < len(a)-pref && suff < len(b)-pref && a[len(a)-1-suff] == b[len(b)-1-suff] {
+		suff++
+	}
+	var out [][2]int
+	for i := 0; i < pref; i++ {
+		out = append(out, [2]int{i, i})
+	}
+	ma, mb := a[pref:len(a)-suff], b[pref:len(b)-suff]
+	if len(ma) != 0 && len(mb) != 0 {
+		w := len(mb) + 1
+		t := make([]int, (len(ma)+1)*w)
+		for i := len(ma) - 1; i >= 0; i-- {
+			for j := len(mb) - 1; j >= 0; j-- {
+				if ma[i] == mb[j] {
+					t[i*w+j] = t[(i+1)*w+j+1] + 1
+				} else if x, y := t[(i+1)*w+j], t[i*w+j+1]; x >= y {
+					t[i*w+j] = x
+				} else {
+					t[i*w+j] = y
+				}
+			}
+		}
+		for i, j := 0, 0; i < len(ma) && j < len(mb); {
+			if ma[i] == mb[j] {
+				out = append(out, [2]int{pref + i, pref + j})
+				i++
+				j++
+			} else if t[(i+1)*w+j] >= t[i*w+j+1] {
+				i++
+			} else {
+				j++
+			}
+		}
+	}
+	for i := 0; i < suff; i++ {
+		out = append(out, [2]int{len(a) - suff + i, len(b) - suff + i})
+	}
+	return out
+}
@@ -0,0 +1,131 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+)
+
+func ident(name string) nodes.Object {
+	return nodes.Object{
+		"@type": nodes.String("uast:Identifier"),
+		"Name":  nodes.String(name),
+	}
+}
+
+var diffCases = []struct {
+	name string
+	a, b nodes.Node
+	exp  Patch
+}{
+	{
+		name: "equal",
+		a:    nodes.Object{"k": nodes.Array{ident("a"), ident("b")}},
+		b:    nodes.Object{"k": nodes.Array{ident("a"), ident("b")}},
+	},
+	{
+		name: "root value",
+		a:    nodes.String("a"),
+		b:    nodes.Int(1),
+		exp: Patch{
+			{Op: Replace, Value: nodes.Int(1)},
+		},
+	},
+	{
+		name: "object fields",
+		a:    nodes.Object{"a": nodes.Int(1), "b": nodes.Int(2), "c": nodes.Int(3)},
+		b:    nodes.Object{"b": nodes.Int(2), "c": nodes.Int(4), "d": nil},
+		exp: Patch{
+			{Op: Delete, Path: nodes.Path{"a"}},
+			{Op: Replace, Path: nodes.Path{"c"}, Value: nodes.Int(4)},
+			{Op: Insert, Path: nodes.Path{"d"}},
+		},
+	},
+	{
+		name: "array insert and delete",
+		a:    nodes.Array{ident("a"), ident("b"), ident("c")},
+		b:    nodes.Array{ident("b"), nodes.Int(1), ident("c"), ident("d")},
+		exp: Patch{
+			{Op: Delete, Path: nodes.Path{0}},
+			{Op: Insert, Path: nodes.Path{1}, Value: nodes.Int(1)},
+			{Op: Insert, Path: nodes.Path{3}, Value: ident("d")},
+		},
+	},
+	{
+		name: "array move",
+		a:    nodes.Array{ident("x"), ident("a"), ident("b"), ident("c")},
+		b:    nodes.Array{ident("a"), ident("b"), ident("c"), ident("x")},
+		exp: Patch{
+			{Op: Move, From: nodes.Path{0}, Path: nodes.Path{3}},
+		},
+	},
+	{
+		name: "nested change",
+		a: nodes.Object{"body": nodes.Array{
+			ident("a"),
+			ident("b"),
+		}},
+		b: nodes.Object{"body": nodes.Array{
+			ident("a"),
+			ident("c"),
+		}},
+		exp: Patch{
+			{Op: Replace, Path: nodes.Path{"body", 1, "Name"}, Value: nodes.String("c")},
+		},
+	},
+	{
+		name: "mixed",
+		a: nodes.Array{
+			ident("a"), ident("b"), ident("c"), ident("d"), ident("e"),
+		},
+		b: nodes.Array{
+			ident("e"), ident("a"), nodes.Object{"k": nil}, ident("c"), ident("x"),
+		},
+	},
+}
+
+func TestChanges(t *testing.T) {
+	for _, c := range diffCases {
+		t.Run(c.name, func(t *testing.T) {
+			orig := c.a.Clone()
+			p := Changes(c.a, c.b)
+			if c.exp != nil || c.name == "equal" {
+				require.Equal(t, c.exp, p)
+			}
+			out, err := Apply(c.a, p)
+			require.NoError(t, err)
+			require.True(t, nodes.Equal(c.b, out), "%v", out)
+			require.True(t, nodes.Equal(orig, c.a), "original tree was modified")
+		})
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	root := nodes.Object{"k": nodes.Array{nodes.Int(1)}}
+	for _, p := range []Patch{
+		{{Op: Delete, Path: nodes.Path{"x"}}},
+		{{Op: Insert, Path: nodes.Path{"k"}, Value: nodes.Int(1)}},
+		{{Op: Insert, Path: nodes.Path{"k", 2}, Value: nodes.Int(1)}},
+		{{Op: Replace, Path: nodes.Path{"k", "x"}, Value: nodes.Int(1)}},
+		{{Op: Move, From: nodes.Path{"k", 0}, Path: nodes.Path{"x", 0}}},
+	} {
+		_, err := Apply(root, p)
+		require.Error(t, err, "%v", p)
+	}
+}
+
+func TestChangesSubslice(t *testing.T) {
+	arr := nodes.Array{nodes.Int(1), nodes.Int(2)}
+	a := nodes.Object{"k": arr}
+	b := nodes.Object{"k": arr[:1]}
+
+	p := Changes(a, b)
+	require.Equal(t, Patch{
+		{Op: Delete, Path: nodes.Path{"k", 1}},
+	}, p)
+	out, err := Apply(a, p)
+	require.NoError(t, err)
+	require.True(t, nodes.Equal(b, out), "%v", out)
+}
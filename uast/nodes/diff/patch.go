@@ -0,0 +1,161 @@
+package diff
+
+import (
+	"fmt"
+
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+)
+
+// Apply replays the patch on a given tree and returns an updated tree.
+//
+// The original tree is never modified; only nodes along the modified paths are copied.
+func Apply(root nodes.Node, p Patch) (nodes.Node, error) {
+	for i, c := range p {
+		var err error
+		root, err = applyChange(root, c)
+		if err != nil {
+			return nil, fmt.Errorf("change %d (%v %v): %v", i, c.Op, c.Path, err)
+		}
+	}
+	return root, nil
+}
+
+func applyChange(root nodes.Node, c Change) (nodes.Node, error) {
+	switch c.Op {
+	case Replace:
+		return update(root, c.Path, func(nodes.Node) (nodes.Node, error) {
+			return c.Value, nil
+		})
+	case Insert, Delete, Move:
+	default:
+		return nil, fmt.Errorf("unsupported operation")
+	}
+	if len(c.Path) == 0 {
+		if c.Op == Delete {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("operation is not supported for the root node")
+	}
+	parent, last := c.Path.Parent(), c.Path[len(c.Path)-1]
+	if c.Op == Move {
+		if len(c.From) != len(c.Path) || !c.From.Parent().Equal(parent) {
+			return nil, fmt.Errorf("move is only supported within the same array")
+		}
+	}
+	return update(root, parent, func(n nodes.Node) (nodes.Node, error) {
+		switch n := n.(type) {
+		case nodes.Object:
+			k, ok := last.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected object key, got %T", last)
+			}
+			_, exists := n[k]
+			switch c.Op {
+			case Insert:
+				if exists {
+					return nil, fmt.Errorf("key %q already exists", k)
+				}
+				n = n.CloneObject()
+				n[k] = c.Value
+			case Delete:
+				if !exists {
+					return nil, fmt.Errorf("key %q does not exist", k)
+				}
+				n = n.CloneObject()
+				delete(n, k)
+			default:
+				return nil, fmt.Errorf("operation is not supported for objects")
+			}
+			return n, nil
+		case nodes.Array:
+			i, ok := last.(int)
+			if !ok {
+				return nil, fmt.Errorf("expected array index, got %T", last)
+			}
+			switch c.Op {
+			case Insert:
+				if i < 0 || i > len(n) {
+					return nil, fmt.Errorf("index out of range: %d", i)
+				}
+				return insertAt(n, i, c.Value), nil
+			case Delete:
+				if i < 0 || i >= len(n) {
+					return nil, fmt.Errorf("index out of range: %d", i)
+				}
+				return deleteAt(n, i), nil
+			case Move:
+				from, ok := c.From[len(c.From)-1].(int)
+				if !ok {
+					return nil, fmt.Errorf("expected array index, got %T", c.From[len(c.From)-1])
+				}
+				if from < 0 || from >= len(n) {
+					return nil, fmt.Errorf("index out of range: %d", from)
+				}
+				v := n[from]
+				n = deleteAt(n, from)
+				if i < 0 || i > len(n) {
+					return nil, fmt.Errorf("index out of range: %d", i)
+				}
+				return insertAt(n, i, v), nil
+			}
+		}
+		return nil, fmt.Errorf("expected object or array, got %v", nodes.KindOf(n))
+	})
+}
+
+// insertAt returns a copy of an array with a new element inserted at index i.
+func insertAt(arr nodes.Array, i int, v nodes.Node) nodes.Array {
+	out := make(nodes.Array, 0, len(arr)+1)
+	out = append(out, arr[:i]...)
+	out = append(out, v)
+	return append(out, arr[i:]...)
+}
+
+// deleteAt returns a copy of an array with an element at index i removed.
+func deleteAt(arr nodes.Array, i int) nodes.Array {
+	out := make(nodes.Array, 0, len(arr)-1)
+	out = append(out, arr[:i]...)
+	return append(out, arr[i+1:]...)
+}
+
+// update replaces a node at a given path with the value returned by the callback.
+// All parent nodes of the modified node are copied.
+func update(n nodes.Node, path nodes.Path, fnc func(n nodes.Node) (nodes.Node, error)) (nodes.Node, error) {
+	if len(path) == 0 {
+		return fnc(n)
+	}
+	switch n := n.(type) {
+	case nodes.Object:
+		k, ok := path[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("expected object key, got %T", path[0])
+		}
+		v, ok := n[k]
+		if !ok {
+			return nil, fmt.Errorf("key %q does not exist", k)
+		}
+		nv, err := update(v, path[1:], fnc)
+		if err != nil {
+			return nil, err
+		}
+		n = n.CloneObject()
+		n[k] = nv
+		return n, nil
+	case nodes.Array:
+		i, ok := path[0].(int)
+		if !ok {
+			return nil, fmt.Errorf("expected array index, got %T", path[0])
+		}
+		if i < 0 || i >= len(n) {
+			return nil, fmt.Errorf("index out of range: %d", i)
+		}
+		nv, err := update(n[i], path[1:], fnc)
+		if err != nil {
+			return nil, err
+		}
+		n = n.CloneList()
+		n[i] = nv
+		return n, nil
+	}
+	return nil, fmt.Errorf("expected object or array, got %v", nodes.KindOf(n))
+}
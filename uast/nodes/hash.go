@@ -33,38 +33,128 @@ type Hasher struct {
 	// KeyFilter allows to skip field in objects by returning false from the function.
 	// Hash will still reflect the presence or absence of these key, but it won't hash a value of that field.
 	KeyFilter func(key string) bool
+
+	// Normalize is called for each node before hashing it, and allows to hash a different node instead.
+	// For example, it can be used to replace identifier names with a placeholder to find similar subtrees.
+	// The function should not modify the node in place.
+	Normalize func(n External) External
+
+	// Cache stores hashes of objects and arrays, allowing to compute hashes of all subtrees in one pass
+	// and to skip hashing of subtrees that were already seen. See HashCache for details.
+	//
+	// Cached hashes depend on the hasher configuration, thus the cache should not be shared between
+	// hashers with different settings.
+	Cache *HashCache
+}
+
+// HashCache stores hashes of subtrees by node identity (see Same).
+//
+// Only non-empty objects and arrays are cached, since other nodes cannot be distinguished by identity.
+// Trees stored in the cache must not be modified.
+type HashCache struct {
+	m map[interface{}]hashEntry
+}
+
+type hashEntry struct {
+	node External // keeps the node alive, so its pointer won't be reused
+	hash Hash
+}
+
+// NewHashCache creates a new empty hash cache.
+func NewHashCache() *HashCache {
+	return &HashCache{m: make(map[interface{}]hashEntry)}
+}
+
+// Len returns the number of cached hashes.
+func (c *HashCache) Len() int {
+	return len(c.m)
+}
+
+// Get returns a cached hash of the node. It returns false if there is no hash for the node.
+func (c *HashCache) Get(n External) (Hash, bool) {
+	k, ok := identityOf(n)
+	if !ok {
+		return Hash{}, false
+	}
+	e, ok := c.m[k]
+	return e.hash, ok
+}
+
+func (c *HashCache) set(n External, h Hash) {
+	if k, ok := identityOf(n); ok {
+		c.m[k] = hashEntry{node: n, hash: h}
+	}
+}
+
+func (c *HashCache) delete(n External) {
+	if k, ok := identityOf(n); ok {
+		delete(c.m, k)
+	}
 }
 
 // HashOf computes a hash of a node with all it's children.
 // Caller should not rely on a specific hash value, since the hash size and the algorithm might change.
 func (h *Hasher) HashOf(n External) Hash {
-	hash := sha256.New()
-	err := h.HashTo(hash, n)
+	v, err := h.hashOf(n, h.Cache)
 	if err != nil {
 		panic(err)
 	}
-	var v Hash
-	sz := len(hash.Sum(v[:0]))
-	if sz != HashSize {
-		panic("unexpected hash size")
-	}
 	return v
 }
 
 // HashTo hashes the node with a custom hash function. See HashOf for details.
+//
+// Only the top-level node is written to the hash directly. Children objects and arrays
+// are represented by their hashes, as returned by HashOf.
 func (h *Hasher) HashTo(hash hash.Hash, n External) error {
-	return h.hashTo(hash, n)
+	if h.Normalize != nil {
+		n = h.Normalize(n)
+	}
+	return h.hashTo(hash, n, h.Cache)
 }
 
 var hashEndianess = binary.LittleEndian
 
-func (h *Hasher) hashTo(w io.Writer, n External) error {
-	kind := KindOf(n)
+// hashOf computes a hash of the node. If the cache is set, it will be used to lookup and store
+// hashes of the node and its children.
+func (h *Hasher) hashOf(n External, cache *HashCache) (Hash, error) {
+	if cache != nil {
+		if v, ok := cache.Get(n); ok {
+			return v, nil
+		}
+	}
+	orig := n
+	if h.Normalize != nil {
+		n = h.Normalize(n)
+	}
+	hash := sha256.New()
+	if err := h.hashTo(hash, n, cache); err != nil {
+		return Hash{}, err
+	}
+	var v Hash
+	sz := len(hash.Sum(v[:0]))
+	if sz != HashSize {
+		return Hash{}, fmt.Errorf("unexpected hash size")
+	}
+	if cache != nil {
+		cache.set(orig, v)
+	}
+	return v, nil
+}
 
+func writeKind(w io.Writer, kind Kind) error {
 	// write kind first (uint32)
 	var buf [4]byte
 	hashEndianess.PutUint32(buf[:], uint32(kind))
-	if _, err := w.Write(buf[:]); err != nil {
+	_, err := w.Write(buf[:])
+	return err
+}
+
+// hashTo writes the node to w. Values are written directly, while children objects and arrays
+// are written as their hashes.
+func (h *Hasher) hashTo(w io.Writer, n External, cache *HashCache) error {
+	kind := KindOf(n)
+	if err := writeKind(w, kind); err != nil {
 		return err
 	}
 	switch kind {
@@ -75,13 +165,13 @@ func (h *Hasher) hashTo(w io.Writer, n External) error {
 		if !ok {
 			return fmt.Errorf("node is an array, but an interface implementation is missing: %T", n)
 		}
-		return h.hashArray(w, arr)
+		return h.hashArray(w, arr, cache)
 	case KindObject:
 		obj, ok := n.(ExternalObject)
 		if !ok {
 			return fmt.Errorf("node is an object, but an interface implementation is missing: %T", n)
 		}
-		return h.hashObject(w, obj)
+		return h.hashObject(w, obj, cache)
 	}
 	if kind.In(KindsValues) {
 		v := n.Value()
@@ -90,7 +180,28 @@ func (h *Hasher) hashTo(w io.Writer, n External) error {
 	return fmt.Errorf("unsupported type: %T (%s)", n, kind)
 }
 
-func (h *Hasher) hashArray(w io.Writer, arr ExternalArray) error {
+// hashChild writes a child node to w. Values are written directly, while objects and arrays are
+// written as their hashes.
+func (h *Hasher) hashChild(w io.Writer, n External, cache *HashCache) error {
+	if !KindOf(n).In(KindsComposite) {
+		if h.Normalize != nil {
+			n = h.Normalize(n)
+		}
+		return h.hashTo(w, n, cache)
+	}
+	v, err := h.hashOf(n, cache)
+	if err != nil {
+		return err
+	}
+	// use a special kind to distinguish hashes from values
+	if err = writeKind(w, KindsComposite); err != nil {
+		return err
+	}
+	_, err = w.Write(v[:])
+	return err
+}
+
+func (h *Hasher) hashArray(w io.Writer, arr ExternalArray, cache *HashCache) error {
 	sz := arr.Size()
 	var buf [4]byte
 	hashEndianess.PutUint32(buf[:], uint32(sz))
@@ -100,14 +211,14 @@ func (h *Hasher) hashArray(w io.Writer, arr ExternalArray) error {
 	}
 	for i := 0; i < sz; i++ {
 		v := arr.ValueAt(i)
-		if err = h.hashTo(w, v); err != nil {
+		if err = h.hashChild(w, v, cache); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (h *Hasher) hashObject(w io.Writer, obj ExternalObject) error {
+func (h *Hasher) hashObject(w io.Writer, obj ExternalObject, cache *HashCache) error {
 	sz := obj.Size()
 	var buf [4]byte
 	hashEndianess.PutUint32(buf[:], uint32(sz))
@@ -130,7 +241,7 @@ func (h *Hasher) hashObject(w io.Writer, obj ExternalObject) error {
 		if h.KeyFilter != nil && !h.KeyFilter(key) {
 			continue
 		}
-		if err = h.hashTo(w, v); err != nil {
+		if err = h.hashChild(w, v, cache); err != nil {
 			return err
 		}
 	}
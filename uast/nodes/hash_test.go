@@ -0,0 +1,64 @@
+package nodes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashCache(t *testing.T) {
+	sub := Object{"k": Array{Int(1), String("v")}}
+	root := Array{sub, Object{"k": nil}, Array{}}
+
+	exp := HashOf(root)
+
+	h := NewHasher()
+	h.Cache = NewHashCache()
+	require.Equal(t, exp, h.HashOf(root))
+	// root, sub, sub.k and the second object
+	require.Equal(t, 4, h.Cache.Len())
+
+	v, ok := h.Cache.Get(sub)
+	require.True(t, ok)
+	require.Equal(t, HashOf(sub), v)
+
+	v, ok = h.Cache.Get(sub["k"])
+	require.True(t, ok)
+	require.Equal(t, HashOf(sub["k"]), v)
+
+	_, ok = h.Cache.Get(Object{"k": nil})
+	require.False(t, ok, "different instance")
+
+	arr := root[:2]
+	_, ok = h.Cache.Get(arr)
+	require.False(t, ok, "sub-slice of a cached array")
+
+	// cached value is returned even if the hash would be different
+	require.Equal(t, exp, h.HashOf(root))
+}
+
+func TestHashNormalize(t *testing.T) {
+	ident := func(name string) Object {
+		return Object{"@type": String("ident"), "Name": String(name), "@role": Array{String("x")}}
+	}
+	h := NewHasher()
+	h.KeyFilter = func(key string) bool {
+		return key != "@role"
+	}
+	h.Normalize = func(n External) External {
+		obj, ok := n.(Object)
+		if !ok || obj["@type"] != String("ident") {
+			return n
+		}
+		obj = obj.CloneObject()
+		obj["Name"] = String("_")
+		return obj
+	}
+	a := Array{ident("a"), Int(1)}
+	b := Array{ident("b"), Int(1)}
+	c := Array{ident("b"), Int(2)}
+
+	require.NotEqual(t, HashOf(a), HashOf(b))
+	require.Equal(t, h.HashOf(a), h.HashOf(b))
+	require.NotEqual(t, h.HashOf(a), h.HashOf(c))
+}
@@ -0,0 +1,401 @@
+package nodes
+
+import "reflect"
+
+// Index is a precomputed navigation index over an immutable tree.
+//
+// It allows to find a parent, siblings and ancestors of any node in the tree, as well as
+// its depth and the size of its subtree. The tree should not be modified after the index
+// was built. See NewLazyIndex for an index that is built on demand.
+//
+// Nodes are looked up by identity (see Same), thus only non-empty objects and arrays can
+// be used as arguments to the index methods. Values and empty nodes cannot be distinguished
+// from one another, and the index will report them as missing.
+//
+// Each node has a single parent in the index. If the same node instance appears multiple times in the tree
+// (for example, in a DAG returned by nodesproto.ReadDAG), lookups by identity return the first occurrence of
+// the node in pre-order. Use NodeRef to navigate all nodes of the tree, including values, nil nodes and
+// all occurrences of shared nodes.
+type Index struct {
+	nodes []indexEntry
+	ids   map[interface{}]NodeRef
+	built bool // all entries are indexed; see build
+}
+
+// NodeRef is a reference to a single occurrence of a node in the Index.
+//
+// References are only valid for the index that returned them.
+type NodeRef int
+
+// NoRef is an invalid node reference.
+const NoRef = NodeRef(-1)
+
+type indexEntry struct {
+	node     External
+	path     interface{} // object key or array index in the parent
+	parent   NodeRef     // NoRef for the root
+	ind      int         // position in the list of parent's children
+	pos      int         // position in the list of parent's non-nil children
+	depth    int
+	size     int // zero for nil nodes; only set after build
+	kids     []NodeRef
+	expanded bool // kids are indexed
+}
+
+// NewIndex builds a navigation index for the tree.
+func NewIndex(root External) *Index {
+	idx := NewLazyIndex(root)
+	idx.build()
+	return idx
+}
+
+// NewLazyIndex creates a navigation index for the tree that is built on demand.
+//
+// Children of a node are indexed when they are first requested with ChildRefs, thus navigating the tree
+// with NodeRef methods only accesses the nodes that were visited. This allows to navigate lazily decoded
+// trees without loading them completely. Methods that look up nodes by identity, as well as Len, index
+// the whole tree on the first call.
+//
+// Unlike the index returned by NewIndex, the lazy index is not safe for concurrent use.
+func NewLazyIndex(root External) *Index {
+	idx := &Index{ids: make(map[interface{}]NodeRef)}
+	if root != nil {
+		idx.add(root, nil, NoRef, 0, 0, 0)
+	}
+	return idx
+}
+
+func (idx *Index) add(n External, key interface{}, parent NodeRef, ind, pos, depth int) NodeRef {
+	id := NodeRef(len(idx.nodes))
+	idx.nodes = append(idx.nodes, indexEntry{
+		node: n, path: key, parent: parent, ind: ind, pos: pos, depth: depth,
+		expanded: n == nil,
+	})
+	return id
+}
+
+// expand indexes children of the node, if it was not done yet.
+func (idx *Index) expand(id NodeRef) {
+	if idx.nodes[id].expanded {
+		return
+	}
+	n, depth := idx.nodes[id].node, idx.nodes[id].depth
+	var kids []NodeRef
+	npos := 0
+	addChild := func(k interface{}, v External) {
+		kids = append(kids, idx.add(v, k, id, len(kids), npos, depth+1))
+		if v != nil {
+			npos++
+		}
+	}
+	switch KindOf(n) {
+	case KindObject:
+		if m, ok := n.(ExternalObject); ok {
+			for _, k := range m.Keys() {
+				v, _ := m.ValueAt(k)
+				addChild(k, v)
+			}
+		}
+	case KindArray:
+		if m, ok := n.(ExternalArray); ok {
+			sz := m.Size()
+			for i := 0; i < sz; i++ {
+				addChild(i, m.ValueAt(i))
+			}
+		}
+	}
+	e := &idx.nodes[id]
+	e.kids, e.expanded = kids, true
+}
+
+// build indexes all the nodes of the tree, computes subtree sizes and registers node identities.
+func (idx *Index) build() {
+	if idx.built {
+		return
+	}
+	idx.built = true
+	// children are always added after their parent
+	for i := 0; i < len(idx.nodes); i++ {
+		idx.expand(NodeRef(i))
+	}
+	for i := len(idx.nodes) - 1; i >= 0; i-- {
+		e := &idx.nodes[i]
+		if e.node == nil {
+			continue
+		}
+		e.size++
+		if e.parent != NoRef {
+			idx.nodes[e.parent].size += e.size
+		}
+	}
+	if len(idx.nodes) != 0 {
+		idx.register(0)
+	}
+}
+
+// register records identities of the node and its children in pre-order.
+func (idx *Index) register(id NodeRef) {
+	e := &idx.nodes[id]
+	if k, ok := identityOf(e.node); ok {
+		if _, dup := idx.ids[k]; !dup {
+			idx.ids[k] = id
+		}
+	}
+	for _, c := range e.kids {
+		idx.register(c)
+	}
+}
+
+// nodeIdentity is an identity of an object or array node that is a reference type.
+//
+// Slices that share the same backing array are distinguished by their size, so a sub-slice
+// does not collide with the parent array.
+type nodeIdentity struct {
+	kind Kind
+	typ  reflect.Type
+	ptr  uintptr
+	size int
+}
+
+// identityOf returns a comparable identity of a non-empty object or array node.
+func identityOf(n External) (interface{}, bool) {
+	kind := KindOf(n)
+	size := 0
+	switch kind {
+	case KindObject:
+		m, ok := n.(ExternalObject)
+		if !ok {
+			return nil, false
+		}
+		size = m.Size()
+	case KindArray:
+		m, ok := n.(ExternalArray)
+		if !ok {
+			return nil, false
+		}
+		size = m.Size()
+	default:
+		return nil, false
+	}
+	if size == 0 {
+		return nil, false
+	}
+	rv := reflect.ValueOf(n)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		return nodeIdentity{kind: kind, typ: rv.Type(), ptr: rv.Pointer(), size: size}, true
+	}
+	if rv.Type().Comparable() {
+		return n, true
+	}
+	return nil, false
+}
+
+func (idx *Index) entry(n External) *indexEntry {
+	idx.build()
+	k, ok := identityOf(n)
+	if !ok {
+		return nil
+	}
+	id, ok := idx.ids[k]
+	if !ok {
+		return nil
+	}
+	return &idx.nodes[id]
+}
+
+func (idx *Index) at(r NodeRef) *indexEntry {
+	if r < 0 || int(r) >= len(idx.nodes) {
+		return nil
+	}
+	return &idx.nodes[r]
+}
+
+// Root returns the root node of the index.
+func (idx *Index) Root() External {
+	if len(idx.nodes) == 0 {
+		return nil
+	}
+	return idx.nodes[0].node
+}
+
+// Len returns the number of non-nil nodes in the tree.
+func (idx *Index) Len() int {
+	idx.build()
+	if len(idx.nodes) == 0 {
+		return 0
+	}
+	return idx.nodes[0].size
+}
+
+// Contains checks if the node is a part of the indexed tree.
+func (idx *Index) Contains(n External) bool {
+	return idx.entry(n) != nil
+}
+
+// Parent returns a parent of the node. It returns nil for the root node, or if the node is not in the index.
+func (idx *Index) Parent(n External) External {
+	e := idx.entry(n)
+	if e == nil || e.parent == NoRef {
+		return nil
+	}
+	return idx.nodes[e.parent].node
+}
+
+// Ancestors returns all the parents of the node, starting from the closest one.
+func (idx *Index) Ancestors(n External) []External {
+	e := idx.entry(n)
+	if e == nil || e.parent == NoRef {
+		return nil
+	}
+	out := make([]External, 0, e.depth)
+	for p := e.parent; p != NoRef; p = idx.nodes[p].parent {
+		out = append(out, idx.nodes[p].node)
+	}
+	return out
+}
+
+// Children returns all non-nil children of the node. Object fields are listed in the order of sorted keys.
+func (idx *Index) Children(n External) []External {
+	e := idx.entry(n)
+	if e == nil {
+		return nil
+	}
+	return idx.list(e.kids)
+}
+
+// Siblings returns all children of the node's parent, including the node itself. See SiblingIndex.
+func (idx *Index) Siblings(n External) []External {
+	e := idx.entry(n)
+	if e == nil {
+		return nil
+	} else if e.parent == NoRef {
+		return []External{e.node}
+	}
+	return idx.list(idx.nodes[e.parent].kids)
+}
+
+// SiblingIndex returns a position of the node in the list returned by Siblings, or -1 if the node is not in the index.
+func (idx *Index) SiblingIndex(n External) int {
+	e := idx.entry(n)
+	if e == nil {
+		return -1
+	}
+	return e.pos
+}
+
+// Depth returns the depth of the node in the tree. The root node has zero depth.
+// It returns -1 if the node is not in the index.
+func (idx *Index) Depth(n External) int {
+	e := idx.entry(n)
+	if e == nil {
+		return -1
+	}
+	return e.depth
+}
+
+// SubtreeSize returns the number of non-nil nodes in the subtree, including the node itself.
+// It returns zero if the node is not in the index.
+func (idx *Index) SubtreeSize(n External) int {
+	e := idx.entry(n)
+	if e == nil {
+		return 0
+	}
+	return e.size
+}
+
+// Path returns a path of the node relative to the root. It returns false if the node is not in the index.
+func (idx *Index) Path(n External) (Path, bool) {
+	e := idx.entry(n)
+	if e == nil {
+		return nil, false
+	}
+	p := make(Path, e.depth)
+	for i := e.depth - 1; i >= 0; i-- {
+		p[i] = e.path
+		e = &idx.nodes[e.parent]
+	}
+	return p, true
+}
+
+// list returns all non-nil nodes with given references.
+func (idx *Index) list(ids []NodeRef) []External {
+	out := make([]External, 0, len(ids))
+	for _, id := range ids {
+		if n := idx.nodes[id].node; n != nil {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// RootRef returns a reference to the root node. It returns NoRef if the index is empty.
+func (idx *Index) RootRef() NodeRef {
+	if len(idx.nodes) == 0 {
+		return NoRef
+	}
+	return 0
+}
+
+// RefOf returns a reference to the first occurrence of the node in the tree. See Index for details.
+// It returns NoRef if the node is not in the index.
+func (idx *Index) RefOf(n External) NodeRef {
+	idx.build()
+	k, ok := identityOf(n)
+	if !ok {
+		return NoRef
+	}
+	id, ok := idx.ids[k]
+	if !ok {
+		return NoRef
+	}
+	return id
+}
+
+// NodeAt returns a node for a given reference.
+func (idx *Index) NodeAt(r NodeRef) External {
+	e := idx.at(r)
+	if e == nil {
+		return nil
+	}
+	return e.node
+}
+
+// ParentRef returns a reference to the parent of the node. It returns NoRef for the root node.
+func (idx *Index) ParentRef(r NodeRef) NodeRef {
+	e := idx.at(r)
+	if e == nil {
+		return NoRef
+	}
+	return e.parent
+}
+
+// ChildRefs returns references to all children of the node, including nil nodes.
+// Object fields are listed in the order of sorted keys. The caller must not modify the returned slice.
+func (idx *Index) ChildRefs(r NodeRef) []NodeRef {
+	if idx.at(r) == nil {
+		return nil
+	}
+	idx.expand(r)
+	return idx.nodes[r].kids
+}
+
+// ChildIndex returns a position of the node in the list returned by ChildRefs for its parent.
+// It returns -1 for invalid references.
+func (idx *Index) ChildIndex(r NodeRef) int {
+	e := idx.at(r)
+	if e == nil {
+		return -1
+	}
+	return e.ind
+}
+
+// KeyAt returns an object key (string) or an array index (int) of the node in its parent.
+// It returns nil for the root node.
+func (idx *Index) KeyAt(r NodeRef) interface{} {
+	e := idx.at(r)
+	if e == nil {
+		return nil
+	}
+	return e.path
+}
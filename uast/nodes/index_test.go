@@ -0,0 +1,134 @@
+package nodes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndex(t *testing.T) {
+	id1 := Object{"Name": String("a")}
+	id2 := Object{"Name": String("b")}
+	args := Array{id1, nil, id2}
+	fnc := Object{
+		"Args": args,
+		"Body": Object{},
+	}
+	root := Array{fnc}
+
+	idx := NewIndex(root)
+	require.Equal(t, root, idx.Root())
+	require.Equal(t, 8, idx.Len())
+	require.Equal(t, Count(root, KindsNotNil), idx.Len())
+
+	require.Nil(t, idx.Parent(root))
+	require.True(t, Same(args, idx.Parent(id2)))
+	require.True(t, Same(fnc, idx.Parent(args)))
+	require.Nil(t, idx.Parent(String("a")), "values cannot be looked up")
+
+	anc := idx.Ancestors(id1)
+	require.Len(t, anc, 3)
+	require.True(t, Same(args, anc[0]))
+	require.True(t, Same(fnc, anc[1]))
+	require.True(t, Same(root, anc[2]))
+
+	sib := idx.Siblings(id2)
+	require.Len(t, sib, 2)
+	require.True(t, Same(id1, sib[0]))
+	require.Equal(t, 1, idx.SiblingIndex(id2))
+	require.Equal(t, []External{String("b")}, idx.Children(id2))
+
+	require.Equal(t, 0, idx.Depth(root))
+	require.Equal(t, 3, idx.Depth(id2))
+	require.Equal(t, -1, idx.Depth(Object{"x": Int(1)}))
+
+	require.Equal(t, 2, idx.SubtreeSize(id1))
+	require.Equal(t, 5, idx.SubtreeSize(args))
+	require.Equal(t, idx.Len(), idx.SubtreeSize(root))
+
+	p, ok := idx.Path(id2)
+	require.True(t, ok)
+	require.Equal(t, Path{0, "Args", 2}, p)
+
+	// external nodes
+	ext := extObject{"k": extArray{extObject{"v": Int(1)}}}
+	eidx := NewIndex(ext)
+	arr := ext["k"].(extArray)
+	require.True(t, eidx.Contains(arr[0]))
+	require.True(t, Same(arr, eidx.Parent(arr[0])))
+}
+
+func TestIndexRefs(t *testing.T) {
+	// shared node, as in a DAG
+	id := Object{"Name": String("a")}
+	root := Object{
+		"A": id,
+		"B": Array{nil, id},
+	}
+
+	idx := NewIndex(root)
+	require.Equal(t, 6, idx.Len())
+
+	// lookups by identity return the first occurrence
+	p, ok := idx.Path(id)
+	require.True(t, ok)
+	require.Equal(t, Path{"A"}, p)
+	require.True(t, Same(root, idx.Parent(id)))
+
+	r := idx.RootRef()
+	require.Equal(t, NoRef, idx.ParentRef(r))
+	require.Nil(t, idx.KeyAt(r))
+
+	kids := idx.ChildRefs(r)
+	require.Len(t, kids, 2)
+	require.Equal(t, kids[0], idx.RefOf(id))
+	require.Equal(t, "B", idx.KeyAt(kids[1]))
+
+	arr := idx.ChildRefs(kids[1])
+	require.Len(t, arr, 2)
+	require.Nil(t, idx.NodeAt(arr[0]))
+	require.Equal(t, 0, idx.KeyAt(arr[0]))
+	require.Equal(t, 1, idx.ChildIndex(arr[1]))
+
+	// second occurrence of the shared node has its own parent
+	require.True(t, Same(id, idx.NodeAt(arr[1])))
+	require.NotEqual(t, idx.RefOf(id), arr[1])
+	require.Equal(t, kids[1], idx.ParentRef(arr[1]))
+	require.Len(t, idx.ChildRefs(arr[1]), 1)
+
+	require.Nil(t, idx.NodeAt(NoRef))
+	require.Nil(t, NewIndex(nil).Root())
+	require.Equal(t, NoRef, NewIndex(nil).RootRef())
+}
+
+func TestLazyIndex(t *testing.T) {
+	id := Object{"Name": String("a")}
+	root := Object{
+		"A": id,
+		"B": Array{nil, id},
+	}
+
+	idx := NewLazyIndex(root)
+	r := idx.RootRef()
+	require.True(t, Same(root, idx.NodeAt(r)))
+
+	// children are indexed when requested
+	require.Len(t, idx.nodes, 1)
+	kids := idx.ChildRefs(r)
+	require.Len(t, kids, 2)
+	require.Len(t, idx.nodes, 3)
+	require.Equal(t, "B", idx.KeyAt(kids[1]))
+	require.Equal(t, r, idx.ParentRef(kids[1]))
+
+	// lookups by identity index the whole tree
+	require.Equal(t, 6, idx.Len())
+	require.Equal(t, kids[0], idx.RefOf(id))
+	p, ok := idx.Path(id)
+	require.True(t, ok)
+	require.Equal(t, Path{"A"}, p)
+
+	arr := idx.ChildRefs(kids[1])
+	require.Len(t, arr, 2)
+	require.True(t, Same(id, idx.NodeAt(arr[1])))
+	require.Equal(t, 2, idx.SubtreeSize(id))
+}
@@ -0,0 +1,109 @@
+package nodes
+
+// Interner deduplicates identical subtrees, allowing multiple trees to share them in memory.
+//
+// Interned trees must be treated as immutable, since modifying a shared node in place
+// will affect all the trees that reference it.
+type Interner struct {
+	hasher *Hasher
+	vals   map[Value]Value
+	nodes  map[Hash]Node
+	cache  *HashCache // hashes of interned nodes
+}
+
+// NewInterner creates a new interner that uses the default hasher to find identical subtrees.
+func NewInterner() *Interner {
+	return NewInternerWith(DefaultHasher)
+}
+
+// NewInternerWith creates a new interner that uses a given hasher to find identical subtrees.
+//
+// The hasher must not skip or normalize any fields, or the interner will merge subtrees that are not equal.
+// The interner maintains its own hash cache, thus the cache set in the hasher is not used.
+func NewInternerWith(h *Hasher) *Interner {
+	return &Interner{
+		hasher: h,
+		vals:   make(map[Value]Value),
+		nodes:  make(map[Hash]Node),
+		cache:  NewHashCache(),
+	}
+}
+
+// Len returns the number of unique nodes stored in the interner.
+func (in *Interner) Len() int {
+	return len(in.vals) + len(in.nodes)
+}
+
+// Intern returns a tree equal to n, where all subtrees identical to ones seen previously are replaced
+// with the previously seen instances. The original tree is not modified.
+func (in *Interner) Intern(n Node) Node {
+	n, _ = in.intern(n)
+	return n
+}
+
+// intern returns an interned node and a flag that indicates if the node instance was replaced.
+func (in *Interner) intern(n Node) (Node, bool) {
+	switch n := n.(type) {
+	case nil:
+		return nil, false
+	case Value:
+		if f, ok := n.(Float); ok && f != f {
+			// NaN is not equal to itself, thus it cannot be found in the map
+			return n, false
+		}
+		if v, ok := in.vals[n]; ok {
+			return v, true
+		}
+		in.vals[n] = n
+		return n, false
+	}
+	if _, ok := in.cache.Get(n); ok {
+		// already an interned node
+		return n, false
+	}
+	changed := false
+	switch m := n.(type) {
+	case Object:
+		var out Object
+		for k, v := range m {
+			nv, ok := in.intern(v)
+			if !ok {
+				continue
+			}
+			if out == nil {
+				out = m.CloneObject()
+			}
+			out[k] = nv
+		}
+		if out != nil {
+			n, changed = out, true
+		}
+	case Array:
+		var out Array
+		for i, v := range m {
+			nv, ok := in.intern(v)
+			if !ok {
+				continue
+			}
+			if out == nil {
+				out = m.CloneList()
+			}
+			out[i] = nv
+		}
+		if out != nil {
+			n, changed = out, true
+		}
+	}
+	// all children are interned at this point, so their hashes are already cached
+	h, err := in.hasher.hashOf(n, in.cache)
+	if err != nil {
+		panic(err)
+	}
+	if n2, ok := in.nodes[h]; ok {
+		// only interned nodes are allowed in the cache
+		in.cache.delete(n)
+		return n2, true
+	}
+	in.nodes[h] = n
+	return n, changed
+}
@@ -0,0 +1,51 @@
+package nodes
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterner(t *testing.T) {
+	newTree := func() Node {
+		return Array{
+			Object{"@type": String("ident"), "Name": String("a")},
+			Object{"@type": String("ident"), "Name": String("a")},
+			Object{"@type": String("ident"), "Name": String("b")},
+			Array{},
+		}
+	}
+	t1, t2 := newTree(), newTree()
+	orig := t1.Clone()
+
+	in := NewInterner()
+	i1 := in.Intern(t1)
+	require.True(t, Equal(orig, i1))
+	require.True(t, Equal(orig, t1), "original tree was modified")
+
+	arr := i1.(Array)
+	require.True(t, Same(arr[0], arr[1]))
+	require.False(t, Same(arr[0], arr[2]))
+
+	i2 := in.Intern(t2)
+	require.True(t, Same(i1, i2), "identical trees should be merged")
+	require.True(t, Same(i1, in.Intern(i1)))
+
+	// unique values: "ident", "a", "b"
+	// unique nodes: 2 objects, 2 arrays
+	require.Equal(t, 7, in.Len())
+}
+
+func TestInternerNaN(t *testing.T) {
+	in := NewInterner()
+	for i := 0; i < 3; i++ {
+		in.Intern(Float(math.NaN()))
+	}
+	require.Equal(t, 0, in.Len())
+
+	o1 := in.Intern(Object{"v": Float(math.NaN())})
+	o2 := in.Intern(Object{"v": Float(math.NaN())})
+	require.True(t, Same(o1, o2))
+	require.Equal(t, 1, in.Len())
+}
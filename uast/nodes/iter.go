@@ -44,6 +44,37 @@ func NewIterator(root External, order IterOrder) Iterator {
 	if root == nil {
 		return Empty{}
 	}
+	return newIterator(pathNode{node: root}, order)
+}
+
+// PathIterator is an iterator that additionally tracks a path of each node.
+type PathIterator interface {
+	Iterator
+	// Path returns a path of the current node relative to the root.
+	Path() Path
+}
+
+var _ PathIterator = EmptyPath{}
+
+// EmptyPath is an empty path iterator.
+type EmptyPath struct {
+	Empty
+}
+
+// Path implements PathIterator.
+func (EmptyPath) Path() Path { return nil }
+
+// NewPathIterator creates a new iterator with a given order that also reports a path of each node.
+// See NewIterator for details.
+func NewPathIterator(root External, order IterOrder) PathIterator {
+	if root == nil {
+		return EmptyPath{}
+	}
+	return newIterator(pathNode{node: root, path: Path{}}, order)
+}
+
+// newIterator creates an iterator with a given order. Paths are only tracked if the root has a non-nil path.
+func newIterator(root pathNode, order IterOrder) PathIterator {
 	if order == IterAny {
 		order = PreOrder
 	}
@@ -57,7 +88,7 @@ func NewIterator(root External, order IterOrder) Iterator {
 		it.start(root)
 		return it
 	case LevelOrder:
-		return &levelOrderIter{level: []External{root}, i: -1}
+		return &levelOrderIter{level: []pathNode{root}, i: -1}
 	case ChildrenOrder:
 		return newChildrenIterator(root)
 	default:
@@ -65,48 +96,63 @@ func NewIterator(root External, order IterOrder) Iterator {
 	}
 }
 
-func eachChild(n External, fnc func(v External)) {
-	switch KindOf(n) {
+// pathNode is a node with an optional path relative to the iteration root.
+type pathNode struct {
+	node External
+	path Path
+}
+
+// child returns a child node at a given key. The path is only set if the parent has a path.
+func (n pathNode) child(k interface{}, v External) pathNode {
+	c := pathNode{node: v}
+	if n.path != nil {
+		c.path = n.path.Join(k)
+	}
+	return c
+}
+
+func eachChild(n pathNode, fnc func(v pathNode)) {
+	switch KindOf(n.node) {
 	case KindObject:
-		if m, ok := n.(ExternalObject); ok {
+		if m, ok := n.node.(ExternalObject); ok {
 			keys := m.Keys()
 			for _, k := range keys {
 				if v, _ := m.ValueAt(k); v != nil {
-					fnc(v)
+					fnc(n.child(k, v))
 				}
 			}
 		}
 	case KindArray:
-		if m, ok := n.(ExternalArray); ok {
+		if m, ok := n.node.(ExternalArray); ok {
 			sz := m.Size()
 			for i := 0; i < sz; i++ {
 				if v := m.ValueAt(i); v != nil {
-					fnc(v)
+					fnc(n.child(i, v))
 				}
 			}
 		}
 	}
 }
 
-func eachChildRev(n External, fnc func(v External)) {
-	switch KindOf(n) {
+func eachChildRev(n pathNode, fnc func(v pathNode)) {
+	switch KindOf(n.node) {
 	case KindObject:
-		if m, ok := n.(ExternalObject); ok {
+		if m, ok := n.node.(ExternalObject); ok {
 			keys := m.Keys()
 			// reverse order
 			for i := len(keys) - 1; i >= 0; i-- {
 				if v, _ := m.ValueAt(keys[i]); v != nil {
-					fnc(v)
+					fnc(n.child(keys[i], v))
 				}
 			}
 		}
 	case KindArray:
-		if m, ok := n.(ExternalArray); ok {
+		if m, ok := n.node.(ExternalArray); ok {
 			sz := m.Size()
 			// reverse order
 			for i := sz - 1; i >= 0; i-- {
 				if v := m.ValueAt(i); v != nil {
-					fnc(v)
+					fnc(n.child(i, v))
 				}
 			}
 		}
@@ -114,20 +160,20 @@ func eachChildRev(n External, fnc func(v External)) {
 }
 
 type preOrderIter struct {
-	cur External
-	q   []External
+	cur pathNode
+	q   []pathNode
 }
 
-func (it *preOrderIter) push(n External) {
-	if n == nil {
+func (it *preOrderIter) push(n pathNode) {
+	if n.node == nil {
 		return
 	}
 	it.q = append(it.q, n)
 }
-func (it *preOrderIter) pop() External {
+func (it *preOrderIter) pop() pathNode {
 	l := len(it.q)
 	if l == 0 {
-		return nil
+		return pathNode{}
 	}
 	n := it.q[l-1]
 	it.q = it.q[:l-1]
@@ -136,29 +182,32 @@ func (it *preOrderIter) pop() External {
 
 func (it *preOrderIter) Next() bool {
 	cur := it.cur
-	it.cur = nil
+	it.cur = pathNode{}
 	eachChildRev(cur, it.push)
 	it.cur = it.pop()
-	return KindOf(it.cur) != KindNil
+	return KindOf(it.cur.node) != KindNil
 }
 func (it *preOrderIter) Node() External {
-	return it.cur
+	return it.cur.node
+}
+func (it *preOrderIter) Path() Path {
+	return it.cur.path
 }
 
 type postOrderIter struct {
-	cur External
-	s   [][]External
+	cur pathNode
+	s   [][]pathNode
 }
 
-func (it *postOrderIter) start(n External) {
-	kind := KindOf(n)
+func (it *postOrderIter) start(n pathNode) {
+	kind := KindOf(n.node)
 	if kind == KindNil {
 		return
 	}
 	si := len(it.s)
-	q := []External{n}
+	q := []pathNode{n}
 	it.s = append(it.s, nil)
-	eachChildRev(n, func(v External) {
+	eachChildRev(n, func(v pathNode) {
 		q = append(q, v)
 	})
 	if l := len(q); l > 1 {
@@ -197,11 +246,14 @@ func (it *postOrderIter) Next() bool {
 	}
 }
 func (it *postOrderIter) Node() External {
-	return it.cur
+	return it.cur.node
+}
+func (it *postOrderIter) Path() Path {
+	return it.cur.path
 }
 
 type levelOrderIter struct {
-	level []External
+	level []pathNode
 	i     int
 }
 
@@ -212,9 +264,9 @@ func (it *levelOrderIter) Next() bool {
 		it.i++
 		return true
 	}
-	var next []External
+	var next []pathNode
 	for _, n := range it.level {
-		eachChild(n, func(v External) {
+		eachChild(n, func(v pathNode) {
 			next = append(next, v)
 		})
 	}
@@ -223,45 +275,48 @@ func (it *levelOrderIter) Next() bool {
 	return len(it.level) > 0
 }
 
-func (it *levelOrderIter) Node() External {
+func (it *levelOrderIter) cur() pathNode {
 	if it.i >= len(it.level) {
-		return nil
+		return pathNode{}
 	}
 	return it.level[it.i]
 }
 
-func addUnfoldingArrays(nodes []External, n External) []External {
-	switch n.Kind() {
+func (it *levelOrderIter) Node() External {
+	return it.cur().node
+}
+
+func (it *levelOrderIter) Path() Path {
+	return it.cur().path
+}
+
+func addUnfoldingArrays(nodes []pathNode, n pathNode) []pathNode {
+	switch n.node.Kind() {
 	case KindArray:
-		if m, ok := n.(ExternalArray); ok {
-			sz := m.Size()
-			for i := 0; i < sz; i++ {
-				if v := m.ValueAt(i); v != nil {
-					nodes = addUnfoldingArrays(nodes, v)
-				}
-			}
-		}
+		eachChild(n, func(v pathNode) {
+			nodes = addUnfoldingArrays(nodes, v)
+		})
 	case KindObject:
 		nodes = append(nodes, n)
 	}
 	return nodes
 }
 
-func newChildrenIterator(n External) Iterator {
-	var nodes []External
-	eachChild(n, func(v External) {
+func newChildrenIterator(n pathNode) PathIterator {
+	var nodes []pathNode
+	eachChild(n, func(v pathNode) {
 		nodes = addUnfoldingArrays(nodes, v)
 	})
 	return newFixedIterator(nodes)
 }
 
 // newFixedIterator creates a node iterator that list nodes in the given slice. It won't recurse into those nodes.
-func newFixedIterator(nodes []External) Iterator {
+func newFixedIterator(nodes []pathNode) PathIterator {
 	return &fixedIter{nodes: nodes, first: true}
 }
 
 type fixedIter struct {
-	nodes []External
+	nodes []pathNode
 	first bool
 }
 
@@ -277,10 +332,19 @@ func (it *fixedIter) Next() bool {
 	return len(it.nodes) > 0
 }
 
-// Node implements Iterator.
-func (it *fixedIter) Node() External {
+func (it *fixedIter) cur() pathNode {
 	if len(it.nodes) == 0 {
-		return nil
+		return pathNode{}
 	}
 	return it.nodes[0]
 }
+
+// Node implements Iterator.
+func (it *fixedIter) Node() External {
+	return it.cur().node
+}
+
+// Path implements PathIterator.
+func (it *fixedIter) Path() Path {
+	return it.cur().path
+}
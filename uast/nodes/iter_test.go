@@ -101,3 +101,34 @@ func allNodes(it nodes.Iterator) []nodes.Node {
 	}
 	return out
 }
+
+func TestPathIter(t *testing.T) {
+	a := nodes.Object{
+		"k1": nodes.Int(1),
+		"k2": nodes.Array{nodes.String("v")},
+	}
+	root := nodes.Array{a, nodes.Object{}}
+
+	var cases = []struct {
+		order nodes.IterOrder
+		exp   []string
+	}{
+		{order: nodes.PreOrder, exp: []string{"", "/0", "/0/k1", "/0/k2", "/0/k2/0", "/1"}},
+		{order: nodes.PostOrder, exp: []string{"/0/k1", "/0/k2/0", "/0/k2", "/0", "/1", ""}},
+		{order: nodes.LevelOrder, exp: []string{"", "/0", "/1", "/0/k1", "/0/k2", "/0/k2/0"}},
+		{order: nodes.ChildrenOrder, exp: []string{"/0", "/1"}},
+	}
+	for _, c := range cases {
+		it := nodes.NewPathIterator(root, c.order)
+		var got []string
+		for it.Next() {
+			p := it.Path()
+			got = append(got, p.String())
+			n, ok := nodes.GetPath(root, p)
+			require.True(t, ok)
+			require.True(t, nodes.Same(n, it.Node()))
+		}
+		require.Equal(t, c.exp, got, "order: %v", c.order)
+		require.Equal(t, len(got), len(allNodes(nodes.NewIterator(root, c.order))))
+	}
+}
@@ -0,0 +1,457 @@
+package nodesjson
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+)
+
+const (
+	// maxDepth is the maximal nesting depth of JSON values accepted by the decoder.
+	maxDepth = 10000
+	// maxKeys is the maximal number of distinct object keys remembered by the decoder.
+	maxKeys = 4096
+)
+
+// Unmarshal decodes a single JSON value to a UAST node.
+func Unmarshal(data []byte) (nodes.Node, error) {
+	dec := NewDecoder(bytes.NewReader(data))
+	n, err := dec.Decode()
+	if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	} else if err != nil {
+		return nil, err
+	}
+	if err = dec.skipSpace(); err == nil {
+		return nil, dec.errorf("unexpected data after the value")
+	} else if err != io.EOF {
+		return nil, err
+	}
+	return n, nil
+}
+
+// NewDecoder creates a JSON decoder for UAST nodes.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{
+		r:    bufio.NewReader(r),
+		keys: make(map[string]string),
+	}
+}
+
+// Decoder reads a stream of JSON values as UAST nodes.
+type Decoder struct {
+	r     *bufio.Reader
+	off   int64
+	kinds bool
+	buf   []byte
+	keys  map[string]string // interned object keys
+}
+
+// SetKinds enables or disables kind-preserving number decoding. See package documentation for details.
+func (dec *Decoder) SetKinds(enable bool) {
+	dec.kinds = enable
+}
+
+// Decode reads the next JSON value from the stream. It returns io.EOF if there are no more values.
+func (dec *Decoder) Decode() (nodes.Node, error) {
+	if err := dec.skipSpace(); err != nil {
+		return nil, err
+	}
+	return dec.decode(0)
+}
+
+func (dec *Decoder) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("offset %d: %s", dec.off, fmt.Sprintf(format, args...))
+}
+
+func (dec *Decoder) readByte() (byte, error) {
+	c, err := dec.r.ReadByte()
+	if err == io.EOF {
+		return 0, dec.errorf("unexpected end of input")
+	} else if err != nil {
+		return 0, err
+	}
+	dec.off++
+	return c, nil
+}
+
+func (dec *Decoder) unreadByte() {
+	_ = dec.r.UnreadByte()
+	dec.off--
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// skipSpace skips the whitespace. It returns io.EOF if the stream ended.
+func (dec *Decoder) skipSpace() error {
+	for {
+		c, err := dec.r.ReadByte()
+		if err != nil {
+			return err
+		}
+		dec.off++
+		if !isSpace(c) {
+			dec.unreadByte()
+			return nil
+		}
+	}
+}
+
+// next returns the next non-whitespace character.
+func (dec *Decoder) next() (byte, error) {
+	for {
+		c, err := dec.readByte()
+		if err != nil || !isSpace(c) {
+			return c, err
+		}
+	}
+}
+
+func (dec *Decoder) expect(c byte) error {
+	got, err := dec.next()
+	if err != nil {
+		return err
+	} else if got != c {
+		return dec.errorf("expected %q, got %q", c, got)
+	}
+	return nil
+}
+
+func (dec *Decoder) decode(depth int) (nodes.Node, error) {
+	if depth > maxDepth {
+		return nil, dec.errorf("maximum nesting depth exceeded")
+	}
+	c, err := dec.next()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case c == '{':
+		return dec.decodeObject(depth)
+	case c == '[':
+		return dec.decodeArray(depth)
+	case c == '"':
+		s, err := dec.decodeString()
+		if err != nil {
+			return nil, err
+		}
+		return nodes.String(s), nil
+	case c == 't':
+		return nodes.Bool(true), dec.literal("rue")
+	case c == 'f':
+		return nodes.Bool(false), dec.literal("alse")
+	case c == 'n':
+		return nil, dec.literal("ull")
+	case c == '-' || (c >= '0' && c <= '9'):
+		dec.unreadByte()
+		return dec.decodeNumber()
+	}
+	return nil, dec.errorf("invalid character %q", c)
+}
+
+func (dec *Decoder) literal(rest string) error {
+	for i := 0; i < len(rest); i++ {
+		c, err := dec.readByte()
+		if err != nil {
+			return err
+		} else if c != rest[i] {
+			return dec.errorf("invalid character %q in literal", c)
+		}
+	}
+	return nil
+}
+
+func (dec *Decoder) decodeObject(depth int) (nodes.Node, error) {
+	obj := make(nodes.Object)
+	isUint := false
+	c, err := dec.next()
+	if err != nil {
+		return nil, err
+	} else if c == '}' {
+		return obj, nil
+	}
+	for {
+		if c != '"' {
+			return nil, dec.errorf("expected object key, got %q", c)
+		}
+		k, err := dec.decodeKey()
+		if err != nil {
+			return nil, err
+		}
+		if dec.kinds && len(k) != 0 && k[0] == keyEscape {
+			if len(k) > 1 && k[1] == keyEscape {
+				k = k[1:]
+			} else if k == uintKey {
+				isUint = true
+			}
+		}
+		if _, ok := obj[k]; ok {
+			return nil, dec.errorf("duplicate key %q", k)
+		}
+		if err = dec.expect(':'); err != nil {
+			return nil, err
+		}
+		v, err := dec.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		obj[k] = v
+		c, err = dec.next()
+		if err != nil {
+			return nil, err
+		} else if c == '}' {
+			break
+		} else if c != ',' {
+			return nil, dec.errorf("expected ',' or '}', got %q", c)
+		}
+		c, err = dec.next()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if isUint {
+		if len(obj) != 1 {
+			return nil, dec.errorf("unexpected fields in %s object", uintKey)
+		}
+		switch v := obj[uintKey].(type) {
+		case nodes.Int:
+			if v >= 0 {
+				return nodes.Uint(v), nil
+			}
+		case nodes.Uint:
+			return v, nil
+		}
+		return nil, dec.errorf("invalid %s value: %v", uintKey, obj[uintKey])
+	}
+	return obj, nil
+}
+
+func (dec *Decoder) decodeArray(depth int) (nodes.Node, error) {
+	arr := nodes.Array{}
+	c, err := dec.next()
+	if err != nil {
+		return nil, err
+	} else if c == ']' {
+		return arr, nil
+	}
+	dec.unreadByte()
+	for {
+		v, err := dec.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, v)
+		c, err = dec.next()
+		if err != nil {
+			return nil, err
+		} else if c == ']' {
+			return arr, nil
+		} else if c != ',' {
+			return nil, dec.errorf("expected ',' or ']', got %q", c)
+		}
+	}
+}
+
+// decodeKey decodes an object key. Keys are interned to reduce allocations.
+func (dec *Decoder) decodeKey() (string, error) {
+	if err := dec.readString(); err != nil {
+		return "", err
+	}
+	if k, ok := dec.keys[string(dec.buf)]; ok {
+		return k, nil
+	}
+	k := string(dec.buf)
+	if len(dec.keys) < maxKeys {
+		dec.keys[k] = k
+	}
+	return k, nil
+}
+
+func (dec *Decoder) decodeString() (string, error) {
+	if err := dec.readString(); err != nil {
+		return "", err
+	}
+	return string(dec.buf), nil
+}
+
+// readString reads the string after an opening quote to the buffer and unescapes it.
+func (dec *Decoder) readString() error {
+	b := dec.buf[:0]
+	defer func() {
+		dec.buf = b
+	}()
+	for {
+		c, err := dec.readByte()
+		if err != nil {
+			return err
+		}
+		switch {
+		case c == '"':
+			return nil
+		case c < 0x20:
+			return dec.errorf("invalid character %q in string", c)
+		case c != '\\':
+			b = append(b, c)
+			continue
+		}
+		c, err = dec.readByte()
+		if err != nil {
+			return err
+		}
+		switch c {
+		case '"', '\\', '/':
+			b = append(b, c)
+		case 'b':
+			b = append(b, '\b')
+		case 'f':
+			b = append(b, '\f')
+		case 'n':
+			b = append(b, '\n')
+		case 'r':
+			b = append(b, '\r')
+		case 't':
+			b = append(b, '\t')
+		case 'u':
+			r, err := dec.readHex()
+			if err != nil {
+				return err
+			}
+			if utf16.IsSurrogate(r) {
+				// try to read the second half of the surrogate pair
+				if next, err := dec.r.Peek(2); err == nil && next[0] == '\\' && next[1] == 'u' {
+					_, _ = dec.r.Discard(2)
+					dec.off += 2
+					r2, err := dec.readHex()
+					if err != nil {
+						return err
+					}
+					if r3 := utf16.DecodeRune(r, r2); r3 != utf8.RuneError {
+						r = r3
+					} else {
+						b = appendRune(b, utf8.RuneError)
+						r = r2
+					}
+				} else {
+					r = utf8.RuneError
+				}
+			}
+			b = appendRune(b, r)
+		default:
+			return dec.errorf("invalid escape sequence '\\%c'", c)
+		}
+	}
+}
+
+func appendRune(b []byte, r rune) []byte {
+	var tmp [utf8.UTFMax]byte
+	n := utf8.EncodeRune(tmp[:], r)
+	return append(b, tmp[:n]...)
+}
+
+func (dec *Decoder) readHex() (rune, error) {
+	var r rune
+	for i := 0; i < 4; i++ {
+		c, err := dec.readByte()
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case c >= '0' && c <= '9':
+			c -= '0'
+		case c >= 'a' && c <= 'f':
+			c -= 'a' - 10
+		case c >= 'A' && c <= 'F':
+			c -= 'A' - 10
+		default:
+			return 0, dec.errorf("invalid character %q in unicode escape", c)
+		}
+		r = r<<4 | rune(c)
+	}
+	return r, nil
+}
+
+func (dec *Decoder) decodeNumber() (nodes.Node, error) {
+	b := dec.buf[:0]
+	for {
+		c, err := dec.r.ReadByte()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if (c >= '0' && c <= '9') || c == '-' || c == '+' || c == '.' || c == 'e' || c == 'E' {
+			dec.off++
+			b = append(b, c)
+			continue
+		}
+		_ = dec.r.UnreadByte()
+		break
+	}
+	dec.buf = b
+	isFloat, ok := scanNumber(b)
+	if !ok {
+		return nil, dec.errorf("invalid number %q", b)
+	}
+	s := string(b)
+	if !isFloat {
+		if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return nodes.Int(v), nil
+		}
+		if v, err := strconv.ParseUint(s, 10, 64); err == nil {
+			return nodes.Uint(v), nil
+		}
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, dec.errorf("invalid number %q: %v", s, err)
+	}
+	// either has a fractional part, or doesn't fit into 64 bits
+	return nodes.Float(v), nil
+}
+
+// scanNumber checks if the number conforms to JSON grammar. It also reports if the number has a fractional
+// part or an exponent.
+func scanNumber(b []byte) (isFloat, ok bool) {
+	i := 0
+	if i < len(b) && b[i] == '-' {
+		i++
+	}
+	digits := func() bool {
+		start := i
+		for i < len(b) && b[i] >= '0' && b[i] <= '9' {
+			i++
+		}
+		return i > start
+	}
+	if i < len(b) && b[i] == '0' {
+		i++
+	} else if !digits() {
+		return false, false
+	}
+	if i < len(b) && b[i] == '.' {
+		i++
+		isFloat = true
+		if !digits() {
+			return false, false
+		}
+	}
+	if i < len(b) && (b[i] == 'e' || b[i] == 'E') {
+		i++
+		isFloat = true
+		if i < len(b) && (b[i] == '+' || b[i] == '-') {
+			i++
+		}
+		if !digits() {
+			return false, false
+		}
+	}
+	return isFloat, i == len(b)
+}
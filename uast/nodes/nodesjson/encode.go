@@ -0,0 +1,302 @@
+// Package nodesjson implements a JSON codec for UAST nodes.
+//
+// In contrast to encoding/json, it encodes and decodes nodes directly, without converting them to native Go
+// values first. Object keys are always written in sorted order, thus the output is deterministic.
+//
+// Numbers with a fractional part or an exponent (for example, 1.0 or 1e3) are always decoded as Float.
+// Other numbers are decoded as Int if they fit into int64, as Uint if they fit into uint64, and as Float
+// otherwise. By default, integral Float values are encoded without a fractional part, and Uint values are
+// encoded as regular numbers. Since JSON has a single number type, this loses the distinction between
+// Int, Uint and Float values with the same numeric value.
+//
+// Kind-preserving mode can be enabled on both the Encoder and the Decoder to encode Float values with a fractional
+// part or an exponent and to encode Uint values as a special object with a single "$uint" field. In this mode,
+// object keys that start with "$" are escaped with an additional "$", so they cannot be confused with Uint values.
+package nodesjson
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+)
+
+const (
+	// uintKey is a key of the object that wraps Uint values in kind-preserving mode.
+	uintKey = "$uint"
+	// keyEscape is a prefix of special keys. Regular keys with this prefix are escaped in kind-preserving mode.
+	keyEscape = '$'
+)
+
+// Marshal encodes the node to JSON.
+func Marshal(n nodes.External) ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	enc := NewEncoder(buf)
+	if err := enc.Encode(n); err != nil {
+		return nil, err
+	}
+	// remove the newline written by the encoder
+	return bytes.TrimSuffix(buf.Bytes(), []byte{'\n'}), nil
+}
+
+// NewEncoder creates a JSON encoder for UAST nodes.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: bufio.NewWriter(w)}
+}
+
+// Encoder writes UAST nodes as JSON values to a stream. Each value is followed by a newline.
+type Encoder struct {
+	w      *bufio.Writer
+	err    error
+	kinds  bool
+	prefix string
+	indent string
+	buf    []byte
+}
+
+// SetKinds enables or disables kind-preserving number encoding. See package documentation for details.
+func (enc *Encoder) SetKinds(enable bool) {
+	enc.kinds = enable
+}
+
+// SetIndent instructs the encoder to format each subsequent encoded value as if indented by the package-level
+// function json.Indent. Calling SetIndent("", "") disables indentation.
+func (enc *Encoder) SetIndent(prefix, indent string) {
+	enc.prefix, enc.indent = prefix, indent
+}
+
+// Encode writes the node to the stream. It accepts both generic nodes and external node implementations.
+func (enc *Encoder) Encode(n nodes.External) error {
+	enc.encode(n, 0)
+	enc.writeByte('\n')
+	if enc.err != nil {
+		return enc.err
+	}
+	return enc.w.Flush()
+}
+
+func (enc *Encoder) encode(n nodes.External, depth int) {
+	if enc.err != nil {
+		return
+	}
+	// fast path for generic nodes
+	switch n := n.(type) {
+	case nil:
+		enc.writeString("null")
+		return
+	case nodes.Object:
+		keys := n.Keys()
+		enc.writeByte('{')
+		for i, k := range keys {
+			enc.writeKey(i, k, depth)
+			enc.encode(n[k], depth+1)
+		}
+		enc.closeComposite('}', len(keys), depth)
+		return
+	case nodes.Array:
+		enc.writeByte('[')
+		for i, v := range n {
+			enc.writeElem(i, depth)
+			enc.encode(v, depth+1)
+		}
+		enc.closeComposite(']', len(n), depth)
+		return
+	case nodes.Value:
+		enc.writeValue(n)
+		return
+	}
+	switch kind := n.Kind(); kind {
+	case nodes.KindNil:
+		enc.writeString("null")
+	case nodes.KindObject:
+		o, ok := n.(nodes.ExternalObject)
+		if !ok {
+			enc.err = fmt.Errorf("node of kind %v doesn't implement ExternalObject: %T", kind, n)
+			return
+		}
+		keys := o.Keys()
+		if !sort.StringsAreSorted(keys) {
+			keys = append([]string{}, keys...)
+			sort.Strings(keys)
+		}
+		enc.writeByte('{')
+		for i, k := range keys {
+			v, _ := o.ValueAt(k)
+			enc.writeKey(i, k, depth)
+			enc.encode(v, depth+1)
+		}
+		enc.closeComposite('}', len(keys), depth)
+	case nodes.KindArray:
+		a, ok := n.(nodes.ExternalArray)
+		if !ok {
+			enc.err = fmt.Errorf("node of kind %v doesn't implement ExternalArray: %T", kind, n)
+			return
+		}
+		sz := a.Size()
+		enc.writeByte('[')
+		for i := 0; i < sz; i++ {
+			enc.writeElem(i, depth)
+			enc.encode(a.ValueAt(i), depth+1)
+		}
+		enc.closeComposite(']', sz, depth)
+	default:
+		enc.writeValue(n.Value())
+	}
+}
+
+func (enc *Encoder) newline(depth int) {
+	if enc.prefix == "" && enc.indent == "" {
+		return
+	}
+	enc.writeByte('\n')
+	enc.writeString(enc.prefix)
+	for i := 0; i < depth; i++ {
+		enc.writeString(enc.indent)
+	}
+}
+
+func (enc *Encoder) writeKey(i int, k string, depth int) {
+	enc.writeElem(i, depth)
+	if enc.kinds && len(k) != 0 && k[0] == keyEscape {
+		k = string(keyEscape) + k
+	}
+	enc.writeQuoted(k)
+	enc.writeByte(':')
+	if enc.indent != "" || enc.prefix != "" {
+		enc.writeByte(' ')
+	}
+}
+
+func (enc *Encoder) writeElem(i int, depth int) {
+	if i != 0 {
+		enc.writeByte(',')
+	}
+	enc.newline(depth + 1)
+}
+
+func (enc *Encoder) closeComposite(c byte, size int, depth int) {
+	if size != 0 {
+		enc.newline(depth)
+	}
+	enc.writeByte(c)
+}
+
+func (enc *Encoder) writeValue(v nodes.Value) {
+	switch v := v.(type) {
+	case nil:
+		enc.writeString("null")
+	case nodes.String:
+		enc.writeQuoted(string(v))
+	case nodes.Int:
+		enc.buf = strconv.AppendInt(enc.buf[:0], int64(v), 10)
+		enc.write(enc.buf)
+	case nodes.Uint:
+		if enc.kinds {
+			enc.writeString(`{"` + uintKey + `":`)
+		}
+		enc.buf = strconv.AppendUint(enc.buf[:0], uint64(v), 10)
+		enc.write(enc.buf)
+		if enc.kinds {
+			enc.writeByte('}')
+		}
+	case nodes.Float:
+		f := float64(v)
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			if enc.err == nil {
+				enc.err = fmt.Errorf("unsupported float value: %v", f)
+			}
+			return
+		}
+		enc.buf = strconv.AppendFloat(enc.buf[:0], f, 'g', -1, 64)
+		if enc.kinds && bytes.IndexAny(enc.buf, ".e") < 0 {
+			enc.buf = append(enc.buf, ".0"...)
+		}
+		enc.write(enc.buf)
+	case nodes.Bool:
+		if v {
+			enc.writeString("true")
+		} else {
+			enc.writeString("false")
+		}
+	default:
+		if enc.err == nil {
+			enc.err = fmt.Errorf("unsupported value type: %T", v)
+		}
+	}
+}
+
+const hex = "0123456789abcdef"
+
+// writeQuoted writes a string as a quoted JSON string. Invalid UTF-8 sequences are replaced with U+FFFD.
+func (enc *Encoder) writeQuoted(s string) {
+	if enc.err != nil {
+		return
+	}
+	b := append(enc.buf[:0], '"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c >= utf8.RuneSelf {
+			r, sz := utf8.DecodeRuneInString(s[i:])
+			if r == utf8.RuneError && sz == 1 {
+				b = append(b, s[start:i]...)
+				b = append(b, `�`...)
+				i += sz
+				start = i
+				continue
+			}
+			i += sz
+			continue
+		}
+		if c >= 0x20 && c != '"' && c != '\\' {
+			i++
+			continue
+		}
+		b = append(b, s[start:i]...)
+		switch c {
+		case '"', '\\':
+			b = append(b, '\\', c)
+		case '\n':
+			b = append(b, '\\', 'n')
+		case '\r':
+			b = append(b, '\\', 'r')
+		case '\t':
+			b = append(b, '\\', 't')
+		default:
+			b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+		}
+		i++
+		start = i
+	}
+	b = append(b, s[start:]...)
+	b = append(b, '"')
+	enc.buf = b
+	enc.write(b)
+}
+
+func (enc *Encoder) write(data []byte) {
+	if enc.err != nil {
+		return
+	}
+	_, enc.err = enc.w.Write(data)
+}
+
+func (enc *Encoder) writeString(s string) {
+	if enc.err != nil {
+		return
+	}
+	_, enc.err = enc.w.WriteString(s)
+}
+
+func (enc *Encoder) writeByte(c byte) {
+	if enc.err != nil {
+		return
+	}
+	enc.err = enc.w.WriteByte(c)
+}
@@ -0,0 +1,311 @@
+package nodesjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+	"github.com/bblfsh/sdk/v3/uast/nodes/nodestest"
+	"github.com/stretchr/testify/require"
+)
+
+var codecCases = []struct {
+	name  string
+	in    nodes.Node
+	json  string
+	kinds string // output in kind-preserving mode, if different
+	out   nodes.Node
+}{
+	{name: "nil", json: `null`},
+	{name: "string", in: nodes.String("a\"\\\n\x01ю"), json: `"a\"\\\n\u0001ю"`},
+	{name: "int", in: nodes.Int(-42), json: `-42`},
+	{
+		name: "uint", in: nodes.Uint(42),
+		json: `42`, kinds: `{"$uint":42}`,
+		out: nodes.Int(42),
+	},
+	{name: "large uint", in: nodes.Uint(math.MaxUint64), json: `18446744073709551615`, kinds: `{"$uint":18446744073709551615}`},
+	{name: "float", in: nodes.Float(4.5), json: `4.5`},
+	{
+		name: "integral float", in: nodes.Float(4),
+		json: `4`, kinds: `4.0`,
+		out: nodes.Int(4),
+	},
+	{name: "bool", in: nodes.Bool(true), json: `true`},
+	{name: "empty object", in: nodes.Object{}, json: `{}`},
+	{name: "empty array", in: nodes.Array{}, json: `[]`},
+	{
+		name: "object",
+		in: nodes.Object{
+			"@type": nodes.String("node"),
+			"b":     nodes.Array{nodes.Int(1), nil, nodes.Bool(false)},
+			"a":     nil,
+			"pos":   nodes.Uint(3),
+		},
+		json:  `{"@type":"node","a":null,"b":[1,null,false],"pos":3}`,
+		kinds: `{"@type":"node","a":null,"b":[1,null,false],"pos":{"$uint":3}}`,
+		out: nodes.Object{
+			"@type": nodes.String("node"),
+			"b":     nodes.Array{nodes.Int(1), nil, nodes.Bool(false)},
+			"a":     nil,
+			"pos":   nodes.Int(3),
+		},
+	},
+	{
+		name: "uint key",
+		in:   nodes.Object{"$uint": nodes.Int(1)},
+		json: `{"$uint":1}`, kinds: `{"$$uint":1}`,
+	},
+	{
+		name: "escaped keys",
+		in: nodes.Object{
+			"$":     nil,
+			"$$x":   nodes.String("a"),
+			"$uint": nodes.Uint(2),
+			"a$":    nodes.Bool(true),
+		},
+		json:  `{"$":null,"$$x":"a","$uint":2,"a$":true}`,
+		kinds: `{"$$":null,"$$$x":"a","$$uint":{"$uint":2},"a$":true}`,
+		out: nodes.Object{
+			"$":     nil,
+			"$$x":   nodes.String("a"),
+			"$uint": nodes.Int(2),
+			"a$":    nodes.Bool(true),
+		},
+	},
+}
+
+func TestCodec(t *testing.T) {
+	for _, c := range codecCases {
+		t.Run(c.name, func(t *testing.T) {
+			data, err := Marshal(c.in)
+			require.NoError(t, err)
+			require.Equal(t, c.json, string(data))
+			require.True(t, json.Valid(data))
+
+			exp := c.out
+			if exp == nil {
+				exp = c.in
+			}
+			out, err := Unmarshal(data)
+			require.NoError(t, err)
+			require.Equal(t, exp, out)
+
+			// kind-preserving mode should return exactly the same node
+			buf := bytes.NewBuffer(nil)
+			enc := NewEncoder(buf)
+			enc.SetKinds(true)
+			err = enc.Encode(c.in)
+			require.NoError(t, err)
+			kinds := c.kinds
+			if kinds == "" {
+				kinds = c.json
+			}
+			require.Equal(t, kinds+"\n", buf.String())
+
+			dec := NewDecoder(buf)
+			dec.SetKinds(true)
+			out, err = dec.Decode()
+			require.NoError(t, err)
+			require.Equal(t, c.in, out)
+		})
+	}
+}
+
+func TestEncodeExternal(t *testing.T) {
+	in := codecCases[len(codecCases)-1].in
+	exp, err := Marshal(in)
+	require.NoError(t, err)
+
+	got, err := Marshal(wrap(in))
+	require.NoError(t, err)
+	require.Equal(t, string(exp), string(got))
+}
+
+func TestEncodeIndent(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	enc := NewEncoder(buf)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(nodes.Object{
+		"a": nodes.Array{nodes.Int(1), nodes.Object{}},
+		"b": nodes.Array{},
+	})
+	require.NoError(t, err)
+	require.Equal(t, `{
+  "a": [
+    1,
+    {}
+  ],
+  "b": []
+}
+`, buf.String())
+}
+
+func TestEncodeErrors(t *testing.T) {
+	_, err := Marshal(nodes.Float(math.NaN()))
+	require.Error(t, err)
+	_, err = Marshal(nodes.Array{nodes.Float(math.Inf(1))})
+	require.Error(t, err)
+}
+
+func TestDecodeStream(t *testing.T) {
+	dec := NewDecoder(bytes.NewReader([]byte(" {\"a\": 1}\n[1, 2.5e1, 1.0]\n\"s\" 18446744073709551616 ")))
+	var out []nodes.Node
+	for {
+		n, err := dec.Decode()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		out = append(out, n)
+	}
+	require.Equal(t, []nodes.Node{
+		nodes.Object{"a": nodes.Int(1)},
+		nodes.Array{nodes.Int(1), nodes.Float(25), nodes.Float(1)},
+		nodes.String("s"),
+		nodes.Float(18446744073709551616),
+	}, out)
+}
+
+func TestDecodeString(t *testing.T) {
+	out, err := Unmarshal([]byte(`"😀\/é\b\f\t\r"`))
+	require.NoError(t, err)
+	require.Equal(t, nodes.String("😀/é\b\f\t\r"), out)
+}
+
+func TestDecodeErrors(t *testing.T) {
+	for _, s := range []string{
+		``,
+		`{`,
+		`{"a" 1}`,
+		`{"a":1,}`,
+		`{"a":1,"a":2}`,
+		`[1,]`,
+		`[1 2]`,
+		`"abc`,
+		`"\x"`,
+		"\"\n\"",
+		`01`,
+		`1.`,
+		`-`,
+		`+1`,
+		`1e`,
+		`tru`,
+		`nul`,
+		`1 2`,
+		`}`,
+	} {
+		t.Run(s, func(t *testing.T) {
+			_, err := Unmarshal([]byte(s))
+			require.Error(t, err)
+		})
+	}
+
+	for _, s := range []string{
+		`{"$uint":-1}`,
+		`{"$uint":"1"}`,
+		`{"$uint":1,"a":2}`,
+		`{"$$uint":1,"$uint":2}`,
+	} {
+		dec := NewDecoder(bytes.NewReader([]byte(s)))
+		dec.SetKinds(true)
+		_, err := dec.Decode()
+		require.Error(t, err, s)
+	}
+}
+
+func TestDecodeDepth(t *testing.T) {
+	data := bytes.Repeat([]byte("["), maxDepth+2)
+	_, err := Unmarshal(data)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "maximum nesting depth exceeded")
+}
+
+// extObject and extArray hide the type of the node to check the generic encoder path.
+type extObject struct {
+	o nodes.Object
+}
+
+type extArray struct {
+	a nodes.Array
+}
+
+func wrap(n nodes.Node) nodes.External {
+	switch n := n.(type) {
+	case nil:
+		return nil
+	case nodes.Object:
+		return extObject{n}
+	case nodes.Array:
+		return extArray{n}
+	}
+	return n
+}
+
+func (extObject) Kind() nodes.Kind             { return nodes.KindObject }
+func (extObject) Value() nodes.Value           { return nil }
+func (extObject) SameAs(n nodes.External) bool { return false }
+func (e extObject) Size() int                  { return len(e.o) }
+func (e extObject) Keys() []string             { return e.o.Keys() }
+func (e extObject) ValueAt(k string) (nodes.External, bool) {
+	v, ok := e.o[k]
+	return wrap(v), ok
+}
+
+func (extArray) Kind() nodes.Kind               { return nodes.KindArray }
+func (extArray) Value() nodes.Value             { return nil }
+func (extArray) SameAs(n nodes.External) bool   { return false }
+func (e extArray) Size() int                    { return len(e.a) }
+func (e extArray) ValueAt(i int) nodes.External { return wrap(e.a[i]) }
+
+func BenchmarkDecode(b *testing.B) {
+	data, err := Marshal(nodestest.GenTree(5, 5))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Run("nodesjson", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			if _, err := Unmarshal(data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("encoding/json", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			var v interface{}
+			if err := json.Unmarshal(data, &v); err != nil {
+				b.Fatal(err)
+			}
+			if _, err := nodes.ToNode(v, nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkEncode(b *testing.B) {
+	tree := nodestest.GenTree(5, 5)
+	b.Run("nodesjson", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := Marshal(tree); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("encoding/json", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := json.Marshal(tree.Native()); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,284 @@
+package nodesmsgpack
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+)
+
+const (
+	// maxDepth is the maximal nesting depth of values accepted by the decoder.
+	maxDepth = 10000
+	// maxPrealloc limits the number of elements allocated in advance, since the size can not be trusted.
+	maxPrealloc = 1024
+	// maxKeys is the maximal number of distinct object keys remembered by the decoder.
+	maxKeys = 4096
+)
+
+// Unmarshal decodes a single MessagePack value to a UAST node.
+func Unmarshal(data []byte) (nodes.Node, error) {
+	dec := NewDecoder(bytes.NewReader(data))
+	n, err := dec.Decode()
+	if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	} else if err != nil {
+		return nil, err
+	}
+	if _, err = dec.r.ReadByte(); err == nil {
+		return nil, dec.errorf("unexpected data after the value")
+	}
+	return n, nil
+}
+
+// NewDecoder creates a MessagePack decoder for UAST nodes.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{
+		r:    bufio.NewReader(r),
+		keys: make(map[string]string),
+	}
+}
+
+// Decoder reads a stream of MessagePack values as UAST nodes.
+type Decoder struct {
+	r    *bufio.Reader
+	off  int64
+	buf  [8]byte
+	str  []byte
+	keys map[string]string // interned object keys
+}
+
+// Decode reads the next value from the stream. It returns io.EOF if there are no more values.
+func (dec *Decoder) Decode() (nodes.Node, error) {
+	if _, err := dec.r.Peek(1); err != nil {
+		return nil, err
+	}
+	return dec.decode(0)
+}
+
+func (dec *Decoder) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("offset %d: %s", dec.off, fmt.Sprintf(format, args...))
+}
+
+func (dec *Decoder) read(n int) ([]byte, error) {
+	b := dec.buf[:n]
+	if _, err := io.ReadFull(dec.r, b); err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil, dec.errorf("unexpected end of input")
+	} else if err != nil {
+		return nil, err
+	}
+	dec.off += int64(n)
+	return b, nil
+}
+
+func (dec *Decoder) decode(depth int) (nodes.Node, error) {
+	if depth > maxDepth {
+		return nil, dec.errorf("maximum nesting depth exceeded")
+	}
+	b, err := dec.read(1)
+	if err != nil {
+		return nil, err
+	}
+	c := b[0]
+	switch {
+	case c <= 0x7f:
+		return nodes.Int(c), nil
+	case c >= negFix:
+		return nodes.Int(int8(c)), nil
+	case c&0xf0 == fixMap:
+		return dec.decodeMap(int(c&0x0f), depth)
+	case c&0xf0 == fixArray:
+		return dec.decodeArray(int(c&0x0f), depth)
+	case c&0xe0 == fixStr:
+		s, err := dec.readStr(int(c & 0x1f))
+		if err != nil {
+			return nil, err
+		}
+		return nodes.String(s), nil
+	}
+	switch c {
+	case codeNil:
+		return nil, nil
+	case codeFalse:
+		return nodes.Bool(false), nil
+	case codeTrue:
+		return nodes.Bool(true), nil
+	case codeFloat32:
+		b, err := dec.read(4)
+		if err != nil {
+			return nil, err
+		}
+		return nodes.Float(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+	case codeFloat64:
+		b, err := dec.read(8)
+		if err != nil {
+			return nil, err
+		}
+		return nodes.Float(math.Float64frombits(binary.BigEndian.Uint64(b))), nil
+	case codeUint8, codeUint16, codeUint32, codeUint64:
+		v, err := dec.readUint(1 << (c - codeUint8))
+		if err != nil {
+			return nil, err
+		}
+		return nodes.Uint(v), nil
+	case codeInt8, codeInt16, codeInt32, codeInt64:
+		n := 1 << (c - codeInt8)
+		v, err := dec.readUint(n)
+		if err != nil {
+			return nil, err
+		}
+		// sign-extend the value
+		shift := uint(64 - 8*n)
+		return nodes.Int(int64(v<<shift) >> shift), nil
+	case codeStr8, codeStr16, codeStr32:
+		sz, err := dec.readUint(1 << (c - codeStr8))
+		if err != nil {
+			return nil, err
+		}
+		s, err := dec.readStr(int(sz))
+		if err != nil {
+			return nil, err
+		}
+		return nodes.String(s), nil
+	case codeArray16, codeArray32:
+		sz, err := dec.readUint(2 << (c - codeArray16))
+		if err != nil {
+			return nil, err
+		}
+		return dec.decodeArray(int(sz), depth)
+	case codeMap16, codeMap32:
+		sz, err := dec.readUint(2 << (c - codeMap16))
+		if err != nil {
+			return nil, err
+		}
+		return dec.decodeMap(int(sz), depth)
+	}
+	return nil, dec.errorf("unsupported type: 0x%x", c)
+}
+
+func (dec *Decoder) readUint(n int) (uint64, error) {
+	b, err := dec.read(n)
+	if err != nil {
+		return 0, err
+	}
+	switch n {
+	case 1:
+		return uint64(b[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	}
+	return binary.BigEndian.Uint64(b), nil
+}
+
+// readBytes reads a string with a given size to the buffer.
+func (dec *Decoder) readBytes(sz int) ([]byte, error) {
+	if sz <= cap(dec.str) || sz <= 64*1024 {
+		if sz > cap(dec.str) {
+			dec.str = make([]byte, sz)
+		}
+		b := dec.str[:sz]
+		if _, err := io.ReadFull(dec.r, b); err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, dec.errorf("unexpected end of input")
+		} else if err != nil {
+			return nil, err
+		}
+		dec.off += int64(sz)
+		return b, nil
+	}
+	// don't trust the size to allocate the buffer - the data might be truncated
+	buf := bytes.NewBuffer(nil)
+	if n, err := io.CopyN(buf, dec.r, int64(sz)); err == io.EOF {
+		return nil, dec.errorf("unexpected end of input")
+	} else if err != nil {
+		return nil, err
+	} else {
+		dec.off += n
+	}
+	dec.str = buf.Bytes()
+	return dec.str, nil
+}
+
+func (dec *Decoder) readStr(sz int) (string, error) {
+	b, err := dec.readBytes(sz)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// readKey reads an object key. Keys are interned to reduce allocations.
+func (dec *Decoder) readKey() (string, error) {
+	b, err := dec.read(1)
+	if err != nil {
+		return "", err
+	}
+	var sz uint64
+	switch c := b[0]; {
+	case c&0xe0 == fixStr:
+		sz = uint64(c & 0x1f)
+	case c >= codeStr8 && c <= codeStr32:
+		sz, err = dec.readUint(1 << (c - codeStr8))
+		if err != nil {
+			return "", err
+		}
+	default:
+		return "", dec.errorf("only string keys are supported, got type 0x%x", c)
+	}
+	kb, err := dec.readBytes(int(sz))
+	if err != nil {
+		return "", err
+	}
+	if k, ok := dec.keys[string(kb)]; ok {
+		return k, nil
+	}
+	k := string(kb)
+	if len(dec.keys) < maxKeys {
+		dec.keys[k] = k
+	}
+	return k, nil
+}
+
+func (dec *Decoder) decodeMap(sz, depth int) (nodes.Node, error) {
+	prealloc := sz
+	if prealloc > maxPrealloc {
+		prealloc = maxPrealloc
+	}
+	obj := make(nodes.Object, prealloc)
+	for i := 0; i < sz; i++ {
+		k, err := dec.readKey()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := obj[k]; ok {
+			return nil, dec.errorf("duplicate key %q", k)
+		}
+		v, err := dec.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		obj[k] = v
+	}
+	return obj, nil
+}
+
+func (dec *Decoder) decodeArray(sz, depth int) (nodes.Node, error) {
+	prealloc := sz
+	if prealloc > maxPrealloc {
+		prealloc = maxPrealloc
+	}
+	arr := make(nodes.Array, 0, prealloc)
+	for i := 0; i < sz; i++ {
+		v, err := dec.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, v)
+	}
+	return arr, nil
+}
@@ -0,0 +1,275 @@
+// Package nodesmsgpack implements a MessagePack codec for UAST nodes.
+//
+// MessagePack is a self-describing binary format with implementations for most languages, thus the encoded
+// UAST can be read by existing libraries without any schema. Objects are encoded as maps with string keys
+// and are always written with sorted keys, arrays are encoded as arrays and values are encoded as the
+// corresponding MessagePack types.
+//
+// Kinds of numeric values are preserved: Int values are encoded as fixint or signed int types, Uint values
+// are encoded as unsigned int types and Float values are encoded as float types.
+package nodesmsgpack
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+)
+
+// MessagePack type codes used by the codec.
+const (
+	codeNil     = 0xc0
+	codeFalse   = 0xc2
+	codeTrue    = 0xc3
+	codeFloat32 = 0xca
+	codeFloat64 = 0xcb
+	codeUint8   = 0xcc
+	codeUint16  = 0xcd
+	codeUint32  = 0xce
+	codeUint64  = 0xcf
+	codeInt8    = 0xd0
+	codeInt16   = 0xd1
+	codeInt32   = 0xd2
+	codeInt64   = 0xd3
+	codeStr8    = 0xd9
+	codeStr16   = 0xda
+	codeStr32   = 0xdb
+	codeArray16 = 0xdc
+	codeArray32 = 0xdd
+	codeMap16   = 0xde
+	codeMap32   = 0xdf
+
+	fixMap   = 0x80
+	fixArray = 0x90
+	fixStr   = 0xa0
+	negFix   = 0xe0
+)
+
+// Marshal encodes the node to MessagePack.
+func Marshal(n nodes.External) ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	enc := NewEncoder(buf)
+	if err := enc.Encode(n); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// NewEncoder creates a MessagePack encoder for UAST nodes.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: bufio.NewWriter(w)}
+}
+
+// Encoder writes UAST nodes to a stream. Multiple nodes are written one after another without any separator.
+type Encoder struct {
+	w   *bufio.Writer
+	err error
+	buf [9]byte
+}
+
+// Encode writes the node to the stream. It accepts both generic nodes and external node implementations.
+func (enc *Encoder) Encode(n nodes.External) error {
+	enc.encode(n)
+	if enc.err != nil {
+		return enc.err
+	}
+	return enc.w.Flush()
+}
+
+func (enc *Encoder) encode(n nodes.External) {
+	if enc.err != nil {
+		return
+	}
+	// fast path for generic nodes
+	switch n := n.(type) {
+	case nil:
+		enc.writeByte(codeNil)
+		return
+	case nodes.Object:
+		keys := n.Keys()
+		enc.writeHead(fixMap, codeMap16, codeMap32, len(keys))
+		for _, k := range keys {
+			enc.writeStr(k)
+			enc.encode(n[k])
+		}
+		return
+	case nodes.Array:
+		enc.writeHead(fixArray, codeArray16, codeArray32, len(n))
+		for _, v := range n {
+			enc.encode(v)
+		}
+		return
+	case nodes.Value:
+		enc.writeValue(n)
+		return
+	}
+	switch kind := n.Kind(); kind {
+	case nodes.KindNil:
+		enc.writeByte(codeNil)
+	case nodes.KindObject:
+		o, ok := n.(nodes.ExternalObject)
+		if !ok {
+			enc.err = fmt.Errorf("node of kind %v doesn't implement ExternalObject: %T", kind, n)
+			return
+		}
+		keys := o.Keys()
+		if !sort.StringsAreSorted(keys) {
+			keys = append([]string{}, keys...)
+			sort.Strings(keys)
+		}
+		enc.writeHead(fixMap, codeMap16, codeMap32, len(keys))
+		for _, k := range keys {
+			v, _ := o.ValueAt(k)
+			enc.writeStr(k)
+			enc.encode(v)
+		}
+	case nodes.KindArray:
+		a, ok := n.(nodes.ExternalArray)
+		if !ok {
+			enc.err = fmt.Errorf("node of kind %v doesn't implement ExternalArray: %T", kind, n)
+			return
+		}
+		sz := a.Size()
+		enc.writeHead(fixArray, codeArray16, codeArray32, sz)
+		for i := 0; i < sz; i++ {
+			enc.encode(a.ValueAt(i))
+		}
+	default:
+		enc.writeValue(n.Value())
+	}
+}
+
+func (enc *Encoder) writeValue(v nodes.Value) {
+	switch v := v.(type) {
+	case nil:
+		enc.writeByte(codeNil)
+	case nodes.String:
+		enc.writeStr(string(v))
+	case nodes.Int:
+		enc.writeInt(int64(v))
+	case nodes.Uint:
+		enc.writeUint(uint64(v))
+	case nodes.Float:
+		f := float64(v)
+		if f32 := float32(f); float64(f32) == f {
+			enc.buf[0] = codeFloat32
+			binary.BigEndian.PutUint32(enc.buf[1:], math.Float32bits(f32))
+			enc.write(enc.buf[:5])
+			return
+		}
+		enc.buf[0] = codeFloat64
+		binary.BigEndian.PutUint64(enc.buf[1:], math.Float64bits(f))
+		enc.write(enc.buf[:9])
+	case nodes.Bool:
+		if v {
+			enc.writeByte(codeTrue)
+		} else {
+			enc.writeByte(codeFalse)
+		}
+	default:
+		if enc.err == nil {
+			enc.err = fmt.Errorf("unsupported value type: %T", v)
+		}
+	}
+}
+
+// writeInt writes a signed integer. Small values are written as fixint, others are written as signed int types,
+// thus the decoder can distinguish them from unsigned values.
+func (enc *Encoder) writeInt(v int64) {
+	switch {
+	case v >= 0 && v <= math.MaxInt8:
+		enc.writeByte(byte(v))
+	case v < 0 && v >= -32:
+		enc.writeByte(byte(v))
+	case v >= math.MinInt8 && v <= math.MaxInt8:
+		enc.buf[0], enc.buf[1] = codeInt8, byte(v)
+		enc.write(enc.buf[:2])
+	case v >= math.MinInt16 && v <= math.MaxInt16:
+		enc.buf[0] = codeInt16
+		binary.BigEndian.PutUint16(enc.buf[1:], uint16(v))
+		enc.write(enc.buf[:3])
+	case v >= math.MinInt32 && v <= math.MaxInt32:
+		enc.buf[0] = codeInt32
+		binary.BigEndian.PutUint32(enc.buf[1:], uint32(v))
+		enc.write(enc.buf[:5])
+	default:
+		enc.buf[0] = codeInt64
+		binary.BigEndian.PutUint64(enc.buf[1:], uint64(v))
+		enc.write(enc.buf[:9])
+	}
+}
+
+// writeUint writes an unsigned integer. It never uses fixint, thus the decoder can distinguish it from signed values.
+func (enc *Encoder) writeUint(v uint64) {
+	switch {
+	case v <= math.MaxUint8:
+		enc.buf[0], enc.buf[1] = codeUint8, byte(v)
+		enc.write(enc.buf[:2])
+	case v <= math.MaxUint16:
+		enc.buf[0] = codeUint16
+		binary.BigEndian.PutUint16(enc.buf[1:], uint16(v))
+		enc.write(enc.buf[:3])
+	case v <= math.MaxUint32:
+		enc.buf[0] = codeUint32
+		binary.BigEndian.PutUint32(enc.buf[1:], uint32(v))
+		enc.write(enc.buf[:5])
+	default:
+		enc.buf[0] = codeUint64
+		binary.BigEndian.PutUint64(enc.buf[1:], v)
+		enc.write(enc.buf[:9])
+	}
+}
+
+// writeHead writes a header of a string, a map or an array with a given size.
+func (enc *Encoder) writeHead(fix, code16, code32 byte, sz int) {
+	switch {
+	case sz < 16 && fix != fixStr:
+		enc.writeByte(fix | byte(sz))
+	case sz < 32 && fix == fixStr:
+		enc.writeByte(fix | byte(sz))
+	case sz <= math.MaxUint16:
+		enc.buf[0] = code16
+		binary.BigEndian.PutUint16(enc.buf[1:], uint16(sz))
+		enc.write(enc.buf[:3])
+	case uint64(sz) <= math.MaxUint32:
+		enc.buf[0] = code32
+		binary.BigEndian.PutUint32(enc.buf[1:], uint32(sz))
+		enc.write(enc.buf[:5])
+	default:
+		if enc.err == nil {
+			enc.err = fmt.Errorf("size is too large: %d", sz)
+		}
+	}
+}
+
+func (enc *Encoder) writeStr(s string) {
+	if len(s) >= 32 && len(s) <= math.MaxUint8 {
+		enc.buf[0], enc.buf[1] = codeStr8, byte(len(s))
+		enc.write(enc.buf[:2])
+	} else {
+		enc.writeHead(fixStr, codeStr16, codeStr32, len(s))
+	}
+	if enc.err != nil {
+		return
+	}
+	_, enc.err = enc.w.WriteString(s)
+}
+
+func (enc *Encoder) write(data []byte) {
+	if enc.err != nil {
+		return
+	}
+	_, enc.err = enc.w.Write(data)
+}
+
+func (enc *Encoder) writeByte(c byte) {
+	if enc.err != nil {
+		return
+	}
+	enc.err = enc.w.WriteByte(c)
+}
@@ -0,0 +1,201 @@
+package nodesmsgpack
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+	"github.com/bblfsh/sdk/v3/uast/nodes/nodesjson"
+	"github.com/bblfsh/sdk/v3/uast/nodes/nodesproto"
+	"github.com/bblfsh/sdk/v3/uast/nodes/nodestest"
+	"github.com/bblfsh/sdk/v3/uast/uastyaml"
+	"github.com/stretchr/testify/require"
+)
+
+var codecCases = []struct {
+	name string
+	in   nodes.Node
+	data []byte
+}{
+	{name: "nil", data: []byte{0xc0}},
+	{name: "true", in: nodes.Bool(true), data: []byte{0xc3}},
+	{name: "false", in: nodes.Bool(false), data: []byte{0xc2}},
+	{name: "fixint", in: nodes.Int(5), data: []byte{0x05}},
+	{name: "negative fixint", in: nodes.Int(-5), data: []byte{0xfb}},
+	{name: "int8", in: nodes.Int(-100), data: []byte{0xd0, 0x9c}},
+	{name: "int16", in: nodes.Int(300), data: []byte{0xd1, 0x01, 0x2c}},
+	{name: "int32", in: nodes.Int(-70000), data: []byte{0xd2, 0xff, 0xfe, 0xee, 0x90}},
+	{name: "int64", in: nodes.Int(math.MinInt64), data: []byte{0xd3, 0x80, 0, 0, 0, 0, 0, 0, 0}},
+	{name: "uint8", in: nodes.Uint(5), data: []byte{0xcc, 0x05}},
+	{name: "uint16", in: nodes.Uint(300), data: []byte{0xcd, 0x01, 0x2c}},
+	{name: "uint32", in: nodes.Uint(70000), data: []byte{0xce, 0, 0x01, 0x11, 0x70}},
+	{name: "uint64", in: nodes.Uint(math.MaxUint64), data: []byte{0xcf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+	{name: "float32", in: nodes.Float(1.5), data: []byte{0xca, 0x3f, 0xc0, 0, 0}},
+	{name: "float64", in: nodes.Float(0.1), data: []byte{0xcb, 0x3f, 0xb9, 0x99, 0x99, 0x99, 0x99, 0x99, 0x9a}},
+	{name: "fixstr", in: nodes.String("abc"), data: []byte{0xa3, 'a', 'b', 'c'}},
+	{name: "str8", in: nodes.String(strings.Repeat("a", 40))},
+	{name: "str16", in: nodes.String(strings.Repeat("a", 300))},
+	{name: "str32", in: nodes.String(strings.Repeat("a", 70000))},
+	{name: "empty object", in: nodes.Object{}, data: []byte{0x80}},
+	{name: "empty array", in: nodes.Array{}, data: []byte{0x90}},
+	{
+		name: "object",
+		in: nodes.Object{
+			"b": nodes.Array{nodes.Int(1), nil},
+			"a": nodes.Uint(1),
+		},
+		data: []byte{0x82, 0xa1, 'a', 0xcc, 0x01, 0xa1, 'b', 0x92, 0x01, 0xc0},
+	},
+	{name: "array16", in: genArray(20)},
+	{name: "map16", in: genObject(20)},
+	{name: "array32", in: genArray(70000)},
+	{name: "map32", in: genObject(70000)},
+}
+
+func genArray(n int) nodes.Array {
+	arr := make(nodes.Array, 0, n)
+	for i := 0; i < n; i++ {
+		arr = append(arr, nodes.Int(i))
+	}
+	return arr
+}
+
+func genObject(n int) nodes.Object {
+	obj := make(nodes.Object, n)
+	for i := 0; i < n; i++ {
+		obj[fmt.Sprint(i)] = nodes.Int(i)
+	}
+	return obj
+}
+
+func TestCodec(t *testing.T) {
+	for _, c := range codecCases {
+		t.Run(c.name, func(t *testing.T) {
+			data, err := Marshal(c.in)
+			require.NoError(t, err)
+			if c.data != nil {
+				require.Equal(t, c.data, data)
+			}
+			out, err := Unmarshal(data)
+			require.NoError(t, err)
+			require.Equal(t, c.in, out)
+
+			// truncated data should always return an error
+			if len(data) > 1 {
+				_, err = Unmarshal(data[:len(data)-1])
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestDecodeStream(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	enc := NewEncoder(buf)
+	for _, c := range codecCases {
+		err := enc.Encode(c.in)
+		require.NoError(t, err)
+	}
+	dec := NewDecoder(buf)
+	for _, c := range codecCases {
+		out, err := dec.Decode()
+		require.NoError(t, err, c.name)
+		require.Equal(t, c.in, out, c.name)
+	}
+	_, err := dec.Decode()
+	require.Equal(t, io.EOF, err)
+}
+
+func TestDecodeErrors(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		data []byte
+	}{
+		{name: "empty"},
+		{name: "bin", data: []byte{0xc4, 0x01, 0x00}},
+		{name: "int key", data: []byte{0x81, 0x01, 0x01}},
+		{name: "duplicate key", data: []byte{0x82, 0xa1, 'a', 0x01, 0xa1, 'a', 0x02}},
+		{name: "trailing data", data: []byte{0x01, 0x02}},
+		{name: "large string", data: []byte{0xdb, 0xff, 0xff, 0xff, 0xff, 'a'}},
+		{name: "large array", data: []byte{0xdd, 0xff, 0xff, 0xff, 0xff, 0x01}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := Unmarshal(c.data)
+			require.Error(t, err)
+		})
+	}
+}
+
+type benchCodec struct {
+	name   string
+	encode func(n nodes.Node) ([]byte, error)
+	decode func(data []byte) (nodes.Node, error)
+}
+
+var benchCodecs = []benchCodec{
+	{
+		name:   "msgpack",
+		encode: func(n nodes.Node) ([]byte, error) { return Marshal(n) },
+		decode: Unmarshal,
+	},
+	{
+		name: "nodesproto",
+		encode: func(n nodes.Node) ([]byte, error) {
+			buf := bytes.NewBuffer(nil)
+			err := nodesproto.WriteTo(buf, n)
+			return buf.Bytes(), err
+		},
+		decode: func(data []byte) (nodes.Node, error) {
+			return nodesproto.ReadTree(bytes.NewReader(data))
+		},
+	},
+	{
+		name:   "json",
+		encode: func(n nodes.Node) ([]byte, error) { return nodesjson.Marshal(n) },
+		decode: nodesjson.Unmarshal,
+	},
+	{
+		name:   "yaml",
+		encode: func(n nodes.Node) ([]byte, error) { return uastyaml.Marshal(n) },
+		decode: uastyaml.Unmarshal,
+	},
+}
+
+func BenchmarkEncode(b *testing.B) {
+	tree := nodestest.GenTree(4, 5)
+	for _, c := range benchCodecs {
+		b.Run(c.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				data, err := c.encode(tree)
+				if err != nil {
+					b.Fatal(err)
+				}
+				b.SetBytes(int64(len(data)))
+			}
+		})
+	}
+}
+
+func BenchmarkDecode(b *testing.B) {
+	tree := nodestest.GenTree(4, 5)
+	for _, c := range benchCodecs {
+		data, err := c.encode(tree)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(c.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				if _, err := c.decode(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package nodesproto
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+)
+
+// Header describes the format of the binary graph.
+type Header struct {
+	Version    int  `json:"version"`
+	Compressed bool `json:"compressed,omitempty"`
+}
+
+// ReadHeader reads the header of the binary graph from r.
+func ReadHeader(r io.Reader) (*Header, error) {
+	vers, flags, err := readHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	return &Header{
+		Version:    int(vers),
+		Compressed: flags&flagGzip != 0,
+	}, nil
+}
+
+// IsGraph checks if the data starts with a magic number of the binary graph.
+// It can be used to detect the format of a file, but it does not check if the graph is valid.
+func IsGraph(data []byte) bool {
+	return len(data) >= len(magic) && string(data[:len(magic)]) == magic
+}
+
+// Validate checks the graph for consistency. It returns all problems found in the graph.
+//
+// It checks that all referenced nodes are defined, that objects have the same number of keys and values,
+// that all keys are strings and are unique within an object, and that the graph reachable from the root
+// has no cycles.
+func (g *RawGraph) Validate() []error {
+	var errs []error
+	ids := make([]uint64, 0, len(g.Nodes))
+	for id := range g.Nodes {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+	if g.Root != 0 {
+		if _, ok := g.Nodes[g.Root]; !ok {
+			errs = append(errs, fmt.Errorf("root refers to an undefined node %d", g.Root))
+		}
+	}
+	if g.Meta != 0 {
+		if _, ok := g.Nodes[g.Meta]; !ok {
+			errs = append(errs, fmt.Errorf("metadata refers to an undefined node %d", g.Meta))
+		}
+	}
+	for _, id := range ids {
+		n := g.Nodes[id]
+		if g.Last != 0 && id > g.Last {
+			errs = append(errs, fmt.Errorf("node %d: id is larger than the last id %d", id, g.Last))
+		}
+		if n.Kind == nodes.KindObject {
+			errs = append(errs, g.checkKeys(n)...)
+		}
+		for _, v := range n.Values {
+			if v == 0 {
+				continue
+			}
+			if _, ok := g.Nodes[v]; !ok {
+				errs = append(errs, fmt.Errorf("node %d: value refers to an undefined node %d", id, v))
+			}
+		}
+	}
+	// only check cycles from the root, since dangling references are already reported
+	if _, ok := g.Nodes[g.Root]; ok {
+		if err := g.checkCycles(g.Root); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// checkKeys validates keys of an object node.
+func (g *RawGraph) checkKeys(n RawNode) []error {
+	var errs []error
+	if len(n.Keys) != len(n.Values) {
+		errs = append(errs, fmt.Errorf("node %d: number of keys doesn't match a number of values: %d vs %d",
+			n.ID, len(n.Keys), len(n.Values)))
+	}
+	seen := make(map[string]struct{}, len(n.Keys))
+	for _, k := range n.Keys {
+		kn, ok := g.Nodes[k]
+		if !ok {
+			errs = append(errs, fmt.Errorf("node %d: key refers to an undefined node %d", n.ID, k))
+			continue
+		}
+		s, ok := kn.Value.(nodes.String)
+		if !ok {
+			errs = append(errs, fmt.Errorf("node %d: key %d is not a string: %v", n.ID, k, kn.Kind))
+			continue
+		}
+		if _, ok := seen[string(s)]; ok {
+			errs = append(errs, fmt.Errorf("node %d: duplicate key %q", n.ID, string(s)))
+		}
+		seen[string(s)] = struct{}{}
+	}
+	return errs
+}
+
+// checkCycles checks that the graph reachable from a given node is acyclic.
+func (g *RawGraph) checkCycles(root uint64) error {
+	const (
+		active = 1
+		done   = 2
+	)
+	state := make(map[uint64]int, len(g.Nodes))
+	var visit func(id uint64) error
+	visit = func(id uint64) error {
+		switch state[id] {
+		case active:
+			return fmt.Errorf("not a DAG: node %d is a part of a cycle", id)
+		case done:
+			return nil
+		}
+		n, ok := g.Nodes[id]
+		if !ok {
+			return nil
+		}
+		state[id] = active
+		for _, v := range n.Values {
+			if v == 0 {
+				continue
+			}
+			if err := visit(v); err != nil {
+				return err
+			}
+		}
+		state[id] = done
+		return nil
+	}
+	return visit(root)
+}
@@ -0,0 +1,222 @@
+package nodesproto
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+)
+
+const (
+	// Version1 is the original version of the binary graph format.
+	//
+	// The header is directly followed by the varint-delimited GraphHeader and Node messages.
+	Version1 = 0x1
+	// Version2 is a version of the binary graph format with checksums and an optional compression.
+	//
+	// The header is followed by a 4 byte little-endian flags field and two sections: the first one
+	// contains the GraphHeader message, the second one contains all Node messages. Each section
+	// is written as a 8 byte little-endian size of the stored data, followed by a 4 byte
+	// little-endian CRC-32 (IEEE) checksum of the stored data and the data itself.
+	// If the data is compressed, the size and the checksum refer to compressed bytes.
+	Version2 = 0x2
+)
+
+const (
+	// flagGzip indicates that all sections are compressed with gzip.
+	flagGzip = 1 << iota
+
+	flagsKnown = flagGzip
+)
+
+const (
+	headerSize  = 8
+	flagsSize   = 4
+	sectionHead = 12
+)
+
+// maxSection is a maximal size of the section data. It matches the size limit of the node message.
+const maxSection = 1 << 30
+
+// WriteOptions controls the format of the binary graph.
+type WriteOptions struct {
+	// Version of the format to write. Zero value selects the oldest version that supports all enabled options.
+	Version int
+	// Compress enables gzip compression of the graph. Requires Version2.
+	Compress bool
+	// Metadata is an optional node stored in the graph alongside the tree. See WriteWithMeta.
+	Metadata nodes.Node
+}
+
+// format returns the version and flags of the binary format for these options.
+func (opt WriteOptions) format() (uint32, uint32, error) {
+	vers := opt.Version
+	if vers == 0 {
+		vers = Version1
+		if opt.Compress {
+			vers = Version2
+		}
+	}
+	switch vers {
+	case Version1:
+		if opt.Compress {
+			return 0, 0, fmt.Errorf("compression is not supported in version %d", vers)
+		}
+		return Version1, 0, nil
+	case Version2:
+		var flags uint32
+		if opt.Compress {
+			flags |= flagGzip
+		}
+		return Version2, flags, nil
+	}
+	return 0, 0, fmt.Errorf("unsupported version: %x", vers)
+}
+
+func writeHeader(w io.Writer, vers, flags uint32) error {
+	var header [headerSize + flagsSize]byte
+	copy(header[:4], magic)
+	binary.LittleEndian.PutUint32(header[4:], vers)
+	sz := headerSize
+	if vers != Version1 {
+		binary.LittleEndian.PutUint32(header[headerSize:], flags)
+		sz += flagsSize
+	}
+	_, err := w.Write(header[:sz])
+	return err
+}
+
+// writeSection compresses the data according to flags and writes it as a section with a checksum.
+func writeSection(w io.Writer, data []byte, flags uint32) error {
+	if flags&flagGzip != 0 {
+		buf := bytes.NewBuffer(nil)
+		zw := gzip.NewWriter(buf)
+		if _, err := zw.Write(data); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		data = buf.Bytes()
+	}
+	var head [sectionHead]byte
+	binary.LittleEndian.PutUint64(head[:8], uint64(len(data)))
+	binary.LittleEndian.PutUint32(head[8:], crc32.ChecksumIEEE(data))
+	if _, err := w.Write(head[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// readHeader reads the magic, the version and the flags of the binary graph.
+func readHeader(r io.Reader) (uint32, uint32, error) {
+	var b [headerSize]byte
+	if _, err := io.ReadFull(r, b[:]); err == io.EOF || err == io.ErrUnexpectedEOF {
+		return 0, 0, fmt.Errorf("truncated graph header")
+	} else if err != nil {
+		return 0, 0, err
+	}
+	if string(b[:4]) != magic {
+		return 0, 0, fmt.Errorf("not a graph file")
+	}
+	vers := binary.LittleEndian.Uint32(b[4:])
+	switch vers {
+	case Version1:
+		return vers, 0, nil
+	case Version2:
+	default:
+		return 0, 0, fmt.Errorf("unsupported version: %x", vers)
+	}
+	if _, err := io.ReadFull(r, b[:flagsSize]); err == io.EOF || err == io.ErrUnexpectedEOF {
+		return 0, 0, fmt.Errorf("truncated graph header")
+	} else if err != nil {
+		return 0, 0, err
+	}
+	flags, err := checkFlags(b[:flagsSize])
+	if err != nil {
+		return 0, 0, err
+	}
+	return vers, flags, nil
+}
+
+func checkFlags(b []byte) (uint32, error) {
+	flags := binary.LittleEndian.Uint32(b)
+	if flags&^flagsKnown != 0 {
+		return 0, fmt.Errorf("unsupported flags: %x", flags)
+	}
+	return flags, nil
+}
+
+// readSection reads a single section from the stream, verifies the checksum and decompresses it.
+func readSection(r io.Reader, flags uint32, name string) ([]byte, error) {
+	var head [sectionHead]byte
+	if _, err := io.ReadFull(r, head[:]); err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil, fmt.Errorf("truncated %s section", name)
+	} else if err != nil {
+		return nil, err
+	}
+	sz := binary.LittleEndian.Uint64(head[:8])
+	if sz > maxSection {
+		return nil, fmt.Errorf("%s section is too large: %d bytes", name, sz)
+	}
+	// don't trust the size to allocate the buffer - the data might be truncated
+	buf := bytes.NewBuffer(nil)
+	if n, err := buf.ReadFrom(io.LimitReader(r, int64(sz))); err != nil {
+		return nil, err
+	} else if uint64(n) != sz {
+		return nil, fmt.Errorf("truncated %s section: expected %d bytes, got %d", name, sz, n)
+	}
+	return decodeSection(buf.Bytes(), binary.LittleEndian.Uint32(head[8:]), flags, name)
+}
+
+// sectionAt reads a single section from a byte slice at a given offset. It returns the section data
+// and the offset of the next section. Uncompressed data is not copied.
+func sectionAt(data []byte, off int, flags uint32, name string) ([]byte, int, error) {
+	if len(data)-off < sectionHead {
+		return nil, 0, fmt.Errorf("truncated %s section", name)
+	}
+	sz := binary.LittleEndian.Uint64(data[off:])
+	crc := binary.LittleEndian.Uint32(data[off+8:])
+	off += sectionHead
+	if sz > uint64(len(data)-off) {
+		return nil, 0, fmt.Errorf("truncated %s section: expected %d bytes, got %d", name, sz, len(data)-off)
+	}
+	end := off + int(sz)
+	sect, err := decodeSection(data[off:end], crc, flags, name)
+	if err != nil {
+		return nil, 0, err
+	}
+	return sect, end, nil
+}
+
+// decodeSection verifies the checksum of the section data and decompresses it.
+func decodeSection(data []byte, crc, flags uint32, name string) ([]byte, error) {
+	if crc32.ChecksumIEEE(data) != crc {
+		return nil, fmt.Errorf("checksum mismatch in %s section", name)
+	}
+	if flags&flagGzip == 0 {
+		return data, nil
+	}
+	return decompress(data, maxSection, name)
+}
+
+// decompress decompresses the section data. The size of the decompressed data must not exceed the limit.
+func decompress(data []byte, limit int64, name string) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("cannot decompress %s section: %v", name, err)
+	}
+	data, err = ioutil.ReadAll(io.LimitReader(zr, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("cannot decompress %s section: %v", name, err)
+	} else if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%s section is too large: more than %d bytes when decompressed", name, limit)
+	}
+	return data, nil
+}
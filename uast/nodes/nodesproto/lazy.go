@@ -0,0 +1,427 @@
+package nodesproto
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+)
+
+// LazyGraph provides random access to nodes of a binary graph without decoding it fully.
+//
+// When opened, the graph is only scanned to build an index of node offsets. Nodes are decoded
+// on demand when accessed via Node or via the External interface returned by Root.
+// Decoded objects and arrays are cached, while values are decoded on each access.
+//
+// LazyGraph is safe for concurrent use.
+type LazyGraph struct {
+	data []byte
+	root uint64
+	meta uint64
+	last uint64
+
+	ids  []uint64 // sorted node IDs
+	offs []lazySpan
+
+	mu    sync.Mutex
+	cache map[uint64]*lazyNode
+}
+
+// lazySpan is a location of a node message in the data buffer.
+type lazySpan struct {
+	off, size int
+}
+
+// lazyNode is a decoded object or array.
+type lazyNode struct {
+	id   uint64
+	kind nodes.Kind
+	keys []string // sorted; nil for arrays
+	vals []uint64
+}
+
+// OpenLazy opens a binary graph stored in data for random access. See LazyGraph for details.
+//
+// The data must not be modified while the graph is in use. It is safe to pass a memory-mapped file.
+// Compressed graphs are decompressed into memory when opened.
+//
+// The root node is optional in the format. If the header does not set it, the root is detected the same way
+// as in ReadTree: a single node that is not referenced by other nodes becomes the root, and multiple such nodes
+// are wrapped into an array. This requires decoding the whole graph once when it is opened, thus graphs written
+// by WriteTo, which always sets the root, are opened faster.
+func OpenLazy(data []byte) (*LazyGraph, error) {
+	g := &LazyGraph{
+		cache: make(map[uint64]*lazyNode),
+	}
+	vers, flags, err := readHeader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	var hdr []byte
+	off := headerSize
+	if vers == Version1 {
+		g.data = data
+		hdr, err = nextMsg(data, &off)
+	} else {
+		off += flagsSize
+		var sect []byte
+		sect, off, err = sectionAt(data, off, flags, "header")
+		if err != nil {
+			return nil, err
+		}
+		g.data, _, err = sectionAt(data, off, flags, "nodes")
+		if err != nil {
+			return nil, err
+		}
+		off = 0
+		hdr, err = nextMsg(sect, new(int))
+	}
+	if err != nil {
+		return nil, err
+	}
+	var gh GraphHeader
+	if err = gh.Unmarshal(hdr); err != nil {
+		return nil, err
+	}
+	g.last, g.root, g.meta = gh.LastId, gh.Root, gh.Metadata
+
+	var prevID uint64
+	for off < len(g.data) {
+		msg, err := nextMsg(g.data, &off)
+		if err != nil {
+			return nil, err
+		}
+		id, err := scanNodeID(msg)
+		if err != nil {
+			return nil, err
+		}
+		if id == 0 {
+			// allow to omit ID
+			id = prevID + 1
+		} else if prevID >= id {
+			// but IDs should be ascending
+			return nil, fmt.Errorf("node IDs should be ascending")
+		}
+		prevID = id
+		g.ids = append(g.ids, id)
+		g.offs = append(g.offs, lazySpan{off: off - len(msg), size: len(msg)})
+	}
+	if g.root == 0 && len(g.ids) != 0 {
+		// root is not set - we need to decode the whole graph to find it; see the doc above
+		gr := newGraphReader()
+		if err := gr.readGraph(bytes.NewReader(data)); err != nil {
+			return nil, err
+		}
+		g.root = gr.root
+		if i := g.index(g.root); i < 0 && g.root != 0 {
+			// the reader created an artificial root node
+			n := gr.nodes[g.root]
+			g.cache[g.root] = &lazyNode{id: g.root, kind: nodes.KindArray, vals: n.Values}
+		}
+	}
+	return g, nil
+}
+
+// nextMsg reads the next varint-delimited message at a given offset and advances the offset.
+func nextMsg(data []byte, off *int) ([]byte, error) {
+	sz, n := binary.Uvarint(data[*off:])
+	if n <= 0 {
+		return nil, fmt.Errorf("invalid message size at offset %d", *off)
+	}
+	start := *off + n
+	end := start + int(sz)
+	if sz > uint64(len(data)) || end > len(data) {
+		return nil, fmt.Errorf("unexpected end of data at offset %d", *off)
+	}
+	*off = end
+	return data[start:end], nil
+}
+
+// scanNodeID reads an ID field of the Node message without decoding the whole message.
+// It returns zero if the field is not set.
+func scanNodeID(msg []byte) (uint64, error) {
+	for len(msg) > 0 {
+		key, n := binary.Uvarint(msg)
+		if n <= 0 {
+			return 0, fmt.Errorf("invalid field key")
+		}
+		msg = msg[n:]
+		field, wire := key>>3, key&0x7
+		switch wire {
+		case 0: // varint
+			v, n := binary.Uvarint(msg)
+			if n <= 0 {
+				return 0, fmt.Errorf("invalid varint")
+			}
+			if field == 1 {
+				return v, nil
+			}
+			msg = msg[n:]
+		case 1: // fixed64
+			if len(msg) < 8 {
+				return 0, fmt.Errorf("unexpected end of message")
+			}
+			msg = msg[8:]
+		case 2: // length-delimited
+			sz, n := binary.Uvarint(msg)
+			if n <= 0 || sz > uint64(len(msg)-n) {
+				return 0, fmt.Errorf("invalid field length")
+			}
+			msg = msg[n+int(sz):]
+		case 5: // fixed32
+			if len(msg) < 4 {
+				return 0, fmt.Errorf("unexpected end of message")
+			}
+			msg = msg[4:]
+		default:
+			return 0, fmt.Errorf("unsupported wire type: %d", wire)
+		}
+	}
+	return 0, nil
+}
+
+// Len returns the number of nodes stored in the graph.
+func (g *LazyGraph) Len() int {
+	return len(g.ids)
+}
+
+// RootID returns an ID of the root node. It returns 0 if the graph is empty.
+func (g *LazyGraph) RootID() uint64 {
+	return g.root
+}
+
+// MetaID returns an ID of the metadata node. It returns 0 if the metadata is not set.
+func (g *LazyGraph) MetaID() uint64 {
+	return g.meta
+}
+
+// Root returns the root node of the graph.
+//
+// Nodes are decoded lazily, thus the returned node will panic on access if the graph data is malformed.
+// Cyclic graphs are not detected, so the caller should avoid traversing the whole tree if the graph
+// may contain cycles. Nodes that are referenced multiple times in the graph are shared, and the tree
+// must be treated as immutable.
+func (g *LazyGraph) Root() nodes.External {
+	n, err := g.Node(g.root)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// Meta returns the metadata node of the graph, or nil if the metadata is not set.
+//
+// See Root for details on how the nodes are decoded.
+func (g *LazyGraph) Meta() nodes.External {
+	n, err := g.Node(g.meta)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// Node decodes a node with a given ID. Children of the node are decoded lazily.
+// It returns nil for the ID 0.
+func (g *LazyGraph) Node(id uint64) (nodes.External, error) {
+	if id == 0 {
+		return nil, nil
+	}
+	g.mu.Lock()
+	ln, ok := g.cache[id]
+	g.mu.Unlock()
+	if ok {
+		return ln.external(g), nil
+	}
+	nd, err := g.decode(id)
+	if err != nil {
+		return nil, err
+	}
+	if nd.Value != nil {
+		return asValue(nd)
+	}
+	ln = &lazyNode{id: id, kind: nd.Kind(), vals: nd.Values}
+	if ln.kind == nodes.KindObject {
+		if err = g.resolveKeys(ln, nd); err != nil {
+			return nil, err
+		}
+	}
+	g.mu.Lock()
+	g.cache[id] = ln
+	g.mu.Unlock()
+	return ln.external(g), nil
+}
+
+func (g *LazyGraph) index(id uint64) int {
+	i := sort.Search(len(g.ids), func(i int) bool {
+		return g.ids[i] >= id
+	})
+	if i >= len(g.ids) || g.ids[i] != id {
+		return -1
+	}
+	return i
+}
+
+// decode reads a node message with a given ID from the data buffer.
+func (g *LazyGraph) decode(id uint64) (*Node, error) {
+	i := g.index(id)
+	if i < 0 {
+		return nil, fmt.Errorf("node %v is not defined", id)
+	}
+	sp := g.offs[i]
+	nd := &Node{}
+	if err := nd.Unmarshal(g.data[sp.off : sp.off+sp.size]); err != nil {
+		return nil, fmt.Errorf("node %v: %v", id, err)
+	}
+	nd.Id = id
+	if nd.KeysFrom != 0 {
+		if nd.KeysFrom >= id {
+			return nil, fmt.Errorf("KeysFrom refers to an undefined node %d", nd.KeysFrom)
+		}
+		n2, err := g.decode(nd.KeysFrom)
+		if err != nil {
+			return nil, err
+		}
+		nd.Keys = n2.Keys
+	} else if keysDiff && len(nd.Keys) > 1 {
+		cur := nd.Keys[0]
+		for i := 1; i < len(nd.Keys); i++ {
+			v := nd.Keys[i]
+			v += cur
+			nd.Keys[i] = v
+			cur = v
+		}
+	}
+	if nd.ValuesOffs != 0 {
+		for i := range nd.Values {
+			nd.Values[i] += nd.ValuesOffs
+		}
+	}
+	return nd, nil
+}
+
+// resolveKeys decodes object keys and sorts them together with values.
+func (g *LazyGraph) resolveKeys(ln *lazyNode, nd *Node) error {
+	if len(nd.Keys) != len(nd.Values) {
+		return fmt.Errorf("number of keys doesn't match a number of values: %d vs %d", len(nd.Keys), len(nd.Values))
+	}
+	ln.keys = make([]string, len(nd.Keys))
+	for i, k := range nd.Keys {
+		kn, err := g.decode(k)
+		if err != nil {
+			return err
+		}
+		v, err := asValue(kn)
+		if err != nil {
+			return err
+		}
+		s, ok := v.(nodes.String)
+		if !ok {
+			return fmt.Errorf("only string keys are supported")
+		}
+		ln.keys[i] = string(s)
+	}
+	sort.Sort(lazyKeys{keys: ln.keys, vals: ln.vals})
+	return nil
+}
+
+type lazyKeys struct {
+	keys []string
+	vals []uint64
+}
+
+func (arr lazyKeys) Len() int {
+	return len(arr.keys)
+}
+
+func (arr lazyKeys) Less(i, j int) bool {
+	return arr.keys[i] < arr.keys[j]
+}
+
+func (arr lazyKeys) Swap(i, j int) {
+	arr.keys[i], arr.keys[j] = arr.keys[j], arr.keys[i]
+	arr.vals[i], arr.vals[j] = arr.vals[j], arr.vals[i]
+}
+
+func (n *lazyNode) external(g *LazyGraph) nodes.External {
+	if n.kind == nodes.KindObject {
+		return lazyObject{g: g, n: n}
+	}
+	return lazyArray{g: g, n: n}
+}
+
+func (g *LazyGraph) mustNode(id uint64) nodes.External {
+	n, err := g.Node(id)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+var (
+	_ nodes.ExternalObject = lazyObject{}
+	_ nodes.ExternalArray  = lazyArray{}
+)
+
+type lazyObject struct {
+	g *LazyGraph
+	n *lazyNode
+}
+
+func (lazyObject) Kind() nodes.Kind {
+	return nodes.KindObject
+}
+
+func (lazyObject) Value() nodes.Value {
+	return nil
+}
+
+func (o lazyObject) SameAs(n nodes.External) bool {
+	o2, ok := n.(lazyObject)
+	return ok && o.g == o2.g && o.n.id == o2.n.id
+}
+
+func (o lazyObject) Size() int {
+	return len(o.n.keys)
+}
+
+func (o lazyObject) Keys() []string {
+	return append([]string{}, o.n.keys...)
+}
+
+func (o lazyObject) ValueAt(key string) (nodes.External, bool) {
+	keys := o.n.keys
+	i := sort.SearchStrings(keys, key)
+	if i >= len(keys) || keys[i] != key {
+		return nil, false
+	}
+	return o.g.mustNode(o.n.vals[i]), true
+}
+
+type lazyArray struct {
+	g *LazyGraph
+	n *lazyNode
+}
+
+func (lazyArray) Kind() nodes.Kind {
+	return nodes.KindArray
+}
+
+func (lazyArray) Value() nodes.Value {
+	return nil
+}
+
+func (a lazyArray) SameAs(n nodes.External) bool {
+	a2, ok := n.(lazyArray)
+	return ok && a.g == a2.g && a.n.id == a2.n.id
+}
+
+func (a lazyArray) Size() int {
+	return len(a.n.vals)
+}
+
+func (a lazyArray) ValueAt(i int) nodes.External {
+	return a.g.mustNode(a.n.vals[i])
+}
@@ -38,6 +38,9 @@ const _ = proto.GoGoProtoPackageIsVersion2 // please upgrade the proto package
 // It should be preceded by the magic number "\x00bgr" (4 bytes) and the version number 0x1
 // written as a little-endian 4 byte integer. Next, the length of the header message should be
 // written in varint encoding, directly followed by the message itself.
+//
+// Version 0x2 of the format stores the header and the node messages in separate checksummed sections
+// that can be compressed. See Version2 for details.
 type GraphHeader struct {
 	// LastID is a last node ID used by ID allocator for this graph. Implementation may reserve some
 	// IDs space by setting LastID > max(nodes.ID). If not set, max(nodes.ID) is assumed.
@@ -1,6 +1,7 @@
 package nodesproto
 
 import (
+	"bytes"
 	"crypto/md5"
 	"encoding/binary"
 	"fmt"
@@ -13,10 +14,7 @@ import (
 
 //go:generate protoc --proto_path=$GOPATH/src:. --gogo_out=. nodes.proto
 
-const (
-	magic   = "\x00bgr"
-	version = 0x1
-)
+const magic = "\x00bgr"
 
 const (
 	keysDiff   = false
@@ -37,31 +35,70 @@ var (
 	DupsCnt     int
 )
 
+// WriteTo writes the tree to w as a binary graph.
+//
+// The graph is written in version 1 of the format for compatibility with existing readers.
+// Use WriteWith to enable compression and checksums.
 func WriteTo(w io.Writer, n nodes.Node) error {
-	var header [8]byte
-	copy(header[:4], magic)
-	binary.LittleEndian.PutUint32(header[4:], version)
-	_, err := w.Write(header[:])
+	return WriteWith(w, n, WriteOptions{})
+}
+
+// WriteWithMeta writes the tree to w as a binary graph, together with a metadata node.
+//
+// Metadata is usually an object that describes the tree, like the language, the file name or the driver version.
+// It can be read back with ReadWithMeta.
+func WriteWithMeta(w io.Writer, root, meta nodes.Node) error {
+	return WriteWith(w, root, WriteOptions{Metadata: meta})
+}
+
+// WriteWith writes the tree to w as a binary graph, using specified options.
+func WriteWith(w io.Writer, n nodes.Node, opt WriteOptions) error {
+	vers, flags, err := opt.format()
 	if err != nil {
 		return err
 	}
-
 	tw := newTreeWriter()
 	root := tw.addNode(n)
+	meta := tw.addNode(opt.Metadata)
 
 	gh := &GraphHeader{
-		LastId: uint64(len(tw.nodes) + 1),
-		Root:   root,
+		LastId:   uint64(len(tw.nodes) + 1),
+		Root:     root,
+		Metadata: meta,
 	}
+	if vers == Version1 {
+		if err = writeHeader(w, vers, flags); err != nil {
+			return err
+		}
+		pw := pio.NewWriter(w)
+		if _, err = pw.WriteMsg(gh); err != nil {
+			return err
+		}
+		return writeNodes(pw, tw.nodes)
+	}
+	buf := bytes.NewBuffer(nil)
+	if _, err = pio.NewWriter(buf).WriteMsg(gh); err != nil {
+		return err
+	}
+	hdr := buf.Bytes()
 
-	pw := pio.NewWriter(w)
-
-	_, err = pw.WriteMsg(gh)
-	if err != nil {
+	buf = bytes.NewBuffer(nil)
+	if err = writeNodes(pio.NewWriter(buf), tw.nodes); err != nil {
+		return err
+	}
+	if err = writeHeader(w, vers, flags); err != nil {
 		return err
 	}
+	if err = writeSection(w, hdr, flags); err != nil {
+		return err
+	}
+	return writeSection(w, buf.Bytes(), flags)
+}
+
+// writeNodes writes a list of node messages. IDs of the nodes are omitted when possible.
+func writeNodes(pw pio.Writer, list []*Node) error {
 	var lastID uint64
-	for _, n := range tw.nodes {
+	for _, n := range list {
 		id := n.Id
 		if id == lastID+1 {
 			n.Id = 0 // omit the field
@@ -85,8 +122,7 @@ func WriteTo(w io.Writer, n nodes.Node) error {
 			DelimSize += sovNodes(uint64(sz))
 		}
 
-		_, err = pw.WriteMsg(n)
-		if err != nil {
+		if _, err := pw.WriteMsg(n); err != nil {
 			return err
 		}
 	}
@@ -298,6 +334,41 @@ func ReadTree(r io.Reader) (nodes.Node, error) {
 	return g.asTree()
 }
 
+// ReadWithMeta reads a binary graph from r and decodes both the tree and the metadata node.
+// The metadata is nil if it was not written to the graph. See WriteWithMeta.
+// If the graph is cyclic, an error is returned.
+func ReadWithMeta(r io.Reader) (root, meta nodes.Node, _ error) {
+	g := newGraphReader()
+	if err := g.readGraph(r); err != nil {
+		return nil, nil, err
+	}
+	root, err := g.asTree()
+	if err != nil {
+		return nil, nil, err
+	}
+	if g.meta != 0 {
+		meta, err = g.asNode(g.meta, make(map[uint64]bool))
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot decode metadata: %v", err)
+		}
+	}
+	return root, meta, nil
+}
+
+// ReadDAG reads a binary graph from r and decodes it as a directed acyclic graph.
+//
+// In contrast to ReadTree, nodes that are referenced multiple times in the graph (for example,
+// deduplicated by WriteTo) are decoded only once and are shared in the resulting tree.
+// Thus, the returned tree must be treated as immutable.
+// If the graph is cyclic, an error is returned.
+func ReadDAG(r io.Reader) (nodes.Node, error) {
+	g := newGraphReader()
+	if err := g.readGraph(r); err != nil {
+		return nil, err
+	}
+	return g.asDAG()
+}
+
 type RawNode struct {
 	ID     uint64      `json:"id"`
 	Kind   nodes.Kind  `json:"kind"`
@@ -314,16 +385,50 @@ type RawGraph struct {
 }
 
 // ReadRaw reads a graph from a binary stream, returning a flat list of all nodes.
+//
+// It stops at the first problem in the stream. Use ReadRawLenient to collect all the problems instead.
 func ReadRaw(r io.Reader) (*RawGraph, error) {
 	g := newGraphReader()
 	if err := g.readGraph(r); err != nil {
 		return nil, err
 	}
+	return g.asRaw()
+}
+
+// ReadRawLenient is like ReadRaw, but it doesn't stop at problems in the stream, such as duplicate or
+// non-ascending node IDs, invalid KeysFrom references, invalid values, checksum mismatches or truncated data.
+// Instead, all problems are collected and returned together with all the nodes that could be read.
+//
+// The error is returned only if the graph cannot be read at all, for example if the header is invalid.
+// The graph returned by this function can be checked further with RawGraph.Validate.
+func ReadRawLenient(r io.Reader) (*RawGraph, []error, error) {
+	g := newGraphReader()
+	g.lenient = true
+	if err := g.readGraph(r); err != nil {
+		return nil, g.problems, err
+	}
+	rg, err := g.asRaw()
+	if err != nil {
+		return nil, g.problems, err
+	}
+	return rg, g.problems, nil
+}
+
+// asRaw converts the graph to a RawGraph.
+func (g *graphReader) asRaw() (*RawGraph, error) {
+	ids := make([]uint64, 0, len(g.nodes))
+	for id := range g.nodes {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
 	rg := &RawGraph{
 		Root: g.root, Meta: g.meta, Last: g.last,
 		Nodes: make(map[uint64]RawNode, len(g.nodes)),
 	}
-	for id, n := range g.nodes {
+	for _, id := range ids {
+		n := g.nodes[id]
 		nd := RawNode{ID: id, Kind: n.Kind()}
 		switch nd.Kind {
 		case nodes.KindObject:
@@ -334,7 +439,9 @@ func ReadRaw(r io.Reader) (*RawGraph, error) {
 		default:
 			v, err := asValue(n)
 			if err != nil {
-				return rg, err
+				if err = g.problem(fmt.Errorf("node %d: %v", id, err)); err != nil {
+					return nil, err
+				}
 			}
 			nd.Value = v
 		}
@@ -353,36 +460,55 @@ type graphReader struct {
 	root     uint64
 	meta     uint64
 	last     uint64
+
+	lenient  bool    // collect problems instead of failing; see ReadRawLenient
+	problems []error // problems found in lenient mode
 }
 
-func (g *graphReader) readHeader(r io.Reader) error {
-	var b [8]byte
-	n, err := r.Read(b[:])
-	if err == io.EOF {
-		return io.ErrUnexpectedEOF
-	} else if err != nil {
+// problem records a problem in the graph. In lenient mode the problem is stored and the function returns nil,
+// otherwise the problem is returned as-is.
+func (g *graphReader) problem(err error) error {
+	if !g.lenient {
 		return err
-	} else if n != len(b) {
-		return fmt.Errorf("short read")
-	}
-	if string(b[:4]) != magic {
-		return fmt.Errorf("not a graph file")
-	}
-	vers := binary.LittleEndian.Uint32(b[4:])
-	if vers != version {
-		return fmt.Errorf("unsupported version: %x", vers)
 	}
+	g.problems = append(g.problems, err)
 	return nil
 }
+
 func (g *graphReader) readGraph(r io.Reader) error {
-	if err := g.readHeader(r); err != nil {
+	vers, flags, err := readHeader(r)
+	if err != nil {
 		return err
 	}
-	pr := pio.NewReader(r, 10*1024*1024)
-	var gh GraphHeader
-	if err := pr.ReadMsg(&gh); err != nil {
+	if vers == Version1 {
+		pr := pio.NewReader(r, 10*1024*1024)
+		return g.readMessages(pr, pr)
+	}
+	hdr, err := readSection(r, flags, "header")
+	if err != nil {
 		return err
 	}
+	data, err := readSection(r, flags, "nodes")
+	if err != nil {
+		if err = g.problem(err); err != nil {
+			return err
+		}
+		data = nil
+	}
+	return g.readMessages(
+		pio.NewReader(bytes.NewReader(hdr), len(hdr)),
+		pio.NewReader(bytes.NewReader(data), 10*1024*1024),
+	)
+}
+
+// readMessages reads the graph header message from hr, and all the node messages from pr.
+func (g *graphReader) readMessages(hr, pr pio.Reader) error {
+	var gh GraphHeader
+	if err := hr.ReadMsg(&gh); err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("truncated graph header")
+	} else if err != nil {
+		return fmt.Errorf("cannot read graph header: %v", err)
+	}
 	g.last, g.root, g.meta = gh.LastId, gh.Root, gh.Metadata
 	var (
 		prevID uint64
@@ -393,28 +519,50 @@ func (g *graphReader) readGraph(r io.Reader) error {
 		if err := pr.ReadMsg(nd); err == io.EOF {
 			break
 		} else if err != nil {
-			return err
+			if err == io.ErrUnexpectedEOF {
+				err = fmt.Errorf("truncated node after id %d", prevID)
+			} else {
+				err = fmt.Errorf("cannot read node after id %d: %v", prevID, err)
+			}
+			// the rest of the stream cannot be read
+			if err = g.problem(err); err != nil {
+				return err
+			}
+			break
 		}
 
 		if nd.Id == 0 {
 			// allow to omit ID
 			nd.Id = prevID + 1
-		} else if prevID >= nd.Id {
-			// but IDs should be ascending
-			return fmt.Errorf("node IDs should be ascending")
 		}
-		prevID = nd.Id
 		// there should be no duplicates
 		if _, ok := nodes[nd.Id]; ok {
-			return fmt.Errorf("duplicate node with id %d", nd.Id)
+			if err := g.problem(fmt.Errorf("duplicate node with id %d", nd.Id)); err != nil {
+				return err
+			}
+			// keep the first node
+			prevID = nd.Id
+			continue
+		}
+		if prevID >= nd.Id {
+			// IDs should be ascending
+			err := fmt.Errorf("node IDs should be ascending: %d after %d", nd.Id, prevID)
+			if err = g.problem(err); err != nil {
+				return err
+			}
 		}
+		prevID = nd.Id
 		// support KeysFrom
 		if nd.KeysFrom != 0 {
 			n2, ok := nodes[nd.KeysFrom]
 			if !ok {
-				return fmt.Errorf("KeysFrom refers to an undefined node %d", nd.KeysFrom)
+				err := fmt.Errorf("node %d: KeysFrom refers to an undefined node %d", nd.Id, nd.KeysFrom)
+				if err = g.problem(err); err != nil {
+					return err
+				}
+			} else {
+				nd.Keys = n2.Keys
 			}
-			nd.Keys = n2.Keys
 		} else if keysDiff && len(nd.Keys) > 1 {
 			cur := nd.Keys[0]
 			for i := 1; i < len(nd.Keys); i++ {
@@ -447,7 +595,8 @@ func (g *graphReader) readGraph(r io.Reader) error {
 		for _, id := range n.Values {
 			use(id)
 		}
-		if _, ok := refs[n.Id]; !ok {
+		if _, ok := refs[n.Id]; !ok && n.Id != g.meta {
+			// metadata is not a part of the tree
 			roots[n.Id] = struct{}{}
 		}
 	}
@@ -468,6 +617,7 @@ func (g *graphReader) readGraph(r io.Reader) error {
 			g.last++
 			id := g.last
 			g.nodes[id] = &Node{Id: id, Values: g.detached}
+			g.root = id
 		}
 	}
 	return nil
@@ -481,6 +631,86 @@ func (g *graphReader) asTree() (nodes.Node, error) {
 	return g.asNode(g.root, seen)
 }
 
+func (g *graphReader) asDAG() (nodes.Node, error) {
+	if g.root == 0 {
+		return nil, nil
+	}
+	d := &dagReader{
+		g:       g,
+		decoded: make(map[uint64]nodes.Node, len(g.nodes)),
+		active:  make(map[uint64]struct{}),
+	}
+	return d.asNode(g.root)
+}
+
+type dagReader struct {
+	g       *graphReader
+	decoded map[uint64]nodes.Node
+	active  map[uint64]struct{} // nodes that are currently being decoded
+}
+
+func (d *dagReader) asNode(id uint64) (nodes.Node, error) {
+	if id == 0 {
+		return nil, nil
+	}
+	if nd, ok := d.decoded[id]; ok {
+		return nd, nil
+	}
+	n, ok := d.g.nodes[id]
+	if !ok {
+		return nil, fmt.Errorf("node %v is not defined", id)
+	}
+	if n.Value != nil {
+		v, err := asValue(n)
+		if err != nil {
+			return nil, err
+		}
+		d.decoded[id] = v
+		return v, nil
+	}
+	if _, ok := d.active[id]; ok {
+		return nil, fmt.Errorf("not a DAG: node %d is a part of a cycle", id)
+	}
+	d.active[id] = struct{}{}
+	defer delete(d.active, id)
+
+	var out nodes.Node
+	if n.Kind() == nodes.KindObject {
+		if len(n.Keys) != len(n.Values) {
+			return nil, fmt.Errorf("number of keys doesn't match a number of values: %d vs %d", len(n.Keys), len(n.Values))
+		}
+		m := make(nodes.Object, len(n.Keys))
+		for i, k := range n.Keys {
+			nk, err := d.asNode(k)
+			if err != nil {
+				return nil, err
+			}
+			sk, ok := nk.(nodes.String)
+			if !ok {
+				return nil, fmt.Errorf("only string keys are supported")
+			}
+			nv, err := d.asNode(n.Values[i])
+			if err != nil {
+				return nil, err
+			}
+			m[string(sk)] = nv
+		}
+		out = m
+	} else {
+		m := make(nodes.Array, 0, len(n.Values))
+		for _, v := range n.Values {
+			nv, err := d.asNode(v)
+			if err != nil {
+				return nil, err
+			}
+			m = append(m, nv)
+		}
+		out = m
+	}
+	d.decoded[id] = out
+	return out, nil
+}
+
 func (m *Node) Kind() nodes.Kind {
 	if m.Value != nil {
 		v, _ := asValue(m)
@@ -2,10 +2,15 @@ package nodesproto
 
 import (
 	"bytes"
+	"compress/gzip"
 	"encoding/json"
+	"strconv"
 	"testing"
 
 	"github.com/bblfsh/sdk/v3/uast/nodes"
+	"github.com/bblfsh/sdk/v3/uast/nodes/nodesproto/pio"
+	"github.com/bblfsh/sdk/v3/uast/query"
+	"github.com/bblfsh/sdk/v3/uast/query/xpath"
 	"github.com/stretchr/testify/require"
 )
 
@@ -171,3 +176,495 @@ func TestTree(t *testing.T) {
 		})
 	}
 }
+
+func TestDAG(t *testing.T) {
+	for _, c := range treeCases {
+		t.Run(c.name, func(t *testing.T) {
+			exp := c.out
+			if exp == nil {
+				exp = c.in.Clone()
+			}
+			buf := bytes.NewBuffer(nil)
+			err := WriteTo(buf, c.in)
+			require.NoError(t, err)
+
+			out, err := ReadDAG(bytes.NewReader(buf.Bytes()))
+			require.NoError(t, err)
+			require.True(t, nodes.Equal(exp, out))
+		})
+	}
+	dup := nodes.Object{
+		"@type": nodes.String("node"),
+		"k":     nodes.Array{nodes.String("n1"), nodes.String("n2")},
+	}
+	buf := bytes.NewBuffer(nil)
+	err := WriteTo(buf, nodes.Array{dup, dup.Clone()})
+	require.NoError(t, err)
+
+	out, err := ReadDAG(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	arr := out.(nodes.Array)
+	require.True(t, nodes.Same(arr[0], arr[1]), "deduplicated nodes should be shared")
+}
+
+func TestLazy(t *testing.T) {
+	for _, c := range treeCases {
+		t.Run(c.name, func(t *testing.T) {
+			exp := c.out
+			if exp == nil {
+				exp = c.in.Clone()
+			}
+			buf := bytes.NewBuffer(nil)
+			err := WriteTo(buf, c.in)
+			require.NoError(t, err)
+
+			g, err := OpenLazy(buf.Bytes())
+			require.NoError(t, err)
+			require.True(t, nodes.Equal(exp, g.Root()))
+		})
+	}
+}
+
+func TestLazyNoRoot(t *testing.T) {
+	str := func(id uint64, s string) *Node {
+		return &Node{Id: id, Value: &Node_String_{String_: s}}
+	}
+	cases := []struct {
+		name string
+		list []*Node
+		exp  nodes.Node
+	}{
+		{
+			name: "single",
+			list: []*Node{
+				str(1, "k"), str(2, "v"),
+				{Id: 3, Keys: []uint64{1}, Values: []uint64{2}},
+			},
+			exp: nodes.Object{"k": nodes.String("v")},
+		},
+		{
+			name: "multiple",
+			list: []*Node{
+				str(1, "a"), str(2, "b"),
+			},
+			exp: nodes.Array{nodes.String("a"), nodes.String("b")},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			data := writeRawGraph(t, &GraphHeader{LastId: uint64(len(c.list))}, c.list)
+
+			tree, err := ReadTree(bytes.NewReader(data))
+			require.NoError(t, err)
+			require.Equal(t, c.exp, tree)
+
+			g, err := OpenLazy(data)
+			require.NoError(t, err)
+			require.True(t, nodes.Equal(c.exp, g.Root()))
+		})
+	}
+}
+
+func TestLazyQuery(t *testing.T) {
+	ident := func(name string) nodes.Object {
+		return nodes.Object{
+			"@type": nodes.String("uast:Identifier"),
+			"Name":  nodes.String(name),
+		}
+	}
+	root := nodes.Object{
+		"@type": nodes.String("File"),
+		"Body":  nodes.Array{ident("a"), ident("b"), ident("a")},
+	}
+	buf := bytes.NewBuffer(nil)
+	err := WriteTo(buf, root)
+	require.NoError(t, err)
+
+	g, err := OpenLazy(buf.Bytes())
+	require.NoError(t, err)
+
+	it, err := xpath.New().Execute(g.Root(), "//uast:Identifier[@Name='a']")
+	require.NoError(t, err)
+	list := query.AllNodes(it)
+	require.Len(t, list, 2)
+	require.True(t, nodes.Equal(ident("a"), list[0]))
+	// identical subtrees are stored once and are shared
+	require.True(t, nodes.Same(list[0], list[1]))
+
+	_, err = g.Node(uint64(g.Len()) + 1)
+	require.Error(t, err)
+}
+
+func TestLazyQueryPartial(t *testing.T) {
+	ident := func(name string) nodes.Object {
+		return nodes.Object{
+			"@type": nodes.String("uast:Identifier"),
+			"Name":  nodes.String(name),
+		}
+	}
+	var deep nodes.Array
+	for i := 0; i < 10; i++ {
+		deep = append(deep, ident(strconv.Itoa(i)))
+	}
+	root := nodes.Object{
+		"@type": nodes.String("File"),
+		"Body":  nodes.Array{ident("a"), ident("b")},
+		"Other": nodes.Object{"Deep": deep},
+	}
+	buf := bytes.NewBuffer(nil)
+	err := WriteTo(buf, root)
+	require.NoError(t, err)
+
+	g, err := OpenLazy(buf.Bytes())
+	require.NoError(t, err)
+
+	decoded := func() int {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		return len(g.cache)
+	}
+
+	it, err := xpath.New().Execute(g.Root(), "/File/Body/uast:Identifier[@Name='b']")
+	require.NoError(t, err)
+	list := query.AllNodes(it)
+	require.Len(t, list, 1)
+	require.True(t, nodes.Equal(ident("b"), list[0]))
+
+	// root, Body, both identifiers and Other; children of Other are not visited
+	require.Equal(t, 5, decoded())
+
+	require.True(t, nodes.Equal(root, g.Root()))
+	require.Equal(t, nodes.Count(root, nodes.KindsComposite), decoded())
+}
+
+var writeOptions = []struct {
+	name string
+	opt  WriteOptions
+}{
+	{name: "v1", opt: WriteOptions{Version: Version1}},
+	{name: "v2", opt: WriteOptions{Version: Version2}},
+	{name: "v2 gzip", opt: WriteOptions{Compress: true}},
+}
+
+func TestWriteOptions(t *testing.T) {
+	for _, o := range writeOptions {
+		t.Run(o.name, func(t *testing.T) {
+			for _, c := range treeCases {
+				t.Run(c.name, func(t *testing.T) {
+					exp := c.out
+					if exp == nil {
+						exp = c.in.Clone()
+					}
+					buf := bytes.NewBuffer(nil)
+					err := WriteWith(buf, c.in, o.opt)
+					require.NoError(t, err)
+
+					out, err := ReadTree(bytes.NewReader(buf.Bytes()))
+					require.NoError(t, err)
+					require.True(t, nodes.Equal(exp, out))
+
+					g, err := OpenLazy(buf.Bytes())
+					require.NoError(t, err)
+					require.True(t, nodes.Equal(exp, g.Root()))
+				})
+			}
+		})
+	}
+
+	err := WriteWith(bytes.NewBuffer(nil), nil, WriteOptions{Version: Version1, Compress: true})
+	require.Error(t, err)
+	err = WriteWith(bytes.NewBuffer(nil), nil, WriteOptions{Version: 3})
+	require.Error(t, err)
+}
+
+func TestWriteDefaultVersion(t *testing.T) {
+	in := treeCases[len(treeCases)-1].in
+
+	v1 := bytes.NewBuffer(nil)
+	err := WriteTo(v1, in)
+	require.NoError(t, err)
+
+	v1opt := bytes.NewBuffer(nil)
+	err = WriteWith(v1opt, in, WriteOptions{})
+	require.NoError(t, err)
+	require.Equal(t, v1.Bytes(), v1opt.Bytes())
+}
+
+func TestReadCorrupted(t *testing.T) {
+	in := treeCases[len(treeCases)-1].in
+
+	var cases = []struct {
+		name    string
+		opt     WriteOptions
+		corrupt func(data []byte) []byte
+		err     string
+	}{
+		{
+			name:    "bad magic",
+			corrupt: func(data []byte) []byte { data[0] = 'x'; return data },
+			err:     "not a graph file",
+		},
+		{
+			name:    "bad version",
+			corrupt: func(data []byte) []byte { data[4] = 9; return data },
+			err:     "unsupported version: 9",
+		},
+		{
+			name:    "truncated header",
+			corrupt: func(data []byte) []byte { return data[:6] },
+			err:     "truncated graph header",
+		},
+		{
+			name:    "v1 truncated node",
+			corrupt: func(data []byte) []byte { return data[:len(data)-1] },
+			err:     "truncated node",
+		},
+		{
+			name:    "v2 bad flags",
+			opt:     WriteOptions{Version: Version2},
+			corrupt: func(data []byte) []byte { data[9] = 1; return data },
+			err:     "unsupported flags: 100",
+		},
+		{
+			name:    "v2 truncated",
+			opt:     WriteOptions{Version: Version2},
+			corrupt: func(data []byte) []byte { return data[:len(data)-1] },
+			err:     "truncated nodes section",
+		},
+		{
+			name:    "v2 checksum",
+			opt:     WriteOptions{Version: Version2},
+			corrupt: func(data []byte) []byte { data[len(data)-1]++; return data },
+			err:     "checksum mismatch in nodes section",
+		},
+		{
+			name:    "v2 gzip checksum",
+			opt:     WriteOptions{Compress: true},
+			corrupt: func(data []byte) []byte { data[len(data)-5]++; return data },
+			err:     "checksum mismatch in nodes section",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			buf := bytes.NewBuffer(nil)
+			err := WriteWith(buf, in, c.opt)
+			require.NoError(t, err)
+			data := c.corrupt(buf.Bytes())
+
+			_, err = ReadTree(bytes.NewReader(data))
+			require.Error(t, err)
+			require.Contains(t, err.Error(), c.err)
+
+			_, err = OpenLazy(data)
+			require.Error(t, err)
+			if c.name != "v1 truncated node" {
+				// lazy reader reports truncated messages differently
+				require.Contains(t, err.Error(), c.err)
+			}
+		})
+	}
+}
+
+func TestDecompressLimit(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	zw := gzip.NewWriter(buf)
+	_, err := zw.Write(make([]byte, 1025))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	data, err := decompress(buf.Bytes(), 1025, "nodes")
+	require.NoError(t, err)
+	require.Len(t, data, 1025)
+
+	_, err = decompress(buf.Bytes(), 1024, "nodes")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "nodes section is too large")
+}
+
+func TestMeta(t *testing.T) {
+	root := treeCases[len(treeCases)-1].in
+	meta := nodes.Object{
+		"language": nodes.String("go"),
+		"filename": nodes.String("main.go"),
+		"driver":   nodes.String("v2.1.0"),
+		"errors":   nodes.Array{},
+	}
+	for _, o := range writeOptions {
+		t.Run(o.name, func(t *testing.T) {
+			opt := o.opt
+			opt.Metadata = meta
+			buf := bytes.NewBuffer(nil)
+			err := WriteWith(buf, root, opt)
+			require.NoError(t, err)
+
+			out, m, err := ReadWithMeta(bytes.NewReader(buf.Bytes()))
+			require.NoError(t, err)
+			require.True(t, nodes.Equal(root, out))
+			require.True(t, nodes.Equal(meta, m))
+
+			// metadata is not visible to readers that expect a tree
+			out, err = ReadTree(bytes.NewReader(buf.Bytes()))
+			require.NoError(t, err)
+			require.True(t, nodes.Equal(root, out))
+
+			g, err := OpenLazy(buf.Bytes())
+			require.NoError(t, err)
+			require.True(t, nodes.Equal(root, g.Root()))
+			require.True(t, nodes.Equal(meta, g.Meta()))
+		})
+	}
+
+	buf := bytes.NewBuffer(nil)
+	err := WriteWithMeta(buf, nil, meta)
+	require.NoError(t, err)
+
+	out, m, err := ReadWithMeta(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.Nil(t, out)
+	require.True(t, nodes.Equal(meta, m))
+
+	g, err := OpenLazy(buf.Bytes())
+	require.NoError(t, err)
+	require.Nil(t, g.Root())
+
+	buf.Reset()
+	err = WriteTo(buf, root)
+	require.NoError(t, err)
+
+	_, m, err = ReadWithMeta(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.Nil(t, m)
+}
+
+func TestValidate(t *testing.T) {
+	for _, c := range treeCases {
+		t.Run(c.name, func(t *testing.T) {
+			buf := bytes.NewBuffer(nil)
+			err := WriteTo(buf, c.in)
+			require.NoError(t, err)
+
+			raw, err := ReadRaw(bytes.NewReader(buf.Bytes()))
+			require.NoError(t, err)
+			require.Empty(t, raw.Validate())
+		})
+	}
+
+	str := func(id uint64, s string) RawNode {
+		return RawNode{ID: id, Kind: nodes.KindString, Value: nodes.String(s)}
+	}
+	g := &RawGraph{
+		Root: 1, Meta: 9, Last: 6,
+		Nodes: map[uint64]RawNode{
+			1: {ID: 1, Kind: nodes.KindObject, Keys: []uint64{2, 3, 4}, Values: []uint64{5, 6, 0}},
+			2: str(2, "a"),
+			3: str(3, "a"),
+			4: {ID: 4, Kind: nodes.KindInt, Value: nodes.Int(1)},
+			5: {ID: 5, Kind: nodes.KindArray, Values: []uint64{1, 8}},
+			6: {ID: 6, Kind: nodes.KindObject, Keys: []uint64{7}},
+			7: str(7, "b"),
+		},
+	}
+	var errs []string
+	for _, err := range g.Validate() {
+		errs = append(errs, err.Error())
+	}
+	require.Equal(t, []string{
+		"metadata refers to an undefined node 9",
+		`node 1: duplicate key "a"`,
+		"node 1: key 4 is not a string: Int",
+		"node 5: value refers to an undefined node 8",
+		"node 6: number of keys doesn't match a number of values: 1 vs 0",
+		"node 7: id is larger than the last id 6",
+		"not a DAG: node 1 is a part of a cycle",
+	}, errs)
+}
+
+func TestReadHeader(t *testing.T) {
+	for _, o := range writeOptions {
+		t.Run(o.name, func(t *testing.T) {
+			buf := bytes.NewBuffer(nil)
+			err := WriteWith(buf, nodes.String("a"), o.opt)
+			require.NoError(t, err)
+
+			require.True(t, IsGraph(buf.Bytes()))
+			h, err := ReadHeader(buf)
+			require.NoError(t, err)
+			exp := o.opt.Version
+			if exp == 0 {
+				exp = Version2
+			}
+			require.Equal(t, &Header{Version: exp, Compressed: o.opt.Compress}, h)
+		})
+	}
+	require.False(t, IsGraph([]byte("\x00bg")))
+	require.False(t, IsGraph([]byte("a: b")))
+}
+
+// writeRawGraph writes a graph in version 1 of the format without any checks.
+func writeRawGraph(t testing.TB, gh *GraphHeader, list []*Node) []byte {
+	buf := bytes.NewBuffer(nil)
+	err := writeHeader(buf, Version1, 0)
+	require.NoError(t, err)
+	pw := pio.NewWriter(buf)
+	_, err = pw.WriteMsg(gh)
+	require.NoError(t, err)
+	for _, n := range list {
+		_, err = pw.WriteMsg(n)
+		require.NoError(t, err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadRawLenient(t *testing.T) {
+	str := func(id uint64, s string) *Node {
+		return &Node{Id: id, Value: &Node_String_{String_: s}}
+	}
+	data := writeRawGraph(t, &GraphHeader{Root: 1, LastId: 6}, []*Node{
+		{Id: 1, Keys: []uint64{2}, Values: []uint64{3}},
+		str(2, "a"),
+		str(2, "b"),
+		str(3, "c"),
+		{Id: 5, KeysFrom: 9, IsObject: true},
+		str(4, "d"),
+	})
+	// truncated node
+	data = append(data, 10, 1, 2)
+
+	_, err := ReadRaw(bytes.NewReader(data))
+	require.Error(t, err)
+
+	g, problems, err := ReadRawLenient(bytes.NewReader(data))
+	require.NoError(t, err)
+	var errs []string
+	for _, err := range problems {
+		errs = append(errs, err.Error())
+	}
+	require.Equal(t, []string{
+		"duplicate node with id 2",
+		"node 5: KeysFrom refers to an undefined node 9",
+		"node IDs should be ascending: 4 after 5",
+		"truncated node after id 4",
+	}, errs)
+	require.Equal(t, uint64(1), g.Root)
+	require.Len(t, g.Nodes, 5)
+	require.Equal(t, nodes.String("a"), g.Nodes[2].Value, "the first node should be used")
+	require.Empty(t, g.Validate())
+
+	// the graph header is required
+	_, _, err = ReadRawLenient(bytes.NewReader(data[:6]))
+	require.Error(t, err)
+
+	// the nodes section cannot be read, but the header is still available
+	buf := bytes.NewBuffer(nil)
+	err = WriteWith(buf, treeCases[0].in, WriteOptions{Version: Version2})
+	require.NoError(t, err)
+	data = buf.Bytes()
+	data[len(data)-1]++
+
+	g, problems, err = ReadRawLenient(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Len(t, problems, 1)
+	require.Contains(t, problems[0].Error(), "checksum mismatch in nodes section")
+	require.Empty(t, g.Nodes)
+	require.NotEmpty(t, g.Validate(), "the root node is missing")
+}
@@ -138,7 +138,10 @@ func (r *varintReader) ReadMsg(msg proto.Message) error {
 		r.buf = make([]byte, r.len)
 	}
 	buf := r.buf[:r.len]
-	if _, err := io.ReadFull(r.r, buf); err != nil {
+	if _, err := io.ReadFull(r.r, buf); err == io.EOF {
+		// the length was already read, thus the message is truncated
+		return io.ErrUnexpectedEOF
+	} else if err != nil {
 		return err
 	}
 	return proto.Unmarshal(buf, msg)
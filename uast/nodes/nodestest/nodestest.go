@@ -0,0 +1,40 @@
+// Package nodestest provides helpers for testing and benchmarking code that works with UAST nodes.
+package nodestest
+
+import (
+	"fmt"
+
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+)
+
+// GenTree generates a tree similar to UAST with a given depth and number of children per node.
+// Each node has a unique name, thus subtrees cannot be deduplicated.
+func GenTree(depth, fanout int) nodes.Node {
+	last := 0
+	var gen func(d, i int) nodes.Node
+	gen = func(d, i int) nodes.Node {
+		last++
+		obj := nodes.Object{
+			"@type": nodes.String(fmt.Sprintf("type_%d", i%10)),
+			"@pos": nodes.Object{
+				"@type": nodes.String("uast:Positions"),
+				"start": nodes.Object{
+					"@type":  nodes.String("uast:Position"),
+					"offset": nodes.Uint(d * i),
+					"line":   nodes.Uint(d),
+					"col":    nodes.Uint(i),
+				},
+			},
+			"Name": nodes.String(fmt.Sprintf("name_%d", last)),
+		}
+		if d > 0 {
+			arr := make(nodes.Array, 0, fanout)
+			for j := 0; j < fanout; j++ {
+				arr = append(arr, gen(d-1, j))
+			}
+			obj["Body"] = arr
+		}
+		return obj
+	}
+	return gen(depth, 0)
+}
@@ -0,0 +1,298 @@
+package nodes
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Path is a location of a node in the tree, similar to JSON Pointer.
+//
+// Each element of the path is either a string (object key) or an int (array index).
+// An empty path refers to the root node.
+type Path []interface{}
+
+// ParsePath parses a JSON Pointer representation of the path. See Path.String.
+//
+// An empty string refers to the root node, while "/" refers to an empty object key, as defined in RFC 6901.
+//
+// Since the pointer syntax does not distinguish between object keys and array indexes,
+// all numeric elements are decoded as array indexes. Lookups on objects will convert
+// them back to string keys.
+func ParsePath(s string) (Path, error) {
+	if s == "" {
+		return Path{}, nil
+	} else if s[0] != '/' {
+		return nil, fmt.Errorf("path should start with '/': %q", s)
+	}
+	parts := strings.Split(s[1:], "/")
+	p := make(Path, 0, len(parts))
+	for _, e := range parts {
+		if i, err := strconv.Atoi(e); err == nil && i >= 0 && strconv.Itoa(i) == e {
+			p = append(p, i)
+			continue
+		}
+		e = strings.Replace(e, "~1", "/", -1)
+		e = strings.Replace(e, "~0", "~", -1)
+		p = append(p, e)
+	}
+	return p, nil
+}
+
+// String returns a JSON Pointer representation of the path (RFC 6901). The root node is represented by an empty string.
+func (p Path) String() string {
+	buf := &strings.Builder{}
+	for _, e := range p {
+		buf.WriteByte('/')
+		switch e := e.(type) {
+		case string:
+			e = strings.Replace(e, "~", "~0", -1)
+			e = strings.Replace(e, "/", "~1", -1)
+			buf.WriteString(e)
+		case int:
+			buf.WriteString(strconv.Itoa(e))
+		default:
+			fmt.Fprint(buf, e)
+		}
+	}
+	return buf.String()
+}
+
+// Join returns a copy of the path with additional elements appended to it.
+func (p Path) Join(elems ...interface{}) Path {
+	p2 := make(Path, len(p), len(p)+len(elems))
+	copy(p2, p)
+	return append(p2, elems...)
+}
+
+// Parent returns a path of the parent node. It returns an empty path for the root node.
+func (p Path) Parent() Path {
+	if len(p) == 0 {
+		return p
+	}
+	return p[:len(p)-1]
+}
+
+// Equal checks if two paths point to the same location.
+func (p Path) Equal(p2 Path) bool {
+	if len(p) != len(p2) {
+		return false
+	}
+	for i := range p {
+		if p[i] != p2[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// pathKey returns an object key for the path element.
+func pathKey(e interface{}) (string, error) {
+	switch e := e.(type) {
+	case string:
+		return e, nil
+	case int:
+		return strconv.Itoa(e), nil
+	}
+	return "", fmt.Errorf("unsupported path element: %T", e)
+}
+
+// pathIndex returns an array index for the path element.
+func pathIndex(e interface{}) (int, error) {
+	switch e := e.(type) {
+	case int:
+		return e, nil
+	case string:
+		if i, err := strconv.Atoi(e); err == nil {
+			return i, nil
+		}
+		return 0, fmt.Errorf("expected array index, got %q", e)
+	}
+	return 0, fmt.Errorf("unsupported path element: %T", e)
+}
+
+// GetPath returns a node at a given path relative to the root. It returns false if the path does not exist.
+func GetPath(root External, p Path) (External, bool) {
+	n := root
+	for _, e := range p {
+		switch KindOf(n) {
+		case KindObject:
+			obj, ok := n.(ExternalObject)
+			if !ok {
+				return nil, false
+			}
+			k, err := pathKey(e)
+			if err != nil {
+				return nil, false
+			}
+			n, ok = obj.ValueAt(k)
+			if !ok {
+				return nil, false
+			}
+		case KindArray:
+			arr, ok := n.(ExternalArray)
+			if !ok {
+				return nil, false
+			}
+			i, err := pathIndex(e)
+			if err != nil || i < 0 || i >= arr.Size() {
+				return nil, false
+			}
+			n = arr.ValueAt(i)
+		default:
+			return nil, false
+		}
+	}
+	return n, true
+}
+
+// GetPath returns a node at a given path relative to the object. It returns false if the path does not exist.
+func (m Object) GetPath(p Path) (Node, bool) {
+	return getPath(m, p)
+}
+
+// GetPath returns a node at a given path relative to the array. It returns false if the path does not exist.
+func (m Array) GetPath(p Path) (Node, bool) {
+	return getPath(m, p)
+}
+
+func getPath(root Node, p Path) (Node, bool) {
+	n := root
+	for _, e := range p {
+		switch m := n.(type) {
+		case Object:
+			k, err := pathKey(e)
+			if err != nil {
+				return nil, false
+			}
+			v, ok := m[k]
+			if !ok {
+				return nil, false
+			}
+			n = v
+		case Array:
+			i, err := pathIndex(e)
+			if err != nil || i < 0 || i >= len(m) {
+				return nil, false
+			}
+			n = m[i]
+		default:
+			return nil, false
+		}
+	}
+	return n, true
+}
+
+// SetPath returns a copy of the object with the node at a given path replaced by v.
+// If the last element of the path refers to a missing object field, it will be created.
+//
+// The object itself is not modified; only the nodes along the path are copied.
+func (m Object) SetPath(p Path, v Node) (Object, error) {
+	if len(p) == 0 {
+		return nil, fmt.Errorf("cannot replace the root node")
+	}
+	n, err := setPath(m, p, v, false)
+	if err != nil {
+		return nil, err
+	}
+	return n.(Object), nil
+}
+
+// SetPath returns a copy of the array with the node at a given path replaced by v.
+// If the last element of the path refers to a missing object field, it will be created.
+//
+// The array itself is not modified; only the nodes along the path are copied.
+func (m Array) SetPath(p Path, v Node) (Array, error) {
+	if len(p) == 0 {
+		return nil, fmt.Errorf("cannot replace the root node")
+	}
+	n, err := setPath(m, p, v, false)
+	if err != nil {
+		return nil, err
+	}
+	return n.(Array), nil
+}
+
+// DeletePath returns a copy of the object with the node at a given path removed.
+// Removing an array element shifts all the following elements to the left.
+//
+// The object itself is not modified; only the nodes along the path are copied.
+func (m Object) DeletePath(p Path) (Object, error) {
+	if len(p) == 0 {
+		return nil, fmt.Errorf("cannot delete the root node")
+	}
+	n, err := setPath(m, p, nil, true)
+	if err != nil {
+		return nil, err
+	}
+	return n.(Object), nil
+}
+
+// DeletePath returns a copy of the array with the node at a given path removed.
+// Removing an array element shifts all the following elements to the left.
+//
+// The array itself is not modified; only the nodes along the path are copied.
+func (m Array) DeletePath(p Path) (Array, error) {
+	if len(p) == 0 {
+		return nil, fmt.Errorf("cannot delete the root node")
+	}
+	n, err := setPath(m, p, nil, true)
+	if err != nil {
+		return nil, err
+	}
+	return n.(Array), nil
+}
+
+func setPath(n Node, p Path, v Node, del bool) (Node, error) {
+	e, last := p[0], len(p) == 1
+	switch m := n.(type) {
+	case Object:
+		k, err := pathKey(e)
+		if err != nil {
+			return nil, err
+		}
+		sub, ok := m[k]
+		if !ok && (del || !last) {
+			return nil, fmt.Errorf("key %q does not exist", k)
+		}
+		m = m.CloneObject()
+		if last {
+			if del {
+				delete(m, k)
+			} else {
+				m[k] = v
+			}
+			return m, nil
+		}
+		nv, err := setPath(sub, p[1:], v, del)
+		if err != nil {
+			return nil, err
+		}
+		m[k] = nv
+		return m, nil
+	case Array:
+		i, err := pathIndex(e)
+		if err != nil {
+			return nil, err
+		} else if i < 0 || i >= len(m) {
+			return nil, fmt.Errorf("index out of range: %d", i)
+		}
+		if last && del {
+			out := make(Array, 0, len(m)-1)
+			out = append(out, m[:i]...)
+			return append(out, m[i+1:]...), nil
+		}
+		m = m.CloneList()
+		if last {
+			m[i] = v
+			return m, nil
+		}
+		nv, err := setPath(m[i], p[1:], v, del)
+		if err != nil {
+			return nil, err
+		}
+		m[i] = nv
+		return m, nil
+	}
+	return nil, fmt.Errorf("expected object or array, got %v", KindOf(n))
+}
@@ -0,0 +1,78 @@
+package nodes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPathString(t *testing.T) {
+	require.Equal(t, "", Path{}.String())
+	require.Equal(t, "/", Path{""}.String())
+	p := Path{"a/b", 0, "c~"}
+	require.Equal(t, "/a~1b/0/c~0", p.String())
+
+	for _, p := range []Path{
+		{}, {""}, {"", ""}, {"a", ""}, {"", 1}, p,
+	} {
+		p2, err := ParsePath(p.String())
+		require.NoError(t, err)
+		require.Equal(t, p, p2)
+	}
+
+	_, err := ParsePath("a")
+	require.Error(t, err)
+}
+
+func TestPathGetSetDelete(t *testing.T) {
+	leaf := Object{"v": Int(1), "0": String("zero")}
+	root := Object{
+		"arr": Array{String("a"), leaf},
+	}
+	orig := root.Clone()
+
+	v, ok := root.GetPath(Path{"arr", 1, "v"})
+	require.True(t, ok)
+	require.Equal(t, Int(1), v)
+
+	// numeric elements are allowed as object keys
+	v, ok = root.GetPath(Path{"arr", 1, 0})
+	require.True(t, ok)
+	require.Equal(t, String("zero"), v)
+
+	_, ok = root.GetPath(Path{"arr", 2})
+	require.False(t, ok)
+	_, ok = root.GetPath(Path{"arr", 0, "v"})
+	require.False(t, ok)
+
+	root2, err := root.SetPath(Path{"arr", 1, "v"}, Int(2))
+	require.NoError(t, err)
+	require.Equal(t, Object{
+		"arr": Array{String("a"), Object{"v": Int(2), "0": String("zero")}},
+	}, root2)
+
+	root2, err = root.SetPath(Path{"arr", 1, "new"}, Bool(true))
+	require.NoError(t, err)
+	v, _ = root2.GetPath(Path{"arr", 1, "new"})
+	require.Equal(t, Bool(true), v)
+
+	_, err = root.SetPath(Path{"arr", 5}, Bool(true))
+	require.Error(t, err)
+	_, err = root.SetPath(Path{"x", "y"}, Bool(true))
+	require.Error(t, err)
+
+	root2, err = root.DeletePath(Path{"arr", 0})
+	require.NoError(t, err)
+	require.Equal(t, Object{
+		"arr": Array{leaf},
+	}, root2)
+
+	arr, err := Array{Int(1), Object{"k": Int(2)}}.DeletePath(Path{1, "k"})
+	require.NoError(t, err)
+	require.Equal(t, Array{Int(1), Object{}}, arr)
+
+	_, err = root.DeletePath(Path{"missing"})
+	require.Error(t, err)
+
+	require.Equal(t, orig, root, "original tree was modified")
+}
@@ -1,7 +1,6 @@
 package xpath
 
 import (
-	"fmt"
 	"strconv"
 	"strings"
 
@@ -14,92 +13,224 @@ import (
 
 var _ xpath.NodeNavigator = &nodeNavigator{}
 
-// newNavigator creates a new xpath.nodeNavigator for the specified html.node.
+// newNavigator creates a new xpath.nodeNavigator for the specified tree.
 func newNavigator(root nodes.External) *nodeNavigator {
-	n := &node{n: root, typ: rootNode}
-	return &nodeNavigator{root: n, cur: n, attri: -1}
+	d := &document{
+		idx:   nodes.NewLazyIndex(root),
+		attrs: make(map[nodes.NodeRef][]attr),
+	}
+	return &nodeNavigator{doc: d, cur: position{ref: nodes.NoRef, root: true}, attri: -1}
 }
 
-// A nodeType is the type of a node.
-type nodeType uint
-
-const (
-	// rootNode is a document object that, as the root of the document tree,
-	// provides access to the entire XML document.
-	rootNode nodeType = iota
-	// objectNode is an element.
-	objectNode
-	fieldNode
-	// valueNode is the text content of a node.
-	valueNode
-)
-
 type attr struct {
 	key string
 	val string
 }
 
-type node struct {
-	typ nodeType
-
-	n    nodes.External
-	kind nodes.Kind
-	obj  nodes.ExternalObject
+// document is a state shared by all navigators created for the same tree.
+type document struct {
+	idx   *nodes.Index
+	attrs map[nodes.NodeRef][]attr
+}
 
-	tag    [2]string
-	attrs  []attr
-	sub    []*node
-	par    *node
-	parInd int // index in parent's sub array
+// position is a node of the XML projection of the tree.
+//
+// Each object is projected to an element with the tag set to the object type. Object fields are
+// projected to field elements that wrap the value of the field. Arrays are always projected to
+// field elements, with array elements as children. Values are projected to text nodes.
+type position struct {
+	ref   nodes.NodeRef
+	field bool // field element that wraps the node
+	root  bool // document root
 }
 
 // nodeNavigator is for navigating JSON document.
 type nodeNavigator struct {
-	root, cur *node
-	attri     int
+	doc   *document
+	cur   position
+	attri int
+}
+
+// node returns a node for a given reference. Nil nodes are projected as empty strings.
+func (d *document) node(r nodes.NodeRef) nodes.External {
+	n := d.idx.NodeAt(r)
+	if n == nil || n.Kind() == nodes.KindNil {
+		return nodes.String("") // TODO
+	}
+	return n
+}
+
+func (d *document) kind(r nodes.NodeRef) nodes.Kind {
+	return d.node(r).Kind()
+}
+
+// fieldName returns the name of the object field that contains the node, or an empty string
+// if the node is not wrapped into a field element.
+func (d *document) fieldName(r nodes.NodeRef) string {
+	p := d.idx.ParentRef(r)
+	if p == nodes.NoRef || d.kind(p) != nodes.KindObject {
+		return ""
+	}
+	k, _ := d.idx.KeyAt(r).(string)
+	if k == uast.KeyToken {
+		return ""
+	}
+	return k
+}
+
+// top returns the outermost projection of the node.
+func (d *document) top(r nodes.NodeRef) position {
+	field := d.kind(r) == nodes.KindArray || d.fieldName(r) != ""
+	return position{ref: r, field: field}
+}
+
+// container returns a projection of the node that holds projections of its children.
+func (d *document) container(r nodes.NodeRef) position {
+	return position{ref: r, field: d.kind(r) == nodes.KindArray}
+}
+
+func (d *document) parent(p position) (position, bool) {
+	if p.root {
+		return position{}, false
+	}
+	if !p.field && d.fieldName(p.ref) != "" {
+		// wrapped into a field element
+		return position{ref: p.ref, field: true}, true
+	}
+	par := d.idx.ParentRef(p.ref)
+	if par == nodes.NoRef {
+		return position{ref: nodes.NoRef, root: true}, true
+	}
+	return d.container(par), true
+}
+
+// sibling returns a projection of the i-th child of the node's parent.
+// The node itself is returned if it is the only child of its parent.
+func (d *document) sibling(p position, i int) (position, bool) {
+	if p.root {
+		return position{}, false
+	}
+	par := d.idx.ParentRef(p.ref)
+	if par == nodes.NoRef || (!p.field && d.fieldName(p.ref) != "") {
+		if i != 0 {
+			return position{}, false
+		}
+		return p, true
+	}
+	kids := d.idx.ChildRefs(par)
+	if i < 0 || i >= len(kids) {
+		return position{}, false
+	}
+	return d.top(kids[i]), true
+}
+
+func (d *document) firstChild(p position) (position, bool) {
+	if p.root {
+		r := d.idx.RootRef()
+		if r == nodes.NoRef {
+			return position{}, false
+		}
+		return d.top(r), true
+	}
+	kind := d.kind(p.ref)
+	if p.field && kind != nodes.KindArray {
+		return position{ref: p.ref}, true
+	}
+	if !p.field && kind != nodes.KindObject {
+		return position{}, false
+	}
+	kids := d.idx.ChildRefs(p.ref)
+	if len(kids) == 0 {
+		return position{}, false
+	}
+	return d.top(kids[0]), true
+}
+
+// index returns the position of the node in the list of its parent's children.
+func (d *document) index(p position) int {
+	if p.root || d.idx.ParentRef(p.ref) == nodes.NoRef || (!p.field && d.fieldName(p.ref) != "") {
+		return 0
+	}
+	return d.idx.ChildIndex(p.ref)
+}
+
+func (d *document) tag(p position) [2]string {
+	if p.root {
+		return [2]string{}
+	}
+	if p.field {
+		return [2]string{"", d.fieldName(p.ref)}
+	}
+	n := d.node(p.ref)
+	if n.Kind() != nodes.KindObject {
+		return [2]string{}
+	}
+	typ := uast.TypeOf(n)
+	if i := strings.Index(typ, ":"); i >= 0 {
+		return [2]string{typ[:i], typ[i+1:]}
+	}
+	return [2]string{"", typ}
+}
+
+func (d *document) attributes(p position) []attr {
+	if p.root || p.field {
+		return nil
+	}
+	if attrs, ok := d.attrs[p.ref]; ok {
+		return attrs
+	}
+	obj, ok := d.node(p.ref).(nodes.ExternalObject)
+	if !ok {
+		return nil
+	}
+	attrs := loadAttributes(obj)
+	d.attrs[p.ref] = attrs
+	return attrs
 }
 
 func (a *nodeNavigator) Current() nodes.External {
-	return a.cur.n
+	if a.cur.root {
+		return a.doc.idx.Root()
+	}
+	return a.doc.node(a.cur.ref)
 }
 
 func (a *nodeNavigator) NodeType() xpath.NodeType {
 	if a.attri >= 0 {
 		return xpath.AttributeNode
 	}
-	switch a.cur.typ {
-	case valueNode:
-		return xpath.TextNode
-	case rootNode:
+	switch {
+	case a.cur.root:
 		return xpath.RootNode
-	case objectNode, fieldNode:
+	case a.cur.field:
+		return xpath.ElementNode
+	case a.doc.kind(a.cur.ref) == nodes.KindObject:
 		return xpath.ElementNode
 	default:
-		panic(fmt.Errorf("unknown node type %v", a.cur.typ))
+		return xpath.TextNode
 	}
 }
 
 func (a *nodeNavigator) LocalName() string {
 	if a.attri >= 0 {
-		return a.cur.attrs[a.attri].key
+		return a.doc.attributes(a.cur)[a.attri].key
 	}
-	return a.cur.tag[1]
+	return a.doc.tag(a.cur)[1]
 }
 
 func (a *nodeNavigator) Prefix() string {
 	if a.attri >= 0 {
 		return ""
 	}
-	return a.cur.tag[0]
+	return a.doc.tag(a.cur)[0]
 }
 
 func (a *nodeNavigator) Value() string {
 	if a.attri >= 0 {
-		return a.cur.attrs[a.attri].val
+		return a.doc.attributes(a.cur)[a.attri].val
 	}
-	switch a.cur.typ {
-	case valueNode:
-		return nodes.ToString(a.cur.n.Value())
+	if a.NodeType() == xpath.TextNode {
+		return nodes.ToString(a.doc.node(a.cur.ref).Value())
 	}
 	return ""
 }
@@ -110,37 +241,34 @@ func (a *nodeNavigator) Copy() xpath.NodeNavigator {
 }
 
 func (a *nodeNavigator) MoveToRoot() {
-	a.cur = a.root
+	a.cur = position{ref: nodes.NoRef, root: true}
 	a.attri = -1
 }
 
 func (a *nodeNavigator) MoveToParent() bool {
-	n := a.cur.par
-	if n == nil {
+	p, ok := a.doc.parent(a.cur)
+	if !ok {
 		return false
 	}
-	a.cur = n
+	a.cur = p
 	return true
 }
 
 func (x *nodeNavigator) MoveToNextAttribute() bool {
-	if x.cur.attrs == nil && x.cur.obj != nil {
-		x.cur.loadAttributes()
-	}
-	if x.attri+1 < len(x.cur.attrs) {
+	if x.attri+1 < len(x.doc.attributes(x.cur)) {
 		x.attri++
 		return true
 	}
 	return false
 }
 
-func (nd *node) loadAttributes() {
-	nd.attrs = []attr{} // indicate that attributes are loaded even if node has none
+func loadAttributes(obj nodes.ExternalObject) []attr {
+	var attrs []attr
 	add := func(k, v string) {
-		nd.attrs = append(nd.attrs, attr{key: k, val: v})
+		attrs = append(attrs, attr{key: k, val: v})
 	}
-	for _, k := range nd.obj.Keys() {
-		v, _ := nd.obj.ValueAt(k)
+	for _, k := range obj.Keys() {
+		v, _ := obj.ValueAt(k)
 		switch sub := v.(type) {
 		case nil:
 			add(k, "")
@@ -203,171 +331,50 @@ func (nd *node) loadAttributes() {
 			}
 		}
 	}
-}
-
-func (nd *node) loadChildren() {
-	// project fields
-	obj := nd.obj
-	keys := obj.Keys()
-	nd.sub = make([]*node, 0, len(keys))
-	for _, k := range keys {
-		v, ok := obj.ValueAt(k)
-		if !ok {
-			continue
-		}
-		var vn *node
-		switch k {
-		case uast.KeyToken:
-			vn = toNode(v, "")
-		default:
-			vn = toNode(v, k)
-		}
-		vn.par = nd
-		vn.parInd = len(nd.sub)
-		nd.sub = append(nd.sub, vn)
-	}
-}
-
-func toNode(n nodes.External, field string) *node {
-	if n == nil || n.Kind() == nodes.KindNil {
-		n = nodes.String("") // TODO
-	}
-	nd := &node{n: n, kind: n.Kind()}
-
-	wrap := func(nd *node) *node {
-		if field == "" {
-			return nd
-		}
-		// wrap node into field-node
-		f := &node{
-			n: nd.n, kind: nd.kind,
-			typ: fieldNode, tag: [2]string{"", field},
-			sub: []*node{nd},
-		}
-		nd.par = f
-		nd.parInd = 0
-		return f
-	}
-
-	switch nd.kind {
-	case nodes.KindNil:
-		return nil // TODO
-	case nodes.KindObject:
-		if typ := uast.TypeOf(n); typ != "" {
-			if i := strings.Index(typ, ":"); i >= 0 {
-				nd.tag = [2]string{typ[:i], typ[i+1:]}
-			} else {
-				nd.tag = [2]string{"", typ}
-			}
-		}
-		nd.obj, _ = nd.n.(nodes.ExternalObject)
-		nd.typ = objectNode
-		return wrap(nd)
-	case nodes.KindArray:
-		arr, _ := nd.n.(nodes.ExternalArray)
-		// array == sub nodes of this field
-		f := &node{
-			n: nd.n, kind: nd.kind,
-			typ: fieldNode, tag: [2]string{"", field},
-		}
-		if arr == nil {
-			f.sub = []*node{}
-			return f
-		}
-		sz := arr.Size()
-		f.sub = make([]*node, 0, sz)
-		for i := 0; i < sz; i++ {
-			v := arr.ValueAt(i)
-			s := toNode(v, "")
-			s.par = f
-			s.parInd = i
-			f.sub = append(f.sub, s)
-		}
-		return f
-	default:
-		// value
-		nd.typ = valueNode
-		return wrap(nd)
-	}
+	return attrs
 }
 
 func (a *nodeNavigator) MoveToChild() bool {
-	switch a.cur.typ {
-	case rootNode:
-		// return the same node, but without the root type
-		n := toNode(a.cur.n, "")
-		if n == nil {
-			return false
-		}
-		n.par = a.cur
-		a.cur = n
-		return true
-	case objectNode:
-		// node is an object, children are wrapped into a tag with the name = field
-		if a.cur.obj == nil {
-			return false
-		}
-		cur := a.cur
-		if cur.sub == nil {
-			cur.loadChildren()
-		}
-		if len(cur.sub) == 0 {
-			return false
-		}
-		a.cur = cur.sub[0]
-		return true
-	case fieldNode:
-		if len(a.cur.sub) == 0 {
-			return false
-		}
-		n := a.cur.sub[0]
-		if n == nil {
-			return false
-		}
-		a.cur = n
-		return true
+	p, ok := a.doc.firstChild(a.cur)
+	if !ok {
+		return false
 	}
-	return false
+	a.cur = p
+	return true
 }
 
-func (a *nodeNavigator) isSub() bool {
-	return a.cur.par != nil && a.cur.parInd < len(a.cur.par.sub)
-}
 func (a *nodeNavigator) MoveToFirst() bool {
-	if a.isSub() {
-		par := a.cur.par
-		if n := par.sub[0]; n != nil {
-			a.cur = n
-		}
+	if p, ok := a.doc.sibling(a.cur, 0); ok {
+		a.cur = p
 	}
 	return true
 }
 
 func (a *nodeNavigator) MoveToNext() bool {
-	if a.isSub() {
-		par := a.cur.par
-		if i := a.cur.parInd + 1; i < len(par.sub) {
-			a.cur = par.sub[i]
-			return true
-		}
+	p, ok := a.doc.sibling(a.cur, a.doc.index(a.cur)+1)
+	if !ok {
+		return false
 	}
-	return false
+	a.cur = p
+	return true
 }
 
 func (a *nodeNavigator) MoveToPrevious() bool {
-	if a.isSub() {
-		par := a.cur.par
-		if i := a.cur.parInd - 1; i >= 0 && i < len(par.sub) {
-			a.cur = par.sub[i]
-			return true
-		}
+	i := a.doc.index(a.cur) - 1
+	if i < 0 {
+		return false
 	}
-	return false
+	p, ok := a.doc.sibling(a.cur, i)
+	if !ok {
+		return false
+	}
+	a.cur = p
+	return true
 }
 
 func (a *nodeNavigator) MoveTo(other xpath.NodeNavigator) bool {
 	node, ok := other.(*nodeNavigator)
-	if !ok || node.root != a.root {
+	if !ok || node.doc != a.doc {
 		return false
 	}
 	a.cur = node.cur
@@ -235,6 +235,33 @@ func TestFilterObject(t *testing.T) {
 	}
 }
 
+func TestFilterShared(t *testing.T) {
+	// the same node instance in two places, as in a DAG
+	b := nodes.Object{
+		uast.KeyType: nodes.String("B"),
+	}
+	sub := nodes.Array{b}
+	var root = nodes.Object{
+		uast.KeyType: nodes.String("A"),
+		"one":        b,
+		"sub":        sub,
+	}
+
+	idx := New()
+
+	it, err := idx.Execute(root, "//B")
+	require.NoError(t, err)
+	expect(t, it, b, b)
+
+	it, err = idx.Execute(root, "//B/..")
+	require.NoError(t, err)
+	expect(t, it, b, sub)
+
+	it, err = idx.Execute(root, "/A/sub/B/ancestor::A")
+	require.NoError(t, err)
+	expect(t, it, root)
+}
+
 func expect(t testing.TB, it query.Iterator, exp ...nodes.Node) {
 	var out []nodes.Node
 	for it.Next() {
@@ -116,9 +116,5 @@ func (it *iterator) Node() nodes.External {
 	if c == nil {
 		return nil
 	}
-	nav := c.(*nodeNavigator)
-	if nav.cur == nil {
-		return nil
-	}
-	return nav.cur.n
+	return c.(*nodeNavigator).Current()
 }
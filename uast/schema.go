@@ -0,0 +1,191 @@
+package uast
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+	"gopkg.in/src-d/go-errors.v1"
+)
+
+// SchemaVersion is the current version of the schema of UAST types registered in this package.
+//
+// It must be incremented on each incompatible change of these types, and a migration from the previous
+// version must be registered with RegisterMigration.
+const SchemaVersion = 1
+
+// KeySchemaVersion is a key of the tree metadata object that stores the schema version. See SchemaMeta.
+const KeySchemaVersion = "schema_version"
+
+var (
+	// ErrSchemaTooNew is returned when trying to migrate a tree with a schema version newer than the current one.
+	ErrSchemaTooNew = errors.NewKind("schema version %d is newer than the current version %d")
+	// ErrNoMigration is returned when there is no migration registered for a schema version.
+	ErrNoMigration = errors.NewKind("no migration from schema version %d")
+)
+
+// MigrationFunc upgrades a tree from one schema version to the next one.
+//
+// The function may modify the tree in place. Its signature matches transformer.Transformer.Do.
+type MigrationFunc func(root nodes.Node) (nodes.Node, error)
+
+// Migrations is a registry of schema migrations.
+type Migrations struct {
+	current int
+	m       map[int]MigrationFunc
+}
+
+// NewMigrations creates a migration registry for a given current schema version.
+func NewMigrations(current int) *Migrations {
+	if current < 1 {
+		panic("schema version should be positive")
+	}
+	return &Migrations{current: current, m: make(map[int]MigrationFunc)}
+}
+
+// Current returns the current schema version.
+func (m *Migrations) Current() int {
+	return m.current
+}
+
+// Register adds a migration that upgrades a tree from a given schema version to the next one.
+func (m *Migrations) Register(from int, fnc MigrationFunc) {
+	if from < 1 || from >= m.current {
+		panic(fmt.Errorf("invalid schema version for a migration: %d", from))
+	} else if _, ok := m.m[from]; ok {
+		panic(fmt.Errorf("migration from schema version %d is already registered", from))
+	}
+	m.m[from] = fnc
+}
+
+// Versions returns a sorted list of schema versions that have a registered migration.
+func (m *Migrations) Versions() []int {
+	out := make([]int, 0, len(m.m))
+	for v := range m.m {
+		out = append(out, v)
+	}
+	sort.Ints(out)
+	return out
+}
+
+// Migrate upgrades a tree from a given schema version to the current one by applying all migrations in order.
+// The tree may be modified in place.
+//
+// It returns ErrSchemaTooNew if the version of the tree is newer than the current one, and ErrNoMigration
+// if one of the migrations is not registered.
+func (m *Migrations) Migrate(root nodes.Node, from int) (nodes.Node, error) {
+	if from > m.current {
+		return nil, ErrSchemaTooNew.New(from, m.current)
+	}
+	// check all migrations before modifying the tree
+	for v := from; v < m.current; v++ {
+		if _, ok := m.m[v]; !ok {
+			return nil, ErrNoMigration.New(v)
+		}
+	}
+	for v := from; v < m.current; v++ {
+		var err error
+		root, err = m.m[v](root)
+		if err != nil {
+			return nil, fmt.Errorf("migration from schema version %d failed: %v", v, err)
+		}
+	}
+	return root, nil
+}
+
+var migrations = NewMigrations(SchemaVersion)
+
+// RegisterMigration registers a migration of UAST types from this package from a given schema version to the next one.
+func RegisterMigration(from int, fnc MigrationFunc) {
+	migrations.Register(from, fnc)
+}
+
+// Migrate upgrades a tree from a given schema version to SchemaVersion. See RegisterMigration.
+func Migrate(root nodes.Node, from int) (nodes.Node, error) {
+	return migrations.Migrate(root, from)
+}
+
+// SchemaMeta returns a copy of the tree metadata with the schema version set to SchemaVersion.
+// The metadata can be nil.
+func SchemaMeta(meta nodes.Object) nodes.Object {
+	out := make(nodes.Object, len(meta)+1)
+	for k, v := range meta {
+		out[k] = v
+	}
+	out[KeySchemaVersion] = nodes.Int(SchemaVersion)
+	return out
+}
+
+// SchemaVersionOf returns the schema version stored in the tree metadata. See SchemaMeta.
+//
+// Driver responses, binary graphs written by "bblfsh-sdk uast convert" and archives always record the schema version,
+// thus trees without it are assumed to be written before the schema was versioned, and have the version 1.
+// Text formats (YAML, JSON, etc.) do not store the metadata; the caller must know the version of such trees.
+func SchemaVersionOf(meta nodes.External) (int, error) {
+	if meta == nil {
+		return 1, nil
+	}
+	obj, ok := meta.(nodes.ExternalObject)
+	if !ok || meta.Kind() != nodes.KindObject {
+		return 0, fmt.Errorf("expected metadata object, got %v", nodes.KindOf(meta))
+	}
+	v, ok := obj.ValueAt(KeySchemaVersion)
+	if !ok || v == nil {
+		return 1, nil
+	}
+	var vers int64
+	switch val := v.Value().(type) {
+	case nodes.Int:
+		vers = int64(val)
+	case nodes.Uint:
+		vers = int64(val)
+	case nodes.Float:
+		vers = int64(val)
+		if nodes.Float(vers) != val {
+			return 0, fmt.Errorf("invalid schema version: %v", val)
+		}
+	default:
+		return 0, fmt.Errorf("invalid schema version: %v", v.Value())
+	}
+	if vers < 1 {
+		return 0, fmt.Errorf("invalid schema version: %d", vers)
+	}
+	return int(vers), nil
+}
+
+// RenameType returns a migration that changes the type of all objects from old to new.
+func RenameType(old, new string) MigrationFunc {
+	return func(root nodes.Node) (nodes.Node, error) {
+		nodes.WalkPreOrder(root, func(n nodes.Node) bool {
+			if obj, ok := n.(nodes.Object); ok && obj[KeyType] == nodes.String(old) {
+				obj[KeyType] = nodes.String(new)
+			}
+			return true
+		})
+		return root, nil
+	}
+}
+
+// RenameField returns a migration that renames a field of all objects of a given type.
+func RenameField(typ, old, new string) MigrationFunc {
+	return func(root nodes.Node) (nodes.Node, error) {
+		var last error
+		nodes.WalkPreOrder(root, func(n nodes.Node) bool {
+			obj, ok := n.(nodes.Object)
+			if !ok || obj[KeyType] != nodes.String(typ) {
+				return true
+			}
+			v, ok := obj[old]
+			if !ok {
+				return true
+			} else if _, ok = obj[new]; ok {
+				last = fmt.Errorf("%s: field %q already exists", typ, new)
+				return true
+			}
+			delete(obj, old)
+			obj[new] = v
+			return true
+		})
+		return root, last
+	}
+}
@@ -0,0 +1,93 @@
+package uast_test
+
+import (
+	"testing"
+
+	. "github.com/bblfsh/sdk/v3/uast"
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrations(t *testing.T) {
+	m := NewMigrations(3)
+	m.Register(2, RenameField("test:Node", "Old", "New"))
+	m.Register(1, RenameType("test:Obsolete", "test:Node"))
+	require.Equal(t, []int{1, 2}, m.Versions())
+	require.Panics(t, func() {
+		m.Register(1, RenameType("a", "b"))
+	})
+	require.Panics(t, func() {
+		m.Register(3, RenameType("a", "b"))
+	})
+
+	tree := func() nodes.Node {
+		return nodes.Array{
+			nodes.Object{KeyType: nodes.String("test:Obsolete"), "Old": nodes.Int(1)},
+			nodes.Object{KeyType: nodes.String("test:Other"), "Old": nodes.Int(2)},
+		}
+	}
+
+	out, err := m.Migrate(tree(), 1)
+	require.NoError(t, err)
+	require.Equal(t, nodes.Array{
+		nodes.Object{KeyType: nodes.String("test:Node"), "New": nodes.Int(1)},
+		nodes.Object{KeyType: nodes.String("test:Other"), "Old": nodes.Int(2)},
+	}, out)
+
+	out, err = m.Migrate(tree(), 3)
+	require.NoError(t, err)
+	require.Equal(t, tree(), out)
+
+	_, err = m.Migrate(tree(), 4)
+	require.True(t, ErrSchemaTooNew.Is(err))
+
+	m = NewMigrations(3)
+	m.Register(2, RenameType("a", "b"))
+	in := tree()
+	_, err = m.Migrate(in, 1)
+	require.True(t, ErrNoMigration.Is(err))
+	require.Equal(t, tree(), in, "tree should not be modified")
+}
+
+func TestRenameFieldConflict(t *testing.T) {
+	fnc := RenameField("test:Node", "Old", "New")
+	_, err := fnc(nodes.Object{
+		KeyType: nodes.String("test:Node"),
+		"Old":   nodes.Int(1),
+		"New":   nodes.Int(2),
+	})
+	require.Error(t, err)
+}
+
+func TestSchemaVersionOf(t *testing.T) {
+	vers, err := SchemaVersionOf(nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, vers)
+
+	meta := SchemaMeta(nodes.Object{"lang": nodes.String("go")})
+	require.Equal(t, nodes.Object{
+		"lang":           nodes.String("go"),
+		KeySchemaVersion: nodes.Int(SchemaVersion),
+	}, meta)
+	vers, err = SchemaVersionOf(meta)
+	require.NoError(t, err)
+	require.Equal(t, SchemaVersion, vers)
+
+	vers, err = SchemaVersionOf(nodes.Object{KeySchemaVersion: nodes.Float(2)})
+	require.NoError(t, err)
+	require.Equal(t, 2, vers)
+
+	for _, meta := range []nodes.Node{
+		nodes.String("meta"),
+		nodes.Object{KeySchemaVersion: nodes.String("1")},
+		nodes.Object{KeySchemaVersion: nodes.Int(0)},
+		nodes.Object{KeySchemaVersion: nodes.Float(1.5)},
+	} {
+		_, err = SchemaVersionOf(meta)
+		require.Error(t, err, "%v", meta)
+	}
+
+	out, err := Migrate(nodes.Int(1), SchemaVersion)
+	require.NoError(t, err)
+	require.Equal(t, nodes.Int(1), out)
+}
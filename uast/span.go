@@ -0,0 +1,165 @@
+package uast
+
+import "github.com/bblfsh/sdk/v3/uast/nodes"
+
+// Span is a range of a source file between two positions. The Start position is inclusive
+// and the End position is exclusive.
+//
+// Positions are compared with Position.Less, thus offsets are used if both positions have them,
+// and line-column pairs are used otherwise.
+type Span struct {
+	Start Position
+	End   Position
+}
+
+// Span returns a span between start and end positions. It returns false if any of them is missing.
+func (p Positions) Span() (Span, bool) {
+	start, end := p.Start(), p.End()
+	if start == nil || end == nil {
+		return Span{}, false
+	}
+	s := Span{Start: *start, End: *end}
+	return s, s.Valid()
+}
+
+// ToPositions converts the span to a positions map with KeyStart and KeyEnd positions.
+func (s Span) ToPositions() Positions {
+	return Positions{KeyStart: s.Start, KeyEnd: s.End}
+}
+
+// ToObject converts the span to a generic UAST node. See Positions.ToObject.
+func (s Span) ToObject() nodes.Object {
+	return s.ToPositions().ToObject()
+}
+
+// Valid checks if both positions of the span are valid, and the start is not after the end.
+func (s Span) Valid() bool {
+	return s.Start.Valid() && s.End.Valid() && !s.End.Less(s.Start)
+}
+
+// IsEmpty checks if the span has a zero length.
+func (s Span) IsEmpty() bool {
+	return !s.Start.Less(s.End)
+}
+
+// ContainsPos checks if the position is inside the span.
+func (s Span) ContainsPos(p Position) bool {
+	return !p.Less(s.Start) && p.Less(s.End)
+}
+
+// Contains checks if the span fully contains another span.
+// An empty span is contained in s if it is located between the start and the end of s.
+func (s Span) Contains(s2 Span) bool {
+	return !s2.Start.Less(s.Start) && !s.End.Less(s2.End)
+}
+
+// Overlaps checks if two spans have at least one position in common. Empty spans never overlap.
+func (s Span) Overlaps(s2 Span) bool {
+	if s.IsEmpty() || s2.IsEmpty() {
+		return false
+	}
+	return s.Start.Less(s2.End) && s2.Start.Less(s.End)
+}
+
+// Intersect returns a span that is common for both spans. It returns false if spans do not overlap.
+func (s Span) Intersect(s2 Span) (Span, bool) {
+	if !s.Overlaps(s2) {
+		return Span{}, false
+	}
+	out := s
+	if out.Start.Less(s2.Start) {
+		out.Start = s2.Start
+	}
+	if s2.End.Less(out.End) {
+		out.End = s2.End
+	}
+	return out, true
+}
+
+// Union returns the smallest span that contains both spans.
+func (s Span) Union(s2 Span) Span {
+	out := s
+	if s2.Start.Less(out.Start) {
+		out.Start = s2.Start
+	}
+	if out.End.Less(s2.End) {
+		out.End = s2.End
+	}
+	return out
+}
+
+// SpanOf returns a span of the node. If the node has no start or end position, the span is computed
+// from positions of its descendants. For arrays, the span covers all the elements.
+//
+// It returns false if neither the node nor its descendants have positions.
+func SpanOf(n nodes.Node) (Span, bool) {
+	s, ok, _ := enclosing(n, nil)
+	return s, ok
+}
+
+// EnclosingNode finds the deepest object in the tree that contains a given span. See SpanOf and Span.Contains.
+//
+// It can be used to map a text selection to a node. It returns nil if no object contains the span.
+func EnclosingNode(root nodes.Node, s Span) nodes.Object {
+	_, _, obj := enclosing(root, &s)
+	return obj
+}
+
+// enclosing computes a span of the node. If the target span is set, it also returns the deepest object
+// that contains it.
+func enclosing(n nodes.Node, target *Span) (Span, bool, nodes.Object) {
+	var (
+		span  Span
+		ok    bool
+		found nodes.Object
+	)
+	add := func(s Span) {
+		if !ok {
+			span, ok = s, true
+		} else {
+			span = span.Union(s)
+		}
+	}
+	sub := func(v nodes.Node) {
+		s, sok, f := enclosing(v, target)
+		if found == nil {
+			found = f
+		}
+		if sok {
+			add(s)
+		}
+	}
+	switch n := n.(type) {
+	case nodes.Object:
+		ps := PositionsOf(n)
+		own, hasOwn := ps.Span()
+		if hasOwn {
+			if target == nil || !own.Contains(*target) {
+				// children are not expected to be outside of the parent
+				return own, true, nil
+			}
+		} else {
+			for _, p := range []*Position{ps.Start(), ps.End()} {
+				if p != nil && p.Valid() {
+					add(Span{Start: *p, End: *p})
+				}
+			}
+		}
+		for _, k := range n.Keys() {
+			if k != KeyPos {
+				sub(n[k])
+			}
+		}
+		if hasOwn {
+			span, ok = own, true
+		}
+		if found == nil && ok && target != nil && span.Contains(*target) {
+			found = n
+		}
+	case nodes.Array:
+		for _, v := range n {
+			sub(v)
+		}
+	}
+	return span, ok, found
+}
@@ -0,0 +1,96 @@
+package uast_test
+
+import (
+	"testing"
+
+	. "github.com/bblfsh/sdk/v3/uast"
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+	"github.com/stretchr/testify/require"
+)
+
+// span creates a span on the first line using offsets.
+func span(start, end uint32) Span {
+	return Span{
+		Start: Position{Offset: start, Line: 1, Col: start + 1},
+		End:   Position{Offset: end, Line: 1, Col: end + 1},
+	}
+}
+
+func TestSpan(t *testing.T) {
+	a, b := span(0, 5), span(3, 8)
+
+	require.True(t, a.Valid())
+	require.False(t, Span{Start: b.End, End: b.Start}.Valid())
+	require.False(t, a.IsEmpty())
+	require.True(t, span(2, 2).IsEmpty())
+
+	require.True(t, a.ContainsPos(a.Start))
+	require.False(t, a.ContainsPos(a.End))
+
+	require.True(t, a.Contains(a))
+	require.True(t, a.Contains(span(1, 4)))
+	require.True(t, a.Contains(span(5, 5)))
+	require.False(t, a.Contains(b))
+
+	require.True(t, a.Overlaps(b))
+	require.True(t, b.Overlaps(a))
+	require.False(t, a.Overlaps(span(5, 8)))
+	require.False(t, a.Overlaps(span(2, 2)))
+
+	s, ok := a.Intersect(b)
+	require.True(t, ok)
+	require.Equal(t, span(3, 5), s)
+	_, ok = a.Intersect(span(6, 8))
+	require.False(t, ok)
+
+	require.Equal(t, span(0, 8), a.Union(b))
+	require.Equal(t, span(0, 8), span(6, 8).Union(a))
+
+	ps := a.ToPositions()
+	s, ok = ps.Span()
+	require.True(t, ok)
+	require.Equal(t, a, s)
+	require.Equal(t, ps.ToObject(), a.ToObject())
+
+	_, ok = Positions{KeyStart: a.Start}.Span()
+	require.False(t, ok)
+}
+
+func spanNode(typ string, s *Span, fields nodes.Object) nodes.Object {
+	obj := nodes.Object{KeyType: nodes.String(typ)}
+	if s != nil {
+		obj[KeyPos] = s.ToObject()
+	}
+	for k, v := range fields {
+		obj[k] = v
+	}
+	return obj
+}
+
+func TestSpanOf(t *testing.T) {
+	s1, s2 := span(2, 4), span(6, 9)
+	left := spanNode("Ident", &s1, nil)
+	right := spanNode("Ident", &s2, nil)
+	// the call node has no positions
+	call := spanNode("Call", nil, nodes.Object{
+		"Args": nodes.Array{left, right},
+	})
+	s0 := span(0, 10)
+	root := spanNode("File", &s0, nodes.Object{"Body": nodes.Array{call}})
+
+	s, ok := SpanOf(call)
+	require.True(t, ok)
+	require.Equal(t, span(2, 9), s)
+
+	s, ok = SpanOf(root)
+	require.True(t, ok)
+	require.Equal(t, s0, s)
+
+	_, ok = SpanOf(spanNode("Empty", nil, nil))
+	require.False(t, ok)
+
+	require.Equal(t, left, EnclosingNode(root, span(3, 4)))
+	require.Equal(t, call, EnclosingNode(root, span(3, 7)))
+	require.Equal(t, root, EnclosingNode(root, span(0, 1)))
+	require.Nil(t, EnclosingNode(root, span(5, 11)))
+}
@@ -0,0 +1,186 @@
+// Package stats computes statistics about UAST trees, such as node counts, depth and size of subtrees.
+package stats
+
+import (
+	"sort"
+
+	"github.com/bblfsh/sdk/v3/uast"
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+	"github.com/bblfsh/sdk/v3/uast/role"
+)
+
+// Stats contains statistics about a single tree.
+type Stats struct {
+	// Nodes is the total number of non-nil nodes in the tree, including values.
+	Nodes int
+	// Objects, Arrays and Values are the number of nodes of each kind.
+	Objects int
+	Arrays  int
+	Values  int
+	// Nils is the number of object fields and array elements set to nil.
+	Nils int
+
+	// MaxDepth is the maximal depth of the tree. Array elements are one level deeper than the array.
+	// A tree with a single node has depth 0.
+	MaxDepth int
+
+	// Types is the number of objects with a specific type (see uast.KeyType).
+	// Objects without a type are counted under an empty string.
+	Types map[string]int
+	// Roles is the number of objects with a specific role (see uast.KeyRoles).
+	Roles map[role.Role]int
+	// FanOut is the number of objects and arrays with a specific number of children.
+	FanOut map[int]int
+
+	// Bytes is an estimated size of the tree in bytes. See Size for details.
+	Bytes int
+	// TypeBytes is the total estimated size of subtrees rooted at objects with a specific type.
+	// Nested subtrees of the same type are counted multiple times.
+	TypeBytes map[string]int
+	// Largest is a list of the largest subtrees in the tree, sorted by size in descending order.
+	// The number of subtrees is limited by Options.Largest.
+	Largest []Subtree
+}
+
+// Subtree contains size information for a specific subtree.
+type Subtree struct {
+	Path  nodes.Path
+	Type  string
+	Nodes int
+	Bytes int
+}
+
+// Options for computing statistics.
+type Options struct {
+	// Largest is the number of the largest subtrees to report.
+	Largest int
+}
+
+// Compute calculates statistics for a tree with default options.
+func Compute(root nodes.External) *Stats {
+	return ComputeWith(root, Options{})
+}
+
+// ComputeWith calculates statistics for a tree in a single pass.
+func ComputeWith(root nodes.External, opt Options) *Stats {
+	st := &Stats{
+		Types:     make(map[string]int),
+		Roles:     make(map[role.Role]int),
+		FanOut:    make(map[int]int),
+		TypeBytes: make(map[string]int),
+	}
+	// accumulated size of children at each depth; children are visited before parents in post-order
+	type acc struct {
+		kids, nodes, bytes int
+	}
+	var levels []acc
+
+	it := nodes.NewPathIterator(root, nodes.PostOrder)
+	for it.Next() {
+		n := it.Node()
+		depth := len(it.Path())
+		if depth > st.MaxDepth {
+			st.MaxDepth = depth
+		}
+		for len(levels) < depth+2 {
+			levels = append(levels, acc{})
+		}
+		kids := levels[depth+1]
+		levels[depth+1] = acc{}
+		sub := acc{nodes: 1 + kids.nodes, bytes: ownSize(n) + kids.bytes}
+
+		st.Nodes++
+		switch kind := nodes.KindOf(n); kind {
+		case nodes.KindObject:
+			st.Objects++
+			obj, _ := n.(nodes.ExternalObject)
+			if obj == nil {
+				break
+			}
+			st.FanOut[obj.Size()]++
+			st.Nils += obj.Size() - kids.kids
+			typ := uast.TypeOf(obj)
+			st.Types[typ]++
+			st.TypeBytes[typ] += sub.bytes
+			for _, r := range uast.RolesOf(obj) {
+				st.Roles[r]++
+			}
+			if opt.Largest > 0 {
+				st.Largest = addLargest(st.Largest, opt.Largest, Subtree{
+					Path: it.Path(), Type: typ, Nodes: sub.nodes, Bytes: sub.bytes,
+				})
+			}
+		case nodes.KindArray:
+			st.Arrays++
+			arr, _ := n.(nodes.ExternalArray)
+			if arr == nil {
+				break
+			}
+			st.FanOut[arr.Size()]++
+			st.Nils += arr.Size() - kids.kids
+		default:
+			st.Values++
+		}
+		levels[depth].kids++
+		levels[depth].nodes += sub.nodes
+		levels[depth].bytes += sub.bytes
+	}
+	if len(levels) != 0 {
+		st.Bytes = levels[0].bytes
+	}
+	return st
+}
+
+// addLargest inserts a subtree into a list sorted by size, keeping at most n elements.
+func addLargest(list []Subtree, n int, s Subtree) []Subtree {
+	i := sort.Search(len(list), func(i int) bool {
+		return list[i].Bytes < s.Bytes
+	})
+	if i >= n {
+		return list
+	}
+	if len(list) < n {
+		list = append(list, Subtree{})
+	}
+	copy(list[i+1:], list[i:])
+	list[i] = s
+	return list
+}
+
+// Size returns an estimated size of the node in bytes, including all its children.
+//
+// The size approximates the size of the serialized tree: strings and object keys
+// count their length, booleans count a single byte and other values count 8 bytes.
+// Objects and arrays have no additional overhead.
+func Size(n nodes.External) int {
+	sz := 0
+	nodes.WalkPreOrderExt(n, func(n nodes.External) bool {
+		sz += ownSize(n)
+		return true
+	})
+	return sz
+}
+
+// ownSize returns an estimated size of the node, excluding its children.
+func ownSize(n nodes.External) int {
+	switch nodes.KindOf(n) {
+	case nodes.KindNil, nodes.KindArray:
+		return 0
+	case nodes.KindObject:
+		obj, ok := n.(nodes.ExternalObject)
+		if !ok {
+			return 0
+		}
+		sz := 0
+		for _, k := range obj.Keys() {
+			sz += len(k)
+		}
+		return sz
+	case nodes.KindBool:
+		return 1
+	case nodes.KindString:
+		s, _ := n.Value().(nodes.String)
+		return len(s)
+	}
+	return 8
+}
@@ -0,0 +1,62 @@
+package stats
+
+import (
+	"testing"
+
+	"github.com/bblfsh/sdk/v3/uast"
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+	"github.com/bblfsh/sdk/v3/uast/role"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompute(t *testing.T) {
+	ident := func(name string) nodes.Object {
+		return nodes.Object{
+			uast.KeyType:  nodes.String("Ident"),
+			uast.KeyRoles: uast.RoleList(role.Identifier),
+			"Name":        nodes.String(name),
+		}
+	}
+	root := nodes.Object{
+		uast.KeyType:  nodes.String("File"),
+		uast.KeyRoles: uast.RoleList(role.File),
+		"Body": nodes.Array{
+			ident("a"),
+			ident("bc"),
+			nil,
+		},
+		"Doc": nil,
+	}
+
+	st := ComputeWith(root, Options{Largest: 2})
+	require.Equal(t, 15, st.Nodes)
+	require.Equal(t, 3, st.Objects)
+	require.Equal(t, 4, st.Arrays)
+	require.Equal(t, 8, st.Values)
+	require.Equal(t, 2, st.Nils)
+	require.Equal(t, 4, st.MaxDepth)
+	require.Equal(t, map[string]int{"File": 1, "Ident": 2}, st.Types)
+	require.Equal(t, map[role.Role]int{role.File: 1, role.Identifier: 2}, st.Roles)
+	require.Equal(t, map[int]int{1: 3, 3: 3, 4: 1}, st.FanOut)
+
+	identSize := len(uast.KeyType) + len(uast.KeyRoles) + len("Name") + len("Ident") + len("Identifier")
+	require.Equal(t, Size(root), st.Bytes)
+	require.Equal(t, 2*identSize+len("a")+len("bc"), st.TypeBytes["Ident"])
+	require.Equal(t, st.Bytes, st.TypeBytes["File"])
+
+	require.Equal(t, []Subtree{
+		{Path: nodes.Path{}, Type: "File", Nodes: 15, Bytes: st.Bytes},
+		{Path: nodes.Path{"Body", 1}, Type: "Ident", Nodes: 5, Bytes: identSize + 2},
+	}, st.Largest)
+}
+
+func TestComputeEmpty(t *testing.T) {
+	st := Compute(nil)
+	require.Equal(t, 0, st.Nodes)
+	require.Equal(t, 0, st.Bytes)
+
+	st = Compute(nodes.String("abc"))
+	require.Equal(t, 1, st.Nodes)
+	require.Equal(t, 1, st.Values)
+	require.Equal(t, 3, st.Bytes)
+}
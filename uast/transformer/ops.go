@@ -1005,14 +1005,31 @@ func TypedObj(typ string, ops map[string]Op) Op {
 // Since reversal transformation needs to build a reverse map,
 // the mapping should not be ambiguous in reverse direction (no duplicate values).
 func Lookup(op Op, m map[nodes.Value]nodes.Value) Op {
-	rev := make(map[nodes.Value]nodes.Value, len(m))
+	return LookupRev(op, m, nil)
+}
+
+// LookupRev is similar to Lookup, but allows multiple keys to be mapped to the same value.
+// For each such value, the reverse map should specify a key that will be assigned to the current node
+// in the reverse step. Values that appear in the map only once can be omitted from the reverse map.
+//
+// It panics if the mapping is ambiguous, or if the reverse map has a key that doesn't map to the value.
+func LookupRev(op Op, m, rev map[nodes.Value]nodes.Value) Op {
+	r := make(map[nodes.Value]nodes.Value, len(m))
 	for k, v := range m {
 		if _, ok := rev[v]; ok {
-			panic(ErrAmbiguousValue.New("map has ambigous value %v", v))
+			continue
+		} else if _, ok := r[v]; ok {
+			panic(ErrAmbiguousValue.New(v))
+		}
+		r[v] = k
+	}
+	for v, k := range rev {
+		if fv, ok := m[k]; !ok || fv != v {
+			panic(fmt.Errorf("reverse mapping %v -> %v doesn't match the forward mapping", v, k))
 		}
-		rev[v] = k
+		r[v] = k
 	}
-	return &opLookup{op: op, fwd: m, rev: rev}
+	return &opLookup{op: op, fwd: m, rev: r}
 }
 
 type opLookup struct {
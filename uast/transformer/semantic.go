@@ -94,6 +94,54 @@ func MapSemanticPos(nativeType string, semType interface{}, pos map[string]strin
 	return MapObj(so, UASTType(semType, do))
 }
 
+// BinaryOperatorVar converts a native operator token to a canonical uast.BinaryOperator and stores it in a given
+// variable.
+//
+// Multiple tokens can be mapped to the same canonical operator (for example, "and" and "&&"). In this case,
+// rev should specify the token that is used when the operator is converted back to the native AST.
+// The rev map can be nil if each canonical operator appears in ops only once.
+// It panics if the mapping is ambiguous. See LookupRev.
+func BinaryOperatorVar(vr string, ops map[string]uast.BinaryOperator, rev map[uast.BinaryOperator]string) Op {
+	m := make(map[nodes.Value]nodes.Value, len(ops))
+	for tok, op := range ops {
+		m[nodes.String(tok)] = nodes.String(op)
+	}
+	var r map[nodes.Value]nodes.Value
+	if len(rev) != 0 {
+		r = make(map[nodes.Value]nodes.Value, len(rev))
+		for op, tok := range rev {
+			r[nodes.String(op)] = nodes.String(tok)
+		}
+	}
+	return LookupRev(Var(vr), m, r)
+}
+
+// MapBinaryOp maps a native binary expression to uast.BinaryOp. The native node should have an operator token
+// in opField and operands in leftField and rightField. Tokens are converted to canonical operators using ops
+// and rev. See BinaryOperatorVar.
+func MapBinaryOp(nativeType, opField, leftField, rightField string, ops map[string]uast.BinaryOperator, rev map[uast.BinaryOperator]string) ObjMapping {
+	return MapSemantic(nativeType, uast.BinaryOp{}, MapObj(
+		Obj{
+			opField:    BinaryOperatorVar("op", ops, rev),
+			leftField:  Var("left"),
+			rightField: Var("right"),
+		},
+		Obj{
+			"Op":    Var("op"),
+			"Left":  Var("left"),
+			"Right": Var("right"),
+		},
+	))
+}
+
+// MapLoop is like MapSemantic, but maps a native loop to uast.Loop of a given kind.
+// Loop fields that are not set by the mapping are set to nil.
+func MapLoop(nativeType string, kind uast.LoopKind, m ObjMapping) ObjMapping {
+	so, do := m.ObjMapping()
+	do = JoinObj(do, Obj{"Kind": String(string(kind))})
+	return MapSemantic(nativeType, uast.Loop{}, MapObj(so, do))
+}
+
 func CommentText(tokens [2]string, vr string) Op {
 	return &commentUAST{
 		startToken: tokens[0],
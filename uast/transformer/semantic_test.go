@@ -3,6 +3,8 @@ package transformer
 import (
 	"testing"
 
+	"github.com/bblfsh/sdk/v3/uast"
+	"github.com/bblfsh/sdk/v3/uast/nodes"
 	"github.com/stretchr/testify/require"
 )
 
@@ -159,3 +161,120 @@ func TestComment(t *testing.T) {
 		})
 	}
 }
+
+func semPositions() nodes.Object {
+	return uast.Positions{
+		uast.KeyStart: {Offset: 0, Line: 1, Col: 1},
+		uast.KeyEnd:   {Offset: 5, Line: 1, Col: 6},
+	}.ToObject()
+}
+
+func testSemanticMapping(t *testing.T, m ObjMapping, native nodes.Object, exp interface{}) {
+	expNode, err := uast.ToNode(exp)
+	require.NoError(t, err)
+
+	out, err := Mappings(m).Do(native.CloneObject())
+	require.NoError(t, err)
+	require.Equal(t, expNode, out)
+	require.Empty(t, uast.Validate(out))
+
+	back, err := Mappings(Reverse(m)).Do(out)
+	require.NoError(t, err)
+	require.Equal(t, native, back)
+}
+
+func TestMapBinaryOp(t *testing.T) {
+	ops := map[string]uast.BinaryOperator{
+		"+":  uast.OpAdd,
+		"==": uast.OpEqual,
+	}
+	m := MapBinaryOp("BinExpr", "op", "x", "y", ops, nil)
+	testSemanticMapping(t, m, nodes.Object{
+		uast.KeyType: nodes.String("BinExpr"),
+		uast.KeyPos:  semPositions(),
+		"op":         nodes.String("=="),
+		"x":          nodes.Int(1),
+		"y":          nodes.Int(2),
+	}, uast.BinaryOp{
+		GenNode: uast.GenNode{Positions: uast.PositionsOf(nodes.Object{uast.KeyPos: semPositions()})},
+		Op:      uast.OpEqual,
+		Left:    nodes.Int(1),
+		Right:   nodes.Int(2),
+	})
+
+	_, err := Mappings(m).Do(nodes.Object{
+		uast.KeyType: nodes.String("BinExpr"),
+		uast.KeyPos:  semPositions(),
+		"op":         nodes.String("<<"),
+		"x":          nodes.Int(1),
+		"y":          nodes.Int(2),
+	})
+	require.Error(t, err)
+}
+
+func TestMapBinaryOpRev(t *testing.T) {
+	ops := map[string]uast.BinaryOperator{
+		"and": uast.OpAnd,
+		"&&":  uast.OpAnd,
+		"||":  uast.OpOr,
+	}
+	require.Panics(t, func() {
+		MapBinaryOp("BinExpr", "op", "x", "y", ops, nil)
+	})
+	require.Panics(t, func() {
+		MapBinaryOp("BinExpr", "op", "x", "y", ops, map[uast.BinaryOperator]string{uast.OpAnd: "||"})
+	})
+
+	m := MapBinaryOp("BinExpr", "op", "x", "y", ops, map[uast.BinaryOperator]string{uast.OpAnd: "&&"})
+	native := func(tok string) nodes.Object {
+		return nodes.Object{
+			uast.KeyType: nodes.String("BinExpr"),
+			uast.KeyPos:  semPositions(),
+			"op":         nodes.String(tok),
+			"x":          nodes.Bool(true),
+			"y":          nodes.Bool(false),
+		}
+	}
+	sem := uast.BinaryOp{
+		GenNode: uast.GenNode{Positions: uast.PositionsOf(nodes.Object{uast.KeyPos: semPositions()})},
+		Op:      uast.OpAnd,
+		Left:    nodes.Bool(true),
+		Right:   nodes.Bool(false),
+	}
+	testSemanticMapping(t, m, native("&&"), sem)
+
+	// both tokens are mapped to the same operator, but only one is used in reverse
+	exp, err := uast.ToNode(sem)
+	require.NoError(t, err)
+	out, err := Mappings(m).Do(native("and"))
+	require.NoError(t, err)
+	require.Equal(t, exp, out)
+
+	back, err := Mappings(Reverse(m)).Do(out)
+	require.NoError(t, err)
+	require.Equal(t, native("&&"), back)
+}
+
+func TestMapLoop(t *testing.T) {
+	m := MapLoop("While", uast.LoopWhile, MapObj(
+		Obj{
+			"test": Var("cond"),
+			"body": Var("body"),
+		},
+		Obj{
+			"Cond": Var("cond"),
+			"Body": Var("body"),
+		},
+	))
+	testSemanticMapping(t, m, nodes.Object{
+		uast.KeyType: nodes.String("While"),
+		uast.KeyPos:  semPositions(),
+		"test":       nodes.Bool(true),
+		"body":       nodes.Array{},
+	}, uast.Loop{
+		GenNode: uast.GenNode{Positions: uast.PositionsOf(nodes.Object{uast.KeyPos: semPositions()})},
+		Kind:    uast.LoopWhile,
+		Cond:    nodes.Bool(true),
+		Body:    nodes.Array{},
+	})
+}
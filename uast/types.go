@@ -3,6 +3,7 @@ package uast
 import (
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/bblfsh/sdk/v3/uast/nodes"
@@ -119,6 +120,106 @@ func LookupType(typ string) (reflect.Type, bool) {
 	return rt, ok
 }
 
+// Namespaces returns a sorted list of all registered UAST namespaces.
+func Namespaces() []string {
+	out := make([]string, 0, len(namespaces))
+	for ns := range namespaces {
+		out = append(out, ns)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// TypesOf returns a sorted list of all UAST types registered in a given namespace.
+func TypesOf(ns string) []string {
+	var out []string
+	for id := range name2type {
+		if id.NS == ns {
+			out = append(out, id.String())
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// TypeNames returns all UAST types registered in given namespaces, and the names of the corresponding Go types.
+// If no namespaces are specified, types from all registered namespaces are returned.
+//
+// It returns an error if one of the namespaces is not registered, or if two types have the same Go name,
+// thus Go names can be used to generate code for the types.
+func TypeNames(nss ...string) ([]string, map[string]string, error) {
+	if len(nss) == 0 {
+		nss = Namespaces()
+	}
+	names := make(map[string]string)
+	byName := make(map[string]string)
+	var types []string
+	for _, ns := range nss {
+		list := TypesOf(ns)
+		if len(list) == 0 {
+			return nil, nil, fmt.Errorf("namespace is not registered: %q", ns)
+		}
+		for _, typ := range list {
+			rt, _ := LookupType(typ)
+			name := rt.Name()
+			if t, ok := byName[name]; ok {
+				return nil, nil, fmt.Errorf("types %s and %s have the same name", t, typ)
+			}
+			names[typ] = name
+			byName[name] = typ
+			types = append(types, typ)
+		}
+	}
+	return types, names, nil
+}
+
+// Field describes a field of a UAST type registered via RegisterPackage.
+type Field struct {
+	// Name is a key of the field in the UAST object.
+	Name string
+	// GoName is a name of the corresponding Go struct field.
+	GoName string
+	// Type is a Go type of the field.
+	Type reflect.Type
+	// Optional is set if the field can be omitted from the UAST object.
+	Optional bool
+}
+
+// FieldsOf returns all fields of a registered UAST type, including the fields of embedded structs.
+// It returns nil for registered types that are not structs.
+func FieldsOf(typ string) ([]Field, error) {
+	rt, ok := LookupType(typ)
+	if !ok {
+		return nil, ErrTypeNotRegistered.New(typ)
+	} else if rt.Kind() != reflect.Struct {
+		return nil, nil
+	}
+	return appendFields(nil, rt)
+}
+
+func appendFields(out []Field, rt reflect.Type) ([]Field, error) {
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		if f.Anonymous {
+			var err error
+			out, err = appendFields(out, f.Type)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		d, err := getFieldDesc(f)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, Field{
+			Name: d.Name, GoName: f.Name,
+			Type: f.Type, Optional: d.OmitEmpty,
+		})
+	}
+	return out, nil
+}
+
 func zeroFieldsTo(obj, opt nodes.Object, rt reflect.Type) error {
 	for i := 0; i < rt.NumField(); i++ {
 		f := rt.Field(i)
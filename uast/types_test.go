@@ -238,3 +238,18 @@ func TestToNode(t *testing.T) {
 		})
 	}
 }
+
+func TestTypeNames(t *testing.T) {
+	types, names, err := TypeNames("uast")
+	require.NoError(t, err)
+	require.Equal(t, TypesOf("uast"), types)
+	require.Equal(t, "Identifier", names[TypeOf(Identifier{})])
+	require.Len(t, names, len(types))
+
+	all, _, err := TypeNames()
+	require.NoError(t, err)
+	require.Contains(t, all, "test:arrayNode")
+
+	_, _, err = TypeNames("missing")
+	require.Error(t, err)
+}
@@ -45,6 +45,14 @@ func init() {
 		Argument{},
 		FunctionType{},
 		Function{},
+		If{},
+		Loop{},
+		Call{},
+		Assignment{},
+		Return{},
+		BinaryOp{},
+		Literal{},
+		TypeDeclaration{},
 	)
 }
 
@@ -235,22 +243,26 @@ func RoleList(roles ...role.Role) nodes.Array {
 
 // RolesOf is a helper for getting node UAST roles (see KeyRoles).
 // The function will returns nil roles array for non-object nodes like arrays and values.
-func RolesOf(n nodes.Node) role.Roles {
-	m, ok := n.(nodes.Object)
-	if !ok {
+func RolesOf(n nodes.External) role.Roles {
+	m, ok := n.(nodes.ExternalObject)
+	if !ok || n.Kind() != nodes.KindObject {
 		return nil
 	}
-	arr, ok := m[KeyRoles].(nodes.Array)
-	if !ok || len(arr) == 0 {
+	v, _ := m.ValueAt(KeyRoles)
+	arr, ok := v.(nodes.ExternalArray)
+	if !ok || v.Kind() != nodes.KindArray || arr.Size() == 0 {
 		if tp := TypeOf(m); tp == "" || strings.HasPrefix(tp, NS+":") {
 			return nil
 		}
 		return role.Roles{role.Unannotated}
 	}
-	out := make(role.Roles, 0, len(arr))
-	for _, v := range arr {
-		if r, ok := v.(nodes.String); ok {
-			out = append(out, role.FromString(string(r)))
+	sz := arr.Size()
+	out := make(role.Roles, 0, sz)
+	for i := 0; i < sz; i++ {
+		if e := arr.ValueAt(i); e != nil {
+			if r, ok := e.Value().(nodes.String); ok {
+				out = append(out, role.FromString(string(r)))
+			}
 		}
 	}
 	return out
@@ -635,9 +647,7 @@ type Function struct {
 	Type FunctionType `json:"Type"`
 
 	// Body is an optional implementation of a function. should point to a Block with
-	// a set of statements. Each code path in those statements should end with return.
-	//
-	// TODO: we don't have return statements yet
+	// a set of statements. Each code path in those statements should end with Return.
 	Body *Block `json:"Body"`
 }
 
@@ -646,3 +656,216 @@ type Bool struct {
 	GenNode
 	Value bool `json:"Value" uast:",content"`
 }
+
+// If is a conditional statement or expression.
+//
+// What is considered an If:
+// - if-else statements;
+// - ternary conditional expressions;
+//
+// Not considered an If:
+// - switch statements and pattern matching;
+type If struct {
+	GenNode
+	// Cond is a condition expression.
+	Cond Any `json:"Cond"`
+
+	// Then is a node that is executed if the condition is true. Usually a Block for statements.
+	Then Any `json:"Then"`
+
+	// Else is an optional node that is executed if the condition is false.
+	//
+	// Chains of "else if" are represented as a nested If in this field.
+	Else Any `json:"Else"`
+}
+
+// LoopKind is a kind of a Loop. It defines which Loop fields are set.
+type LoopKind string
+
+const (
+	// LoopWhile checks the Cond before each iteration.
+	LoopWhile = LoopKind("while")
+	// LoopDoWhile checks the Cond after each iteration.
+	LoopDoWhile = LoopKind("do-while")
+	// LoopFor executes the Init once, checks the Cond before each iteration, and executes the Post after it.
+	LoopFor = LoopKind("for")
+	// LoopForEach assigns each element of the Iter to the Var and executes the Body.
+	LoopForEach = LoopKind("for-each")
+	// LoopInfinite has no condition. It can only be stopped from the Body.
+	LoopInfinite = LoopKind("infinite")
+)
+
+// Loop is a statement that executes its body repeatedly.
+//
+// Fields that are not used by a specific Kind of the loop are set to nil.
+type Loop struct {
+	GenNode
+	// Kind of the loop. See LoopKind.
+	Kind LoopKind `json:"Kind"`
+
+	// Init is executed once before the loop. Only used by LoopFor.
+	Init Any `json:"Init"`
+
+	// Cond is a condition that must be true to continue the loop.
+	Cond Any `json:"Cond"`
+
+	// Post is executed after each iteration. Only used by LoopFor.
+	Post Any `json:"Post"`
+
+	// Var is a variable (or a pattern) that receives each element of the Iter. Only used by LoopForEach.
+	Var Any `json:"Var"`
+
+	// Iter is a collection the loop iterates over. Only used by LoopForEach.
+	Iter Any `json:"Iter"`
+
+	// Body is executed on each iteration. Usually a Block.
+	Body Any `json:"Body"`
+}
+
+// Call is a function or method call.
+//
+// What is considered a Call:
+// - function and method calls;
+// - constructor calls;
+//
+// Not considered a Call:
+// - operators, even if they are overloaded (see BinaryOp);
+type Call struct {
+	GenNode
+	// Func is an expression that evaluates to a function, usually an Identifier or
+	// a QualifiedIdentifier.
+	Func Any `json:"Func"`
+
+	// Args is a list of call arguments in the order they appear in the source.
+	Args []Any `json:"Args"`
+}
+
+// Assignment is a statement that stores a value to a variable, a field or any other assignable expression.
+//
+// Not considered an Assignment:
+// - constant declarations (see Alias);
+type Assignment struct {
+	GenNode
+	// Target is an expression a value is assigned to.
+	Target Any `json:"Target"`
+
+	// Value is an assigned value.
+	Value Any `json:"Value"`
+
+	// Op is set for compound assignments. For example, "a += b" has an OpAdd operator.
+	Op BinaryOperator `json:"Op,omitempty"`
+}
+
+// Return is a statement that exits a function.
+type Return struct {
+	GenNode
+	// Values returned by the function. Empty for a return statement without values.
+	Values []Any `json:"Values"`
+}
+
+// BinaryOperator is a canonical operator of the BinaryOp.
+type BinaryOperator string
+
+// Arithmetic operators.
+const (
+	OpAdd = BinaryOperator("Add")
+	OpSub = BinaryOperator("Sub")
+	OpMul = BinaryOperator("Mul")
+	OpDiv = BinaryOperator("Div")
+	OpMod = BinaryOperator("Mod")
+	OpPow = BinaryOperator("Pow")
+)
+
+// Bitwise operators.
+const (
+	OpBitAnd     = BinaryOperator("BitAnd")
+	OpBitOr      = BinaryOperator("BitOr")
+	OpBitXor     = BinaryOperator("BitXor")
+	OpShiftLeft  = BinaryOperator("ShiftLeft")
+	OpShiftRight = BinaryOperator("ShiftRight")
+)
+
+// Boolean operators. Both of them are short-circuiting.
+const (
+	OpAnd = BinaryOperator("And")
+	OpOr  = BinaryOperator("Or")
+)
+
+// Comparison operators.
+const (
+	OpEqual        = BinaryOperator("Equal")
+	OpNotEqual     = BinaryOperator("NotEqual")
+	OpLess         = BinaryOperator("Less")
+	OpLessEqual    = BinaryOperator("LessEqual")
+	OpGreater      = BinaryOperator("Greater")
+	OpGreaterEqual = BinaryOperator("GreaterEqual")
+	// OpIdentical compares identities of the values (for example, "is" in Python or "===" in JS).
+	OpIdentical = BinaryOperator("Identical")
+	// OpNotIdentical is a negation of OpIdentical.
+	OpNotIdentical = BinaryOperator("NotIdentical")
+)
+
+// BinaryOperators returns a list of all canonical binary operators.
+func BinaryOperators() []BinaryOperator {
+	return []BinaryOperator{
+		OpAdd, OpSub, OpMul, OpDiv, OpMod, OpPow,
+		OpBitAnd, OpBitOr, OpBitXor, OpShiftLeft, OpShiftRight,
+		OpAnd, OpOr,
+		OpEqual, OpNotEqual, OpLess, OpLessEqual, OpGreater, OpGreaterEqual,
+		OpIdentical, OpNotIdentical,
+	}
+}
+
+// BinaryOp is an expression with a binary operator.
+//
+// Operators that have no canonical representation should remain in the native AST.
+type BinaryOp struct {
+	GenNode
+	// Op is a canonical operator. See BinaryOperator.
+	Op BinaryOperator `json:"Op"`
+
+	// Left is the first operand.
+	Left Any `json:"Left"`
+
+	// Right is the second operand.
+	Right Any `json:"Right"`
+}
+
+// Literal is a numeric literal.
+//
+// Not considered a Literal:
+// - string literals (see String);
+// - boolean literals (see Bool);
+type Literal struct {
+	GenNode
+	// Value is a number in a canonical form: a decimal integer ("255") or a decimal floating point
+	// number with an optional exponent ("1.5e3"). Negative literals are prefixed with "-".
+	//
+	// Drivers should remove any language-specific prefixes and suffixes, digit separators and
+	// convert the number to the decimal form.
+	Value string `json:"Value" uast:",content"`
+
+	// Format is an optional language-specific string that describes the original format of the literal,
+	// for example "hex" or "octal".
+	//
+	// This field can be empty for decimal literals.
+	Format string `json:"Format"`
+}
+
+// TypeDeclaration declares a new named type.
+//
+// What is considered a TypeDeclaration:
+// - class, struct, interface and enum declarations;
+// - type definitions and type aliases;
+type TypeDeclaration struct {
+	GenNode
+	// Name of the declared type.
+	Name Identifier `json:"Name"`
+
+	// Bases is a list of types the declared type extends or implements.
+	Bases []Any `json:"Bases"`
+
+	// Type is a definition of the type. It may point to a Block with members for classes and structs,
+	// or to an existing type for type definitions and aliases.
+	Type Any `json:"Type"`
+}
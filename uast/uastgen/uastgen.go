@@ -0,0 +1,213 @@
+// Package uastgen generates typed Go accessors for UAST types registered via uast.RegisterPackage.
+//
+// For each registered type the generator emits a type constant, a type predicate, and a view type that
+// wraps a generic nodes.Object without copying it. For example, uast:Identifier produces:
+//
+//	const TypeIdentifier = "uast:Identifier"
+//	type IdentifierView struct{ Obj nodes.Object }
+//	func IsIdentifier(n nodes.Node) bool
+//	func AsIdentifier(n nodes.Node) (IdentifierView, bool)
+//	func (v IdentifierView) Name() string
+//
+// Accessors never fail: if the field is missing, has an unexpected kind, or an integer does not fit into the Go type
+// of the field, they return a zero value. Integer fields accept both nodes.Int and nodes.Uint values.
+package uastgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"reflect"
+	"text/template"
+
+	"github.com/bblfsh/sdk/v3/uast"
+)
+
+// viewField is a name of the view struct field that stores the object.
+const viewField = "Obj"
+
+type typeDesc struct {
+	Type   string // UAST type name
+	Name   string // Go type name
+	Fields []fieldDesc
+}
+
+type fieldDesc struct {
+	Key    string // UAST field name
+	Method string
+	Result string
+	Body   string
+}
+
+var tmpl = template.Must(template.New("views").Parse(`// Code generated by bblfsh-sdk gen-types. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"github.com/bblfsh/sdk/v3/uast"
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+)
+
+// UAST types.
+const (
+{{- range .Types}}
+	Type{{.Name}} = {{printf "%q" .Type}}
+{{- end}}
+)
+{{range $t := .Types}}
+// {{.Name}}View is a typed view of a {{.Type}} node.
+type {{.Name}}View struct {
+	Obj nodes.Object
+}
+
+// Is{{.Name}} checks if the node has a {{.Type}} type.
+func Is{{.Name}}(n nodes.Node) bool {
+	_, ok := As{{.Name}}(n)
+	return ok
+}
+
+// As{{.Name}} returns a typed view of the node if it has a {{.Type}} type.
+func As{{.Name}}(n nodes.Node) ({{.Name}}View, bool) {
+	obj, ok := n.(nodes.Object)
+	if !ok || obj[uast.KeyType] != nodes.String(Type{{.Name}}) {
+		return {{.Name}}View{}, false
+	}
+	return {{.Name}}View{Obj: obj}, true
+}
+{{range .Fields}}
+// {{.Method}} returns the {{printf "%q" .Key}} field of the node.
+func (v {{$t.Name}}View) {{.Method}}() {{.Result}} {
+	{{.Body}}
+}
+{{end}}{{end}}`))
+
+// Generate writes typed views for all types registered in specified UAST namespaces to w.
+// The output is a Go source file of a given package. If no namespaces are specified,
+// types from all registered namespaces are generated.
+func Generate(w io.Writer, pkg string, namespaces ...string) error {
+	// Go type name for each UAST type
+	types, names, err := uast.TypeNames(namespaces...)
+	if err != nil {
+		return err
+	}
+	descs := make([]typeDesc, 0, len(types))
+	for _, typ := range types {
+		d, err := describeType(typ, names)
+		if err != nil {
+			return err
+		}
+		descs = append(descs, d)
+	}
+	buf := bytes.NewBuffer(nil)
+	err = tmpl.Execute(buf, struct {
+		Package string
+		Types   []typeDesc
+	}{
+		Package: pkg,
+		Types:   descs,
+	})
+	if err != nil {
+		return err
+	}
+	data, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("cannot format the code: %v", err)
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// intAccessor generates an accessor for an integer field. Integers can be stored either as nodes.Int or nodes.Uint,
+// depending on the source of the tree (for example, YAML and JSON decoders always use nodes.Int for non-negative
+// numbers). Values that do not fit into the Go type of the field are returned as zero, similar to uast.Validate.
+func intAccessor(get string, rt reflect.Type) (string, string) {
+	bits := uint(rt.Bits())
+	var (
+		result   string
+		intCond  string // condition for nodes.Int values
+		uintCond string // condition for nodes.Uint values
+	)
+	switch rt.Kind() {
+	case reflect.Int, reflect.Int64, reflect.Int32, reflect.Int16, reflect.Int8:
+		result = "int64"
+		max := uint64(1)<<(bits-1) - 1
+		if bits < 64 {
+			intCond = fmt.Sprintf("val >= %d && val <= %d", -int64(max)-1, max)
+		}
+		uintCond = fmt.Sprintf("val <= %d", max)
+	default:
+		result = "uint64"
+		intCond = "val >= 0"
+		if bits < 64 {
+			max := uint64(1)<<bits - 1
+			intCond += fmt.Sprintf(" && val <= %d", max)
+			uintCond = fmt.Sprintf("val <= %d", max)
+		}
+	}
+	ret := func(cond string) string {
+		if cond == "" {
+			return fmt.Sprintf("return %s(val)", result)
+		}
+		return fmt.Sprintf("if %s {\n\t\t\treturn %s(val)\n\t\t}", cond, result)
+	}
+	body := fmt.Sprintf("switch val := %s.(type) {\n\tcase nodes.Int:\n\t\t%s\n\tcase nodes.Uint:\n\t\t%s\n\t}\n\treturn 0",
+		get, ret(intCond), ret(uintCond))
+	return result, body
+}
+
+func describeType(typ string, names map[string]string) (typeDesc, error) {
+	fields, err := uast.FieldsOf(typ)
+	if err != nil {
+		return typeDesc{}, err
+	}
+	d := typeDesc{Type: typ, Name: names[typ]}
+	for _, f := range fields {
+		if f.GoName == viewField {
+			return typeDesc{}, fmt.Errorf("%s: field name %q conflicts with the view", typ, f.GoName)
+		}
+		result, body := accessor(f, names)
+		d.Fields = append(d.Fields, fieldDesc{
+			Key: f.Name, Method: f.GoName,
+			Result: result, Body: body,
+		})
+	}
+	return d, nil
+}
+
+// accessor returns a result type and a function body of a field accessor.
+func accessor(f uast.Field, names map[string]string) (string, string) {
+	get := fmt.Sprintf("v.Obj[%q]", f.Name)
+	rt := f.Type
+	if rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	if rt.Kind() == reflect.Struct {
+		if name, ok := names[uast.TypeOf(reflect.Zero(rt).Interface())]; ok {
+			return name + "View", fmt.Sprintf("obj, _ := %s.(nodes.Object)\n\treturn %sView{Obj: obj}", get, name)
+		}
+	}
+	var node, result string
+	switch rt.Kind() {
+	case reflect.String:
+		node, result = "nodes.String", "string"
+	case reflect.Bool:
+		node, result = "nodes.Bool", "bool"
+	case reflect.Int, reflect.Int64, reflect.Int32, reflect.Int16, reflect.Int8,
+		reflect.Uint, reflect.Uint64, reflect.Uint32, reflect.Uint16, reflect.Uint8:
+		return intAccessor(get, rt)
+	case reflect.Float32, reflect.Float64:
+		node, result = "nodes.Float", "float64"
+	case reflect.Slice, reflect.Array:
+		node, result = "nodes.Array", "nodes.Array"
+	case reflect.Map, reflect.Struct:
+		node, result = "nodes.Object", "nodes.Object"
+	default:
+		// interfaces and generic nodes
+		return "nodes.Node", "return " + get
+	}
+	if node == result {
+		return result, fmt.Sprintf("val, _ := %s.(%s)\n\treturn val", get, node)
+	}
+	return result, fmt.Sprintf("val, _ := %s.(%s)\n\treturn %s(val)", get, node, result)
+}
@@ -0,0 +1,119 @@
+package uastgen
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/bblfsh/sdk/v3/uast"
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+	"github.com/stretchr/testify/require"
+)
+
+type genNode struct {
+	uast.GenNode
+	Name    string            `json:"Name"`
+	Count   int               `json:"Count,omitempty"`
+	Size    uint32            `json:"Size"`
+	Ratio   float64           `json:"Ratio"`
+	Ok      bool              `json:"Ok"`
+	Ref     *genLeaf          `json:"Ref"`
+	Ident   uast.Identifier   `json:"Ident"`
+	Leaves  []genLeaf         `json:"Leaves"`
+	Attrs   map[string]string `json:"Attrs"`
+	Value   uast.Any          `json:"Value"`
+	Generic nodes.Node        `json:"Generic"`
+}
+
+type genLeaf struct {
+	Obj string `json:"Obj"`
+}
+
+func init() {
+	uast.RegisterPackage("gentest", genNode{}, genLeaf{})
+}
+
+var accessorCases = []struct {
+	field  string
+	result string
+	body   string
+}{
+	{field: "Name", result: "string", body: `val, _ := v.Obj["Name"].(nodes.String)
+	return string(val)`},
+	{field: "Count", result: "int64", body: `switch val := v.Obj["Count"].(type) {
+	case nodes.Int:
+		return int64(val)
+	case nodes.Uint:
+		if val <= 9223372036854775807 {
+			return int64(val)
+		}
+	}
+	return 0`},
+	{field: "Size", result: "uint64", body: `switch val := v.Obj["Size"].(type) {
+	case nodes.Int:
+		if val >= 0 && val <= 4294967295 {
+			return uint64(val)
+		}
+	case nodes.Uint:
+		if val <= 4294967295 {
+			return uint64(val)
+		}
+	}
+	return 0`},
+	{field: "Ratio", result: "float64", body: `val, _ := v.Obj["Ratio"].(nodes.Float)
+	return float64(val)`},
+	{field: "Ok", result: "bool", body: `val, _ := v.Obj["Ok"].(nodes.Bool)
+	return bool(val)`},
+	{field: "Ref", result: "genLeafView", body: `obj, _ := v.Obj["Ref"].(nodes.Object)
+	return genLeafView{Obj: obj}`},
+	{field: "Ident", result: "IdentifierView", body: `obj, _ := v.Obj["Ident"].(nodes.Object)
+	return IdentifierView{Obj: obj}`},
+	{field: "Leaves", result: "nodes.Array", body: `val, _ := v.Obj["Leaves"].(nodes.Array)
+	return val`},
+	{field: "Attrs", result: "nodes.Object", body: `val, _ := v.Obj["Attrs"].(nodes.Object)
+	return val`},
+	{field: "Value", result: "nodes.Node", body: `return v.Obj["Value"]`},
+	{field: "Generic", result: "nodes.Node", body: `return v.Obj["Generic"]`},
+}
+
+func TestAccessor(t *testing.T) {
+	names := map[string]string{
+		"gentest:genLeaf": "genLeaf",
+		"uast:Identifier": "Identifier",
+	}
+	fields, err := uast.FieldsOf("gentest:genNode")
+	require.NoError(t, err)
+	byName := make(map[string]uast.Field)
+	for _, f := range fields {
+		byName[f.GoName] = f
+	}
+	for _, c := range accessorCases {
+		t.Run(c.field, func(t *testing.T) {
+			f, ok := byName[c.field]
+			require.True(t, ok)
+			result, body := accessor(f, names)
+			require.Equal(t, c.result, result)
+			require.Equal(t, c.body, body)
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	err := Generate(buf, "views", "uast")
+	require.NoError(t, err)
+
+	_, err = parser.ParseFile(token.NewFileSet(), "views.go", buf.Bytes(), 0)
+	require.NoError(t, err)
+	require.Contains(t, buf.String(), "func (v IdentifierView) Name() string {")
+}
+
+func TestGenerateErrors(t *testing.T) {
+	err := Generate(&bytes.Buffer{}, "views", "unknown")
+	require.Error(t, err)
+
+	// genLeaf has a field that conflicts with the view field
+	err = Generate(&bytes.Buffer{}, "views", "gentest")
+	require.Error(t, err)
+}
@@ -0,0 +1,110 @@
+package uastschema
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/bblfsh/sdk/v3/uast"
+)
+
+// protoAny is a protobuf type used for fields that can hold any UAST node.
+const protoAny = "google.protobuf.Value"
+
+// WriteProto writes a proto3 definition of all types registered in specified UAST namespaces.
+// If no namespaces are specified, types from all registered namespaces are included.
+//
+// Each struct type is described by a message with the same name. The first field of each message stores the
+// UAST type (uast.KeyType); other fields are numbered in the order of Go struct fields and use UAST field names
+// as JSON names. Fields that can hold any UAST node (uast.Any) use google.protobuf.Value, and registered map
+// types (such as uast.Positions) use google.protobuf.Struct.
+func WriteProto(w io.Writer, pkg string, namespaces ...string) error {
+	types, names, err := uast.TypeNames(namespaces...)
+	if err != nil {
+		return err
+	}
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString("// Code generated by bblfsh-sdk gen-schema. DO NOT EDIT.\n\n")
+	buf.WriteString("syntax = \"proto3\";\n\n")
+	fmt.Fprintf(buf, "package %s;\n\n", pkg)
+	buf.WriteString("import \"google/protobuf/struct.proto\";\n")
+	for _, typ := range types {
+		rt, _ := uast.LookupType(typ)
+		if rt.Kind() != reflect.Struct {
+			continue
+		}
+		fields, err := uast.FieldsOf(typ)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(buf, "\n// %s\nmessage %s {\n", typ, names[typ])
+		fmt.Fprintf(buf, "\tstring uast_type = 1 [json_name = %q];\n", uast.KeyType)
+		for i, f := range fields {
+			fmt.Fprintf(buf, "\t%s %s = %d [json_name = %q];\n",
+				protoType(f.Type, names), snakeCase(f.GoName), i+2, f.Name)
+		}
+		buf.WriteString("}\n")
+	}
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+func protoType(rt reflect.Type, names map[string]string) string {
+	if rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	if typ, ok := registeredType(rt, names); ok {
+		if rt.Kind() == reflect.Map {
+			return "google.protobuf.Struct"
+		}
+		return names[typ]
+	}
+	switch rt.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int32, reflect.Int16, reflect.Int8:
+		return "int32"
+	case reflect.Int, reflect.Int64:
+		return "int64"
+	case reflect.Uint32, reflect.Uint16, reflect.Uint8:
+		return "uint32"
+	case reflect.Uint, reflect.Uint64:
+		return "uint64"
+	case reflect.Float32:
+		return "float"
+	case reflect.Float64:
+		return "double"
+	case reflect.Slice, reflect.Array:
+		elem := rt.Elem()
+		if k := elem.Kind(); k == reflect.Slice || k == reflect.Array {
+			// nested repeated fields are not supported
+			return "repeated " + protoAny
+		}
+		return "repeated " + protoType(elem, names)
+	case reflect.Map:
+		return "google.protobuf.Struct"
+	}
+	return protoAny
+}
+
+// snakeCase converts a Go field name to a protobuf field name.
+func snakeCase(s string) string {
+	var buf strings.Builder
+	rs := []rune(s)
+	for i, r := range rs {
+		if unicode.IsUpper(r) {
+			// start a new word, unless it's an abbreviation
+			if i != 0 && (unicode.IsLower(rs[i-1]) || (i+1 < len(rs) && unicode.IsLower(rs[i+1]))) {
+				buf.WriteRune('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
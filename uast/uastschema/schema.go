@@ -0,0 +1,154 @@
+// Package uastschema exports a machine-readable description of UAST types registered via uast.RegisterPackage.
+//
+// The package can generate a JSON Schema (draft-07) and a Protocol Buffers (proto3) definition of the registered
+// types. Both are derived from Go structs using the same rules as uast.ToNode.
+package uastschema
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+
+	"github.com/bblfsh/sdk/v3/uast"
+)
+
+// SchemaVersion is the JSON Schema version used by the generator.
+const SchemaVersion = "http://json-schema.org/draft-07/schema#"
+
+// Schema is a subset of JSON Schema used to describe UAST types.
+//
+// An empty schema matches any value.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Const                string             `json:"const,omitempty"`
+	Minimum              *int               `json:"minimum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+	Definitions          map[string]*Schema `json:"definitions,omitempty"`
+}
+
+// registeredType returns a UAST type name of a Go type. It returns false if the type is not one of the given types.
+func registeredType(rt reflect.Type, names map[string]string) (string, bool) {
+	if rt.Kind() != reflect.Struct && rt.Kind() != reflect.Map {
+		return "", false
+	}
+	typ := uast.TypeOf(reflect.Zero(rt).Interface())
+	_, ok := names[typ]
+	return typ, ok
+}
+
+// JSONSchema generates a JSON Schema for all types registered in specified UAST namespaces.
+// If no namespaces are specified, types from all registered namespaces are included.
+//
+// Each UAST type is described in the definitions section of the schema under its full name (for example,
+// "uast:Identifier"). Fields that can hold any UAST node (uast.Any) are described by a schema without constraints.
+func JSONSchema(namespaces ...string) (*Schema, error) {
+	types, names, err := uast.TypeNames(namespaces...)
+	if err != nil {
+		return nil, err
+	}
+	s := &Schema{
+		Schema:      SchemaVersion,
+		Definitions: make(map[string]*Schema, len(types)),
+	}
+	for _, typ := range types {
+		d, err := typeSchema(typ, names)
+		if err != nil {
+			return nil, err
+		}
+		s.Definitions[typ] = d
+	}
+	return s, nil
+}
+
+// WriteJSONSchema writes a JSON Schema for all types registered in specified UAST namespaces. See JSONSchema.
+func WriteJSONSchema(w io.Writer, namespaces ...string) error {
+	s, err := JSONSchema(namespaces...)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+func typeSchema(typ string, names map[string]string) (*Schema, error) {
+	rt, _ := uast.LookupType(typ)
+	s := &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			uast.KeyType: {Const: typ},
+		},
+		Required: []string{uast.KeyType},
+	}
+	if rt.Kind() == reflect.Map {
+		s.AdditionalProperties = fieldSchema(rt.Elem(), names)
+		return s, nil
+	}
+	fields, err := uast.FieldsOf(typ)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range fields {
+		s.Properties[f.Name] = fieldSchema(f.Type, names)
+		if !f.Optional {
+			s.Required = append(s.Required, f.Name)
+		}
+	}
+	return s, nil
+}
+
+func refTo(typ string) *Schema {
+	return &Schema{Ref: "#/definitions/" + typ}
+}
+
+// fieldSchema returns a schema for a field of a given Go type. Pointers, structs, maps and slices also accept null,
+// the same way as uast.NodeAs and uast.Validate do.
+func fieldSchema(rt reflect.Type, names map[string]string) *Schema {
+	nullable := false
+	if rt.Kind() == reflect.Ptr {
+		rt, nullable = rt.Elem(), true
+	}
+	s := valueSchema(rt, names)
+	switch rt.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		nullable = true
+	}
+	if !nullable {
+		return s
+	}
+	return &Schema{OneOf: []*Schema{s, {Type: "null"}}}
+}
+
+func valueSchema(rt reflect.Type, names map[string]string) *Schema {
+	if typ, ok := registeredType(rt, names); ok {
+		return refTo(typ)
+	}
+	switch rt.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64, reflect.Int32, reflect.Int16, reflect.Int8:
+		return &Schema{Type: "integer"}
+	case reflect.Uint, reflect.Uint64, reflect.Uint32, reflect.Uint16, reflect.Uint8:
+		min := 0
+		return &Schema{Type: "integer", Minimum: &min}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: fieldSchema(rt.Elem(), names)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: fieldSchema(rt.Elem(), names)}
+	case reflect.Interface:
+		return &Schema{Description: "any UAST node"}
+	}
+	// types from other namespaces
+	return &Schema{}
+}
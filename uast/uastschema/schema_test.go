@@ -0,0 +1,190 @@
+package uastschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bblfsh/sdk/v3/uast"
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+)
+
+func TestJSONSchema(t *testing.T) {
+	s, err := JSONSchema("uast")
+	require.NoError(t, err)
+	require.Equal(t, SchemaVersion, s.Schema)
+
+	data, err := json.Marshal(s.Definitions["uast:Identifier"])
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+	"type": "object",
+	"properties": {
+		"@type": {"const": "uast:Identifier"},
+		"@pos": {"oneOf": [{"$ref": "#/definitions/uast:Positions"}, {"type": "null"}]},
+		"Name": {"type": "string"}
+	},
+	"required": ["@type", "Name"]
+}`, string(data))
+
+	data, err = json.Marshal(s.Definitions["uast:Positions"])
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+	"type": "object",
+	"properties": {
+		"@type": {"const": "uast:Positions"}
+	},
+	"required": ["@type"],
+	"additionalProperties": {"oneOf": [{"$ref": "#/definitions/uast:Position"}, {"type": "null"}]}
+}`, string(data))
+
+	arg := s.Definitions["uast:Argument"].Properties
+	data, err = json.Marshal(arg["Name"])
+	require.NoError(t, err)
+	require.JSONEq(t, `{"oneOf": [{"$ref": "#/definitions/uast:Identifier"}, {"type": "null"}]}`, string(data))
+
+	data, err = json.Marshal(arg["Init"])
+	require.NoError(t, err)
+	require.JSONEq(t, `{"description": "any UAST node"}`, string(data))
+
+	data, err = json.Marshal(s.Definitions["uast:Position"].Properties["line"])
+	require.NoError(t, err)
+	require.JSONEq(t, `{"type": "integer", "minimum": 0}`, string(data))
+
+	_, err = JSONSchema("unknown")
+	require.Error(t, err)
+}
+
+// match checks a node against the subset of JSON Schema generated by JSONSchema.
+func match(s *Schema, defs map[string]*Schema, n nodes.Node) error {
+	if s.Ref != "" {
+		return match(defs[strings.TrimPrefix(s.Ref, "#/definitions/")], defs, n)
+	}
+	if len(s.OneOf) != 0 {
+		cnt := 0
+		for _, sub := range s.OneOf {
+			if match(sub, defs, n) == nil {
+				cnt++
+			}
+		}
+		if cnt != 1 {
+			return fmt.Errorf("%d of %d schemas match %v", cnt, len(s.OneOf), n)
+		}
+	}
+	if s.Const != "" && n != nodes.String(s.Const) {
+		return fmt.Errorf("expected %q, got %v", s.Const, n)
+	}
+	ok := true
+	switch s.Type {
+	case "null":
+		ok = n == nil
+	case "string":
+		_, ok = n.(nodes.String)
+	case "boolean":
+		_, ok = n.(nodes.Bool)
+	case "integer":
+		switch n := n.(type) {
+		case nodes.Int:
+			ok = s.Minimum == nil || int64(n) >= int64(*s.Minimum)
+		case nodes.Uint:
+		default:
+			ok = false
+		}
+	case "number":
+		switch n.(type) {
+		case nodes.Int, nodes.Uint, nodes.Float:
+		default:
+			ok = false
+		}
+	case "array":
+		var arr nodes.Array
+		if arr, ok = n.(nodes.Array); ok && s.Items != nil {
+			for _, sub := range arr {
+				if err := match(s.Items, defs, sub); err != nil {
+					return err
+				}
+			}
+		}
+	case "object":
+		var obj nodes.Object
+		if obj, ok = n.(nodes.Object); !ok {
+			break
+		}
+		for _, k := range s.Required {
+			if _, ok := obj[k]; !ok {
+				return fmt.Errorf("missing field %q", k)
+			}
+		}
+		for k, v := range obj {
+			sub, ok := s.Properties[k]
+			if !ok {
+				sub = s.AdditionalProperties
+			}
+			if sub == nil {
+				continue
+			}
+			if err := match(sub, defs, v); err != nil {
+				return fmt.Errorf("%s: %v", k, err)
+			}
+		}
+	}
+	if !ok {
+		return fmt.Errorf("expected %s, got %v", s.Type, nodes.KindOf(n))
+	}
+	return nil
+}
+
+func TestJSONSchemaMatch(t *testing.T) {
+	s, err := JSONSchema("uast")
+	require.NoError(t, err)
+
+	for _, typ := range uast.TypesOf("uast") {
+		obj := uast.NewObjectByType(typ)
+		require.NoError(t, match(s.Definitions[typ], s.Definitions, obj), typ)
+
+		// null fields match the schema if they are accepted by uast.Validate
+		for k := range obj {
+			if k == uast.KeyType {
+				continue
+			}
+			sub := obj.CloneObject()
+			sub[k] = nil
+			err = match(s.Definitions[typ], s.Definitions, sub)
+			if len(uast.Validate(sub)) == 0 {
+				require.NoError(t, err, "%s: %s", typ, k)
+			} else {
+				require.Error(t, err, "%s: %s", typ, k)
+			}
+		}
+	}
+}
+
+func TestWriteProto(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	err := WriteProto(buf, "uast", "uast")
+	require.NoError(t, err)
+	require.Contains(t, buf.String(), `
+// uast:FunctionType
+message FunctionType {
+	string uast_type = 1 [json_name = "@type"];
+	google.protobuf.Struct positions = 2 [json_name = "@pos"];
+	repeated Argument arguments = 3 [json_name = "Arguments"];
+	repeated Argument returns = 4 [json_name = "Returns"];
+}
+`)
+	require.NotContains(t, buf.String(), "message Positions")
+}
+
+func TestSnakeCase(t *testing.T) {
+	for in, exp := range map[string]string{
+		"Name":        "name",
+		"MapVariadic": "map_variadic",
+		"NodeID":      "node_id",
+		"HTTPServer":  "http_server",
+	} {
+		require.Equal(t, exp, snakeCase(in), in)
+	}
+}
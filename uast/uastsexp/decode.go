@@ -0,0 +1,323 @@
+package uastsexp
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/bblfsh/sdk/v3/uast"
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+)
+
+// maxDepth is the maximal nesting depth accepted by the parser.
+const maxDepth = 10000
+
+// Unmarshal parses an S-expression to a UAST.
+func Unmarshal(data []byte) (nodes.Node, error) {
+	p := &parser{data: data, line: 1, col: 1}
+	p.skipSpace()
+	if p.eof() {
+		return nil, p.errorf("unexpected end of input")
+	}
+	n, err := p.parseValue("", 0)
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if !p.eof() {
+		return nil, p.errorf("unexpected data after the value")
+	}
+	return n, nil
+}
+
+type parser struct {
+	data      []byte
+	off       int
+	line, col int
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %d, col %d: %s", p.line, p.col, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) eof() bool {
+	return p.off >= len(p.data)
+}
+
+func (p *parser) peek() byte {
+	return p.data[p.off]
+}
+
+func (p *parser) advance() {
+	if p.data[p.off] == '\n' {
+		p.line++
+		p.col = 0
+	}
+	p.off++
+	p.col++
+}
+
+// skipSpace skips whitespace and comments.
+func (p *parser) skipSpace() {
+	for !p.eof() {
+		switch c := p.peek(); {
+		case c == ';':
+			for !p.eof() && p.peek() != '\n' {
+				p.advance()
+			}
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			p.advance()
+		default:
+			return
+		}
+	}
+}
+
+// expect skips the whitespace and consumes a given character.
+func (p *parser) expect(c byte) error {
+	p.skipSpace()
+	if p.eof() {
+		return p.errorf("expected %q, got end of input", c)
+	} else if p.peek() != c {
+		return p.errorf("expected %q, got %q", c, p.peek())
+	}
+	p.advance()
+	return nil
+}
+
+// parseAtom reads an unquoted token.
+func (p *parser) parseAtom() string {
+	start := p.off
+	for !p.eof() && strings.IndexByte(delims, p.peek()) < 0 {
+		p.advance()
+	}
+	return string(p.data[start:p.off])
+}
+
+// parseString reads a quoted string.
+func (p *parser) parseString() (string, error) {
+	start := p.off
+	line, col := p.line, p.col
+	p.advance()
+	for {
+		if p.eof() {
+			p.line, p.col = line, col
+			return "", p.errorf("unterminated string")
+		}
+		c := p.peek()
+		p.advance()
+		if c == '"' {
+			break
+		} else if c == '\\' && !p.eof() {
+			p.advance()
+		}
+	}
+	s, err := strconv.Unquote(string(p.data[start:p.off]))
+	if err != nil {
+		p.line, p.col = line, col
+		return "", p.errorf("invalid string: %v", err)
+	}
+	return s, nil
+}
+
+// parseName reads a type name or a key, that can be either quoted or bare.
+func (p *parser) parseName() (string, error) {
+	p.skipSpace()
+	if p.eof() {
+		return "", p.errorf("unexpected end of input")
+	}
+	if p.peek() == '"' {
+		return p.parseString()
+	}
+	s := p.parseAtom()
+	if s == "" {
+		return "", p.errorf("unexpected character %q", p.peek())
+	}
+	return s, nil
+}
+
+// parseValue parses a value for a given object key. The key is used to parse positions and roles.
+func (p *parser) parseValue(key string, depth int) (nodes.Node, error) {
+	if depth > maxDepth {
+		return nil, p.errorf("maximum nesting depth exceeded")
+	}
+	p.skipSpace()
+	if p.eof() {
+		return nil, p.errorf("unexpected end of input")
+	}
+	switch p.peek() {
+	case '(':
+		p.advance()
+		tp, err := p.parseName()
+		if err != nil {
+			return nil, err
+		}
+		obj := nodes.Object{uast.KeyType: nodes.String(tp)}
+		return obj, p.parseFields(obj, ')', depth)
+	case '{':
+		p.advance()
+		obj := nodes.Object{}
+		return obj, p.parseFields(obj, '}', depth)
+	case '[':
+		p.advance()
+		arr := nodes.Array{}
+		for {
+			p.skipSpace()
+			if !p.eof() && p.peek() == ']' {
+				p.advance()
+				return arr, nil
+			}
+			v, err := p.parseValue("", depth+1)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+	case '"':
+		s, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		return nodes.String(s), nil
+	}
+	line, col := p.line, p.col
+	s := p.parseAtom()
+	if s == "" {
+		return nil, p.errorf("unexpected character %q", p.peek())
+	}
+	v, err := parseAtomValue(key, s)
+	if err != nil {
+		p.line, p.col = line, col
+		return nil, p.errorf("%v", err)
+	}
+	return v, nil
+}
+
+// parseFields reads key-value pairs of an object until the closing character.
+func (p *parser) parseFields(obj nodes.Object, end byte, depth int) error {
+	for {
+		p.skipSpace()
+		if p.eof() {
+			return p.errorf("expected %q, got end of input", end)
+		} else if p.peek() == end {
+			p.advance()
+			return nil
+		}
+		line, col := p.line, p.col
+		k, err := p.parseName()
+		if err != nil {
+			return err
+		}
+		if _, ok := obj[k]; ok {
+			p.line, p.col = line, col
+			return p.errorf("duplicate key %q", k)
+		}
+		if err = p.expect('='); err != nil {
+			return err
+		}
+		v, err := p.parseValue(k, depth+1)
+		if err != nil {
+			return err
+		}
+		obj[k] = v
+	}
+}
+
+// parseAtomValue converts an unquoted token to a value. Positions and roles are parsed for corresponding keys.
+func parseAtomValue(key, s string) (nodes.Node, error) {
+	switch s {
+	case "nil":
+		return nil, nil
+	case "true":
+		return nodes.Bool(true), nil
+	case "false":
+		return nodes.Bool(false), nil
+	case "NaN":
+		return nodes.Float(math.NaN()), nil
+	case "+Inf":
+		return nodes.Float(math.Inf(1)), nil
+	case "-Inf":
+		return nodes.Float(math.Inf(-1)), nil
+	}
+	switch {
+	case key == uast.KeyPos && strings.Contains(s, ":"):
+		return parsePositions(s)
+	case key == uast.KeyRoles && isRoleName(strings.SplitN(s, ",", 2)[0]):
+		names := strings.Split(s, ",")
+		arr := make(nodes.Array, 0, len(names))
+		for _, r := range names {
+			if !isRoleName(r) {
+				return nil, fmt.Errorf("invalid role name: %q", r)
+			}
+			arr = append(arr, nodes.String(r))
+		}
+		return arr, nil
+	case strings.HasSuffix(s, "u"):
+		v, err := strconv.ParseUint(s[:len(s)-1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid uint: %q", s)
+		}
+		return nodes.Uint(v), nil
+	case strings.ContainsAny(s, ".eE"):
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid float: %q", s)
+		}
+		return nodes.Float(v), nil
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected token: %q", s)
+	}
+	return nodes.Int(v), nil
+}
+
+// parsePositions parses a compact representation of uast.Positions.
+func parsePositions(s string) (nodes.Node, error) {
+	i := strings.IndexByte(s, '-')
+	if i < 0 {
+		return nil, fmt.Errorf("invalid positions: %q", s)
+	}
+	obj := nodes.Object{uast.KeyType: nodes.String(uast.TypePositions)}
+	for _, p := range []struct {
+		key string
+		val string
+	}{
+		{key: uast.KeyStart, val: s[:i]},
+		{key: uast.KeyEnd, val: s[i+1:]},
+	} {
+		if p.val == "" {
+			continue
+		}
+		pos, err := parsePosition(p.val)
+		if err != nil {
+			return nil, err
+		}
+		obj[p.key] = pos
+	}
+	return obj, nil
+}
+
+// parsePosition parses a line:col#offset representation of uast.Position.
+func parsePosition(s string) (nodes.Node, error) {
+	i := strings.IndexByte(s, ':')
+	j := strings.IndexByte(s, '#')
+	if i < 0 || j < i {
+		return nil, fmt.Errorf("invalid position: %q", s)
+	}
+	var vals [3]nodes.Uint
+	for k, v := range []string{s[:i], s[i+1 : j], s[j+1:]} {
+		u, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid position: %q", s)
+		}
+		vals[k] = nodes.Uint(u)
+	}
+	return nodes.Object{
+		uast.KeyType:    nodes.String(uast.TypePosition),
+		uast.KeyPosLine: vals[0],
+		uast.KeyPosCol:  vals[1],
+		uast.KeyPosOff:  vals[2],
+	}, nil
+}
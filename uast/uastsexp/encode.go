@@ -0,0 +1,350 @@
+// Package uastsexp implements a compact S-expression format for UAST.
+//
+// Objects with a string type are written as a list with the type as the first element followed by
+// key-value pairs, for example:
+//
+//	(uast:Identifier @pos=1:3#2-1:7#6 @role=Expression,Identifier Name="x")
+//
+// Other objects are written as {key=value ...} and arrays are written as [value ...].
+// Strings are always quoted, Uint values have an "u" suffix, Float values always have a fractional part
+// or an exponent, and nil is written as "nil". Positions (see uast.Positions) are written as
+// line:col#offset pairs separated by "-", and roles are written as a comma-separated list.
+// Line comments start with ";".
+//
+// The format preserves all the information of the tree, thus parsing the output of the Encoder returns
+// exactly the same tree.
+package uastsexp
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/bblfsh/sdk/v3/uast"
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+)
+
+const (
+	defaultWidth = 80
+	indentStep   = 2
+	// delims is a set of characters that cannot appear in bare atoms.
+	delims = " \t\r\n()[]{}\"=;"
+)
+
+// Marshal encodes the UAST to an S-expression.
+func Marshal(n nodes.External) ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	enc := NewEncoder(buf)
+	if err := enc.Encode(n); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// NewEncoder creates an S-expression encoder for UAST.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: bufio.NewWriter(w), width: defaultWidth}
+}
+
+// Encoder writes UAST as S-expressions. Each tree is followed by a newline.
+type Encoder struct {
+	w     *bufio.Writer
+	width int
+	err   error
+}
+
+// SetWidth sets the maximal width of the line. Nodes that don't fit into a line are split into multiple lines.
+// The width of zero disables line wrapping. The default width is 80.
+func (enc *Encoder) SetWidth(width int) {
+	enc.width = width
+}
+
+// Encode writes the UAST to the stream. It accepts both generic nodes and external node implementations.
+func (enc *Encoder) Encode(n nodes.External) error {
+	nd, err := nodes.ToNode(n, nil)
+	if err != nil {
+		return err
+	}
+	enc.print(nd, 0, 0)
+	enc.writeString("\n")
+	if enc.err != nil {
+		return enc.err
+	}
+	return enc.w.Flush()
+}
+
+// print writes the node that starts at a given column. If the node doesn't fit into the line, each of its children
+// is written on a separate line with the indentation relative to the specified indent.
+func (enc *Encoder) print(n nodes.Node, col, indent int) {
+	if enc.err != nil {
+		return
+	}
+	limit := -1
+	if enc.width > 0 {
+		limit = enc.width - col
+	}
+	if s, ok := flat(n, limit); ok {
+		enc.writeString(s)
+		return
+	}
+	switch n := n.(type) {
+	case nodes.Object:
+		open, close := "{", "}"
+		tp, typed := n[uast.KeyType].(nodes.String)
+		keys := n.Keys()
+		if typed {
+			open, close = "("+formatAtom(string(tp)), ")"
+			keys = removeKey(keys, uast.KeyType)
+		}
+		enc.writeString(open)
+		for _, k := range keys {
+			enc.newline(indent + indentStep)
+			key := formatAtom(k) + "="
+			enc.writeString(key)
+			if s, ok := formatSpecial(k, n[k]); ok {
+				enc.writeString(s)
+				continue
+			}
+			enc.print(n[k], indent+indentStep+len(key), indent+indentStep)
+		}
+		enc.writeString(close)
+	case nodes.Array:
+		enc.writeString("[")
+		for _, v := range n {
+			enc.newline(indent + indentStep)
+			enc.print(v, indent+indentStep, indent+indentStep)
+		}
+		enc.writeString("]")
+	default:
+		// values always fit
+		s, _ := flat(n, -1)
+		enc.writeString(s)
+	}
+}
+
+func (enc *Encoder) newline(indent int) {
+	enc.writeString("\n")
+	enc.writeString(strings.Repeat(" ", indent))
+}
+
+func (enc *Encoder) writeString(s string) {
+	if enc.err != nil {
+		return
+	}
+	_, enc.err = enc.w.WriteString(s)
+}
+
+func removeKey(keys []string, key string) []string {
+	for i, k := range keys {
+		if k == key {
+			return append(keys[:i:i], keys[i+1:]...)
+		}
+	}
+	return keys
+}
+
+// flatWriter accumulates a single-line representation of the node. It stops when the limit is reached.
+type flatWriter struct {
+	buf   []byte
+	limit int
+}
+
+func (w *flatWriter) full() bool {
+	return w.limit >= 0 && len(w.buf) > w.limit
+}
+
+func (w *flatWriter) writeString(s string) {
+	w.buf = append(w.buf, s...)
+}
+
+// flat returns a single-line representation of the node. It returns false if the node doesn't fit into the limit.
+// Negative limit means no limit.
+func flat(n nodes.Node, limit int) (string, bool) {
+	w := &flatWriter{limit: limit}
+	w.write(n)
+	if w.full() {
+		return "", false
+	}
+	return string(w.buf), true
+}
+
+func (w *flatWriter) write(n nodes.Node) {
+	if w.full() {
+		return
+	}
+	switch n := n.(type) {
+	case nodes.Object:
+		tp, typed := n[uast.KeyType].(nodes.String)
+		keys := n.Keys()
+		if typed {
+			w.writeString("(" + formatAtom(string(tp)))
+			keys = removeKey(keys, uast.KeyType)
+		} else {
+			w.writeString("{")
+		}
+		for i, k := range keys {
+			if w.full() {
+				return
+			}
+			if i != 0 || typed {
+				w.writeString(" ")
+			}
+			w.writeString(formatAtom(k) + "=")
+			if s, ok := formatSpecial(k, n[k]); ok {
+				w.writeString(s)
+				continue
+			}
+			w.write(n[k])
+		}
+		if typed {
+			w.writeString(")")
+		} else {
+			w.writeString("}")
+		}
+	case nodes.Array:
+		w.writeString("[")
+		for i, v := range n {
+			if w.full() {
+				return
+			}
+			if i != 0 {
+				w.writeString(" ")
+			}
+			w.write(v)
+		}
+		w.writeString("]")
+	case nil:
+		w.writeString("nil")
+	case nodes.String:
+		w.writeString(strconv.Quote(string(n)))
+	case nodes.Int:
+		w.writeString(strconv.FormatInt(int64(n), 10))
+	case nodes.Uint:
+		w.writeString(strconv.FormatUint(uint64(n), 10) + "u")
+	case nodes.Float:
+		w.writeString(formatFloat(float64(n)))
+	case nodes.Bool:
+		w.writeString(strconv.FormatBool(bool(n)))
+	}
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
+
+// isAtom checks if a string can be written without quotes.
+func isAtom(s string) bool {
+	return s != "" && !strings.ContainsAny(s, delims)
+}
+
+// formatAtom writes the string without quotes if possible.
+func formatAtom(s string) string {
+	if isAtom(s) {
+		return s
+	}
+	return strconv.Quote(s)
+}
+
+// formatSpecial returns a compact representation of positions and roles.
+func formatSpecial(key string, v nodes.Node) (string, bool) {
+	switch key {
+	case uast.KeyPos:
+		return formatPositions(v)
+	case uast.KeyRoles:
+		return formatRoles(v)
+	}
+	return "", false
+}
+
+// isRoleName checks if the string can be written as a part of a compact roles list.
+func isRoleName(s string) bool {
+	switch s {
+	case "", "nil", "true", "false", "NaN":
+		// keywords
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case r >= '0' && r <= '9' && i != 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func formatRoles(v nodes.Node) (string, bool) {
+	arr, ok := v.(nodes.Array)
+	if !ok || len(arr) == 0 {
+		return "", false
+	}
+	names := make([]string, 0, len(arr))
+	for _, r := range arr {
+		s, ok := r.(nodes.String)
+		if !ok || !isRoleName(string(s)) {
+			return "", false
+		}
+		names = append(names, string(s))
+	}
+	return strings.Join(names, ","), true
+}
+
+func formatPositions(v nodes.Node) (string, bool) {
+	obj, ok := v.(nodes.Object)
+	if !ok || obj[uast.KeyType] != nodes.String(uast.TypePositions) {
+		return "", false
+	}
+	var start, end string
+	for k, p := range obj {
+		switch k {
+		case uast.KeyType:
+		case uast.KeyStart:
+			if start, ok = formatPosition(p); !ok {
+				return "", false
+			}
+		case uast.KeyEnd:
+			if end, ok = formatPosition(p); !ok {
+				return "", false
+			}
+		default:
+			return "", false
+		}
+	}
+	if start == "" && end == "" {
+		return "", false
+	}
+	return start + "-" + end, true
+}
+
+func formatPosition(v nodes.Node) (string, bool) {
+	obj, ok := v.(nodes.Object)
+	if !ok || len(obj) != 4 || obj[uast.KeyType] != nodes.String(uast.TypePosition) {
+		return "", false
+	}
+	var vals [3]uint64
+	for i, k := range []string{uast.KeyPosLine, uast.KeyPosCol, uast.KeyPosOff} {
+		u, ok := obj[k].(nodes.Uint)
+		if !ok {
+			return "", false
+		}
+		vals[i] = uint64(u)
+	}
+	return strconv.FormatUint(vals[0], 10) + ":" + strconv.FormatUint(vals[1], 10) +
+		"#" + strconv.FormatUint(vals[2], 10), true
+}
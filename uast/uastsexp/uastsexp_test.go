@@ -0,0 +1,171 @@
+package uastsexp
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/bblfsh/sdk/v3/uast"
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+	"github.com/bblfsh/sdk/v3/uast/role"
+	"github.com/stretchr/testify/require"
+)
+
+func pos(line, col, off uint32) uast.Position {
+	return uast.Position{Line: line, Col: col, Offset: off}
+}
+
+func ident(name string, start, end uast.Position) nodes.Object {
+	return nodes.Object{
+		uast.KeyType:  nodes.String("uast:Identifier"),
+		uast.KeyPos:   uast.Positions{uast.KeyStart: start, uast.KeyEnd: end}.ToObject(),
+		uast.KeyRoles: uast.RoleList(role.Expression, role.Identifier),
+		"Name":        nodes.String(name),
+	}
+}
+
+var sexpCases = []struct {
+	name string
+	in   nodes.Node
+	out  string
+}{
+	{name: "nil", out: "nil"},
+	{name: "string", in: nodes.String("a \"b\"\n"), out: `"a \"b\"\n"`},
+	{name: "int", in: nodes.Int(-5), out: "-5"},
+	{name: "uint", in: nodes.Uint(5), out: "5u"},
+	{name: "float", in: nodes.Float(5), out: "5.0"},
+	{name: "float exp", in: nodes.Float(1e100), out: "1e+100"},
+	{name: "inf", in: nodes.Float(math.Inf(-1)), out: "-Inf"},
+	{name: "bool", in: nodes.Bool(true), out: "true"},
+	{name: "empty object", in: nodes.Object{}, out: "{}"},
+	{name: "empty array", in: nodes.Array{}, out: "[]"},
+	{
+		name: "identifier",
+		in:   ident("x", pos(1, 3, 2), pos(1, 7, 6)),
+		out:  `(uast:Identifier @pos=1:3#2-1:7#6 @role=Expression,Identifier Name="x")`,
+	},
+	{
+		name: "untyped object",
+		in: nodes.Object{
+			"a b":         nodes.Array{nodes.Int(1), nil},
+			"c":           nodes.Object{uast.KeyType: nodes.Int(1)},
+			uast.KeyRoles: nodes.Array{nodes.String("not a role")},
+		},
+		out: `{@role=["not a role"] "a b"=[1 nil] c={@type=1}}`,
+	},
+	{
+		name: "quoted type",
+		in:   nodes.Object{uast.KeyType: nodes.String("C++ node")},
+		out:  `("C++ node")`,
+	},
+	{
+		name: "partial positions",
+		in: nodes.Object{
+			uast.KeyType: nodes.String("node"),
+			uast.KeyPos:  uast.Positions{uast.KeyStart: pos(1, 1, 0)}.ToObject(),
+		},
+		out: `(node @pos=1:1#0-)`,
+	},
+	{
+		name: "custom positions",
+		in: nodes.Object{
+			uast.KeyType: nodes.String("node"),
+			uast.KeyPos: nodes.Object{
+				uast.KeyType: nodes.String(uast.TypePositions),
+				"block":      pos(1, 1, 0).ToObject(),
+			},
+		},
+		out: `(node @pos=(uast:Positions block=(uast:Position col=1u line=1u offset=0u)))`,
+	},
+	{
+		name: "multiline",
+		in: nodes.Object{
+			uast.KeyType: nodes.String("File"),
+			"Body": nodes.Array{
+				ident("first", pos(1, 1, 0), pos(1, 6, 5)),
+				ident("second", pos(2, 1, 6), pos(2, 7, 12)),
+			},
+		},
+		out: `(File
+  Body=[
+    (uast:Identifier @pos=1:1#0-1:6#5 @role=Expression,Identifier Name="first")
+    (uast:Identifier
+      @pos=2:1#6-2:7#12
+      @role=Expression,Identifier
+      Name="second")])`,
+	},
+}
+
+func TestSexp(t *testing.T) {
+	for _, c := range sexpCases {
+		t.Run(c.name, func(t *testing.T) {
+			data, err := Marshal(c.in)
+			require.NoError(t, err)
+			require.Equal(t, c.out+"\n", string(data))
+
+			out, err := Unmarshal(data)
+			require.NoError(t, err)
+			require.Equal(t, c.in, out)
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	out, err := Unmarshal([]byte(`
+; comments are ignored
+(File
+	Body = [ 1 ; one
+	         2u ]
+	"@type2"={} )
+`))
+	require.NoError(t, err)
+	require.Equal(t, nodes.Object{
+		uast.KeyType: nodes.String("File"),
+		"Body":       nodes.Array{nodes.Int(1), nodes.Uint(2)},
+		"@type2":     nodes.Object{},
+	}, out)
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, c := range []struct {
+		in  string
+		err string
+	}{
+		{in: ``, err: "line 1, col 1: unexpected end of input"},
+		{in: `(a`, err: `line 1, col 3: expected ')', got end of input`},
+		{in: "(a\n  b=1 b=2)", err: `line 2, col 7: duplicate key "b"`},
+		{in: `(a @type="b")`, err: `line 1, col 4: duplicate key "@type"`},
+		{in: `{a 1}`, err: `line 1, col 4: expected '=', got '1'`},
+		{in: `[foo]`, err: `line 1, col 2: unexpected token: "foo"`},
+		{in: `"abc`, err: "line 1, col 1: unterminated string"},
+		{in: `1 2`, err: "line 1, col 3: unexpected data after the value"},
+		{in: `(a @pos=1:2#0-3)`, err: `line 1, col 9: invalid position: "3"`},
+		{in: `(a @role=A,1)`, err: `line 1, col 10: invalid role name: "1"`},
+		{in: `-u`, err: `line 1, col 1: invalid uint: "-u"`},
+	} {
+		t.Run(c.in, func(t *testing.T) {
+			_, err := Unmarshal([]byte(c.in))
+			require.Error(t, err)
+			require.Equal(t, c.err, err.Error())
+		})
+	}
+}
+
+func TestWidth(t *testing.T) {
+	in := sexpCases[len(sexpCases)-1].in
+
+	enc := func(width int) string {
+		buf := bytes.NewBuffer(nil)
+		e := NewEncoder(buf)
+		e.SetWidth(width)
+		require.NoError(t, e.Encode(in))
+		return buf.String()
+	}
+	single := enc(0)
+	require.NotContains(t, single[:len(single)-1], "\n")
+
+	narrow := enc(10)
+	out, err := Unmarshal([]byte(narrow))
+	require.NoError(t, err)
+	require.Equal(t, in, out)
+}
@@ -0,0 +1,11 @@
+// Package uastview provides typed views for the nodes of the UAST schema.
+//
+// Views wrap generic nodes.Object values without copying them, and allow to access the fields
+// of UAST nodes with the types checked at compile time:
+//
+//	if id, ok := uastview.AsIdentifier(n); ok {
+//		fmt.Println(id.Name())
+//	}
+package uastview
+
+//go:generate go run ../../cmd/bblfsh-sdk gen-types -p uastview -o views.go uast
@@ -0,0 +1,75 @@
+package uastview
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/bblfsh/sdk/v3/uast"
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+	"github.com/bblfsh/sdk/v3/uast/uastgen"
+	"github.com/bblfsh/sdk/v3/uast/uastyaml"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpToDate(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	err := uastgen.Generate(buf, "uastview", uast.NS)
+	require.NoError(t, err)
+
+	data, err := ioutil.ReadFile("views.go")
+	require.NoError(t, err)
+	require.Equal(t, buf.String(), string(data), "views are outdated; run go generate")
+}
+
+func TestViews(t *testing.T) {
+	n, err := uast.ToNode(uast.Alias{
+		Name: uast.Identifier{Name: "f"},
+		Node: uast.Function{
+			Type: uast.FunctionType{
+				Arguments: []uast.Argument{{Variadic: true}},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	require.False(t, IsFunction(n))
+	alias, ok := AsAlias(n)
+	require.True(t, ok)
+	require.Equal(t, "f", alias.Name().Name())
+
+	fnc, ok := AsFunction(alias.Node())
+	require.True(t, ok)
+	args := fnc.Type().Arguments()
+	require.Len(t, args, 1)
+	arg, ok := AsArgument(args[0])
+	require.True(t, ok)
+	require.True(t, arg.Variadic())
+	require.Equal(t, "", arg.Name().Name())
+
+	// views share the data with the tree
+	alias.Name().Obj["Name"] = nodes.String("g")
+	require.Equal(t, nodes.String("g"), n.(nodes.Object)["Name"].(nodes.Object)["Name"])
+}
+
+func TestViewsYAML(t *testing.T) {
+	n, err := uast.ToNode(uast.Position{Offset: 5, Line: 2, Col: 3})
+	require.NoError(t, err)
+	data, err := uastyaml.Marshal(n)
+	require.NoError(t, err)
+	// YAML decoder stores all integers as nodes.Int
+	n, err = uastyaml.Unmarshal(data)
+	require.NoError(t, err)
+
+	pos, ok := AsPosition(n)
+	require.True(t, ok)
+	require.Equal(t, uint64(5), pos.Offset())
+	require.Equal(t, uint64(2), pos.Line())
+	require.Equal(t, uint64(3), pos.Col())
+
+	// values that don't fit into the field type
+	pos.Obj["line"] = nodes.Int(-1)
+	require.Equal(t, uint64(0), pos.Line())
+	pos.Obj["col"] = nodes.Uint(1 << 40)
+	require.Equal(t, uint64(0), pos.Col())
+}
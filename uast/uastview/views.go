@@ -0,0 +1,1138 @@
+// Code generated by bblfsh-sdk gen-types. DO NOT EDIT.
+
+package uastview
+
+import (
+	"github.com/bblfsh/sdk/v3/uast"
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+)
+
+// UAST types.
+const (
+	TypeAlias               = "uast:Alias"
+	TypeArgument            = "uast:Argument"
+	TypeAssignment          = "uast:Assignment"
+	TypeBinaryOp            = "uast:BinaryOp"
+	TypeBlock               = "uast:Block"
+	TypeBool                = "uast:Bool"
+	TypeCall                = "uast:Call"
+	TypeComment             = "uast:Comment"
+	TypeFunction            = "uast:Function"
+	TypeFunctionGroup       = "uast:FunctionGroup"
+	TypeFunctionType        = "uast:FunctionType"
+	TypeGenNode             = "uast:GenNode"
+	TypeGroup               = "uast:Group"
+	TypeIdentifier          = "uast:Identifier"
+	TypeIf                  = "uast:If"
+	TypeImport              = "uast:Import"
+	TypeInlineImport        = "uast:InlineImport"
+	TypeLiteral             = "uast:Literal"
+	TypeLoop                = "uast:Loop"
+	TypePosition            = "uast:Position"
+	TypePositions           = "uast:Positions"
+	TypeQualifiedIdentifier = "uast:QualifiedIdentifier"
+	TypeReturn              = "uast:Return"
+	TypeRuntimeImport       = "uast:RuntimeImport"
+	TypeRuntimeReImport     = "uast:RuntimeReImport"
+	TypeString              = "uast:String"
+	TypeTypeDeclaration     = "uast:TypeDeclaration"
+)
+
+// AliasView is a typed view of a uast:Alias node.
+type AliasView struct {
+	Obj nodes.Object
+}
+
+// IsAlias checks if the node has a uast:Alias type.
+func IsAlias(n nodes.Node) bool {
+	_, ok := AsAlias(n)
+	return ok
+}
+
+// AsAlias returns a typed view of the node if it has a uast:Alias type.
+func AsAlias(n nodes.Node) (AliasView, bool) {
+	obj, ok := n.(nodes.Object)
+	if !ok || obj[uast.KeyType] != nodes.String(TypeAlias) {
+		return AliasView{}, false
+	}
+	return AliasView{Obj: obj}, true
+}
+
+// Positions returns the "@pos" field of the node.
+func (v AliasView) Positions() nodes.Object {
+	val, _ := v.Obj["@pos"].(nodes.Object)
+	return val
+}
+
+// Name returns the "Name" field of the node.
+func (v AliasView) Name() IdentifierView {
+	obj, _ := v.Obj["Name"].(nodes.Object)
+	return IdentifierView{Obj: obj}
+}
+
+// Node returns the "Node" field of the node.
+func (v AliasView) Node() nodes.Node {
+	return v.Obj["Node"]
+}
+
+// ArgumentView is a typed view of a uast:Argument node.
+type ArgumentView struct {
+	Obj nodes.Object
+}
+
+// IsArgument checks if the node has a uast:Argument type.
+func IsArgument(n nodes.Node) bool {
+	_, ok := AsArgument(n)
+	return ok
+}
+
+// AsArgument returns a typed view of the node if it has a uast:Argument type.
+func AsArgument(n nodes.Node) (ArgumentView, bool) {
+	obj, ok := n.(nodes.Object)
+	if !ok || obj[uast.KeyType] != nodes.String(TypeArgument) {
+		return ArgumentView{}, false
+	}
+	return ArgumentView{Obj: obj}, true
+}
+
+// Positions returns the "@pos" field of the node.
+func (v ArgumentView) Positions() nodes.Object {
+	val, _ := v.Obj["@pos"].(nodes.Object)
+	return val
+}
+
+// Name returns the "Name" field of the node.
+func (v ArgumentView) Name() IdentifierView {
+	obj, _ := v.Obj["Name"].(nodes.Object)
+	return IdentifierView{Obj: obj}
+}
+
+// Type returns the "Type" field of the node.
+func (v ArgumentView) Type() nodes.Node {
+	return v.Obj["Type"]
+}
+
+// Init returns the "Init" field of the node.
+func (v ArgumentView) Init() nodes.Node {
+	return v.Obj["Init"]
+}
+
+// Variadic returns the "Variadic" field of the node.
+func (v ArgumentView) Variadic() bool {
+	val, _ := v.Obj["Variadic"].(nodes.Bool)
+	return bool(val)
+}
+
+// MapVariadic returns the "MapVariadic" field of the node.
+func (v ArgumentView) MapVariadic() bool {
+	val, _ := v.Obj["MapVariadic"].(nodes.Bool)
+	return bool(val)
+}
+
+// Receiver returns the "Receiver" field of the node.
+func (v ArgumentView) Receiver() bool {
+	val, _ := v.Obj["Receiver"].(nodes.Bool)
+	return bool(val)
+}
+
+// AssignmentView is a typed view of a uast:Assignment node.
+type AssignmentView struct {
+	Obj nodes.Object
+}
+
+// IsAssignment checks if the node has a uast:Assignment type.
+func IsAssignment(n nodes.Node) bool {
+	_, ok := AsAssignment(n)
+	return ok
+}
+
+// AsAssignment returns a typed view of the node if it has a uast:Assignment type.
+func AsAssignment(n nodes.Node) (AssignmentView, bool) {
+	obj, ok := n.(nodes.Object)
+	if !ok || obj[uast.KeyType] != nodes.String(TypeAssignment) {
+		return AssignmentView{}, false
+	}
+	return AssignmentView{Obj: obj}, true
+}
+
+// Positions returns the "@pos" field of the node.
+func (v AssignmentView) Positions() nodes.Object {
+	val, _ := v.Obj["@pos"].(nodes.Object)
+	return val
+}
+
+// Target returns the "Target" field of the node.
+func (v AssignmentView) Target() nodes.Node {
+	return v.Obj["Target"]
+}
+
+// Value returns the "Value" field of the node.
+func (v AssignmentView) Value() nodes.Node {
+	return v.Obj["Value"]
+}
+
+// Op returns the "Op" field of the node.
+func (v AssignmentView) Op() string {
+	val, _ := v.Obj["Op"].(nodes.String)
+	return string(val)
+}
+
+// BinaryOpView is a typed view of a uast:BinaryOp node.
+type BinaryOpView struct {
+	Obj nodes.Object
+}
+
+// IsBinaryOp checks if the node has a uast:BinaryOp type.
+func IsBinaryOp(n nodes.Node) bool {
+	_, ok := AsBinaryOp(n)
+	return ok
+}
+
+// AsBinaryOp returns a typed view of the node if it has a uast:BinaryOp type.
+func AsBinaryOp(n nodes.Node) (BinaryOpView, bool) {
+	obj, ok := n.(nodes.Object)
+	if !ok || obj[uast.KeyType] != nodes.String(TypeBinaryOp) {
+		return BinaryOpView{}, false
+	}
+	return BinaryOpView{Obj: obj}, true
+}
+
+// Positions returns the "@pos" field of the node.
+func (v BinaryOpView) Positions() nodes.Object {
+	val, _ := v.Obj["@pos"].(nodes.Object)
+	return val
+}
+
+// Op returns the "Op" field of the node.
+func (v BinaryOpView) Op() string {
+	val, _ := v.Obj["Op"].(nodes.String)
+	return string(val)
+}
+
+// Left returns the "Left" field of the node.
+func (v BinaryOpView) Left() nodes.Node {
+	return v.Obj["Left"]
+}
+
+// Right returns the "Right" field of the node.
+func (v BinaryOpView) Right() nodes.Node {
+	return v.Obj["Right"]
+}
+
+// BlockView is a typed view of a uast:Block node.
+type BlockView struct {
+	Obj nodes.Object
+}
+
+// IsBlock checks if the node has a uast:Block type.
+func IsBlock(n nodes.Node) bool {
+	_, ok := AsBlock(n)
+	return ok
+}
+
+// AsBlock returns a typed view of the node if it has a uast:Block type.
+func AsBlock(n nodes.Node) (BlockView, bool) {
+	obj, ok := n.(nodes.Object)
+	if !ok || obj[uast.KeyType] != nodes.String(TypeBlock) {
+		return BlockView{}, false
+	}
+	return BlockView{Obj: obj}, true
+}
+
+// Positions returns the "@pos" field of the node.
+func (v BlockView) Positions() nodes.Object {
+	val, _ := v.Obj["@pos"].(nodes.Object)
+	return val
+}
+
+// Statements returns the "Statements" field of the node.
+func (v BlockView) Statements() nodes.Array {
+	val, _ := v.Obj["Statements"].(nodes.Array)
+	return val
+}
+
+// BoolView is a typed view of a uast:Bool node.
+type BoolView struct {
+	Obj nodes.Object
+}
+
+// IsBool checks if the node has a uast:Bool type.
+func IsBool(n nodes.Node) bool {
+	_, ok := AsBool(n)
+	return ok
+}
+
+// AsBool returns a typed view of the node if it has a uast:Bool type.
+func AsBool(n nodes.Node) (BoolView, bool) {
+	obj, ok := n.(nodes.Object)
+	if !ok || obj[uast.KeyType] != nodes.String(TypeBool) {
+		return BoolView{}, false
+	}
+	return BoolView{Obj: obj}, true
+}
+
+// Positions returns the "@pos" field of the node.
+func (v BoolView) Positions() nodes.Object {
+	val, _ := v.Obj["@pos"].(nodes.Object)
+	return val
+}
+
+// Value returns the "Value" field of the node.
+func (v BoolView) Value() bool {
+	val, _ := v.Obj["Value"].(nodes.Bool)
+	return bool(val)
+}
+
+// CallView is a typed view of a uast:Call node.
+type CallView struct {
+	Obj nodes.Object
+}
+
+// IsCall checks if the node has a uast:Call type.
+func IsCall(n nodes.Node) bool {
+	_, ok := AsCall(n)
+	return ok
+}
+
+// AsCall returns a typed view of the node if it has a uast:Call type.
+func AsCall(n nodes.Node) (CallView, bool) {
+	obj, ok := n.(nodes.Object)
+	if !ok || obj[uast.KeyType] != nodes.String(TypeCall) {
+		return CallView{}, false
+	}
+	return CallView{Obj: obj}, true
+}
+
+// Positions returns the "@pos" field of the node.
+func (v CallView) Positions() nodes.Object {
+	val, _ := v.Obj["@pos"].(nodes.Object)
+	return val
+}
+
+// Func returns the "Func" field of the node.
+func (v CallView) Func() nodes.Node {
+	return v.Obj["Func"]
+}
+
+// Args returns the "Args" field of the node.
+func (v CallView) Args() nodes.Array {
+	val, _ := v.Obj["Args"].(nodes.Array)
+	return val
+}
+
+// CommentView is a typed view of a uast:Comment node.
+type CommentView struct {
+	Obj nodes.Object
+}
+
+// IsComment checks if the node has a uast:Comment type.
+func IsComment(n nodes.Node) bool {
+	_, ok := AsComment(n)
+	return ok
+}
+
+// AsComment returns a typed view of the node if it has a uast:Comment type.
+func AsComment(n nodes.Node) (CommentView, bool) {
+	obj, ok := n.(nodes.Object)
+	if !ok || obj[uast.KeyType] != nodes.String(TypeComment) {
+		return CommentView{}, false
+	}
+	return CommentView{Obj: obj}, true
+}
+
+// Positions returns the "@pos" field of the node.
+func (v CommentView) Positions() nodes.Object {
+	val, _ := v.Obj["@pos"].(nodes.Object)
+	return val
+}
+
+// Block returns the "Block" field of the node.
+func (v CommentView) Block() bool {
+	val, _ := v.Obj["Block"].(nodes.Bool)
+	return bool(val)
+}
+
+// Text returns the "Text" field of the node.
+func (v CommentView) Text() string {
+	val, _ := v.Obj["Text"].(nodes.String)
+	return string(val)
+}
+
+// Prefix returns the "Prefix" field of the node.
+func (v CommentView) Prefix() string {
+	val, _ := v.Obj["Prefix"].(nodes.String)
+	return string(val)
+}
+
+// Suffix returns the "Suffix" field of the node.
+func (v CommentView) Suffix() string {
+	val, _ := v.Obj["Suffix"].(nodes.String)
+	return string(val)
+}
+
+// Tab returns the "Tab" field of the node.
+func (v CommentView) Tab() string {
+	val, _ := v.Obj["Tab"].(nodes.String)
+	return string(val)
+}
+
+// FunctionView is a typed view of a uast:Function node.
+type FunctionView struct {
+	Obj nodes.Object
+}
+
+// IsFunction checks if the node has a uast:Function type.
+func IsFunction(n nodes.Node) bool {
+	_, ok := AsFunction(n)
+	return ok
+}
+
+// AsFunction returns a typed view of the node if it has a uast:Function type.
+func AsFunction(n nodes.Node) (FunctionView, bool) {
+	obj, ok := n.(nodes.Object)
+	if !ok || obj[uast.KeyType] != nodes.String(TypeFunction) {
+		return FunctionView{}, false
+	}
+	return FunctionView{Obj: obj}, true
+}
+
+// Positions returns the "@pos" field of the node.
+func (v FunctionView) Positions() nodes.Object {
+	val, _ := v.Obj["@pos"].(nodes.Object)
+	return val
+}
+
+// Type returns the "Type" field of the node.
+func (v FunctionView) Type() FunctionTypeView {
+	obj, _ := v.Obj["Type"].(nodes.Object)
+	return FunctionTypeView{Obj: obj}
+}
+
+// Body returns the "Body" field of the node.
+func (v FunctionView) Body() BlockView {
+	obj, _ := v.Obj["Body"].(nodes.Object)
+	return BlockView{Obj: obj}
+}
+
+// FunctionGroupView is a typed view of a uast:FunctionGroup node.
+type FunctionGroupView struct {
+	Obj nodes.Object
+}
+
+// IsFunctionGroup checks if the node has a uast:FunctionGroup type.
+func IsFunctionGroup(n nodes.Node) bool {
+	_, ok := AsFunctionGroup(n)
+	return ok
+}
+
+// AsFunctionGroup returns a typed view of the node if it has a uast:FunctionGroup type.
+func AsFunctionGroup(n nodes.Node) (FunctionGroupView, bool) {
+	obj, ok := n.(nodes.Object)
+	if !ok || obj[uast.KeyType] != nodes.String(TypeFunctionGroup) {
+		return FunctionGroupView{}, false
+	}
+	return FunctionGroupView{Obj: obj}, true
+}
+
+// Positions returns the "@pos" field of the node.
+func (v FunctionGroupView) Positions() nodes.Object {
+	val, _ := v.Obj["@pos"].(nodes.Object)
+	return val
+}
+
+// Nodes returns the "Nodes" field of the node.
+func (v FunctionGroupView) Nodes() nodes.Array {
+	val, _ := v.Obj["Nodes"].(nodes.Array)
+	return val
+}
+
+// FunctionTypeView is a typed view of a uast:FunctionType node.
+type FunctionTypeView struct {
+	Obj nodes.Object
+}
+
+// IsFunctionType checks if the node has a uast:FunctionType type.
+func IsFunctionType(n nodes.Node) bool {
+	_, ok := AsFunctionType(n)
+	return ok
+}
+
+// AsFunctionType returns a typed view of the node if it has a uast:FunctionType type.
+func AsFunctionType(n nodes.Node) (FunctionTypeView, bool) {
+	obj, ok := n.(nodes.Object)
+	if !ok || obj[uast.KeyType] != nodes.String(TypeFunctionType) {
+		return FunctionTypeView{}, false
+	}
+	return FunctionTypeView{Obj: obj}, true
+}
+
+// Positions returns the "@pos" field of the node.
+func (v FunctionTypeView) Positions() nodes.Object {
+	val, _ := v.Obj["@pos"].(nodes.Object)
+	return val
+}
+
+// Arguments returns the "Arguments" field of the node.
+func (v FunctionTypeView) Arguments() nodes.Array {
+	val, _ := v.Obj["Arguments"].(nodes.Array)
+	return val
+}
+
+// Returns returns the "Returns" field of the node.
+func (v FunctionTypeView) Returns() nodes.Array {
+	val, _ := v.Obj["Returns"].(nodes.Array)
+	return val
+}
+
+// GenNodeView is a typed view of a uast:GenNode node.
+type GenNodeView struct {
+	Obj nodes.Object
+}
+
+// IsGenNode checks if the node has a uast:GenNode type.
+func IsGenNode(n nodes.Node) bool {
+	_, ok := AsGenNode(n)
+	return ok
+}
+
+// AsGenNode returns a typed view of the node if it has a uast:GenNode type.
+func AsGenNode(n nodes.Node) (GenNodeView, bool) {
+	obj, ok := n.(nodes.Object)
+	if !ok || obj[uast.KeyType] != nodes.String(TypeGenNode) {
+		return GenNodeView{}, false
+	}
+	return GenNodeView{Obj: obj}, true
+}
+
+// Positions returns the "@pos" field of the node.
+func (v GenNodeView) Positions() nodes.Object {
+	val, _ := v.Obj["@pos"].(nodes.Object)
+	return val
+}
+
+// GroupView is a typed view of a uast:Group node.
+type GroupView struct {
+	Obj nodes.Object
+}
+
+// IsGroup checks if the node has a uast:Group type.
+func IsGroup(n nodes.Node) bool {
+	_, ok := AsGroup(n)
+	return ok
+}
+
+// AsGroup returns a typed view of the node if it has a uast:Group type.
+func AsGroup(n nodes.Node) (GroupView, bool) {
+	obj, ok := n.(nodes.Object)
+	if !ok || obj[uast.KeyType] != nodes.String(TypeGroup) {
+		return GroupView{}, false
+	}
+	return GroupView{Obj: obj}, true
+}
+
+// Positions returns the "@pos" field of the node.
+func (v GroupView) Positions() nodes.Object {
+	val, _ := v.Obj["@pos"].(nodes.Object)
+	return val
+}
+
+// Nodes returns the "Nodes" field of the node.
+func (v GroupView) Nodes() nodes.Array {
+	val, _ := v.Obj["Nodes"].(nodes.Array)
+	return val
+}
+
+// IdentifierView is a typed view of a uast:Identifier node.
+type IdentifierView struct {
+	Obj nodes.Object
+}
+
+// IsIdentifier checks if the node has a uast:Identifier type.
+func IsIdentifier(n nodes.Node) bool {
+	_, ok := AsIdentifier(n)
+	return ok
+}
+
+// AsIdentifier returns a typed view of the node if it has a uast:Identifier type.
+func AsIdentifier(n nodes.Node) (IdentifierView, bool) {
+	obj, ok := n.(nodes.Object)
+	if !ok || obj[uast.KeyType] != nodes.String(TypeIdentifier) {
+		return IdentifierView{}, false
+	}
+	return IdentifierView{Obj: obj}, true
+}
+
+// Positions returns the "@pos" field of the node.
+func (v IdentifierView) Positions() nodes.Object {
+	val, _ := v.Obj["@pos"].(nodes.Object)
+	return val
+}
+
+// Name returns the "Name" field of the node.
+func (v IdentifierView) Name() string {
+	val, _ := v.Obj["Name"].(nodes.String)
+	return string(val)
+}
+
+// IfView is a typed view of a uast:If node.
+type IfView struct {
+	Obj nodes.Object
+}
+
+// IsIf checks if the node has a uast:If type.
+func IsIf(n nodes.Node) bool {
+	_, ok := AsIf(n)
+	return ok
+}
+
+// AsIf returns a typed view of the node if it has a uast:If type.
+func AsIf(n nodes.Node) (IfView, bool) {
+	obj, ok := n.(nodes.Object)
+	if !ok || obj[uast.KeyType] != nodes.String(TypeIf) {
+		return IfView{}, false
+	}
+	return IfView{Obj: obj}, true
+}
+
+// Positions returns the "@pos" field of the node.
+func (v IfView) Positions() nodes.Object {
+	val, _ := v.Obj["@pos"].(nodes.Object)
+	return val
+}
+
+// Cond returns the "Cond" field of the node.
+func (v IfView) Cond() nodes.Node {
+	return v.Obj["Cond"]
+}
+
+// Then returns the "Then" field of the node.
+func (v IfView) Then() nodes.Node {
+	return v.Obj["Then"]
+}
+
+// Else returns the "Else" field of the node.
+func (v IfView) Else() nodes.Node {
+	return v.Obj["Else"]
+}
+
+// ImportView is a typed view of a uast:Import node.
+type ImportView struct {
+	Obj nodes.Object
+}
+
+// IsImport checks if the node has a uast:Import type.
+func IsImport(n nodes.Node) bool {
+	_, ok := AsImport(n)
+	return ok
+}
+
+// AsImport returns a typed view of the node if it has a uast:Import type.
+func AsImport(n nodes.Node) (ImportView, bool) {
+	obj, ok := n.(nodes.Object)
+	if !ok || obj[uast.KeyType] != nodes.String(TypeImport) {
+		return ImportView{}, false
+	}
+	return ImportView{Obj: obj}, true
+}
+
+// Positions returns the "@pos" field of the node.
+func (v ImportView) Positions() nodes.Object {
+	val, _ := v.Obj["@pos"].(nodes.Object)
+	return val
+}
+
+// Path returns the "Path" field of the node.
+func (v ImportView) Path() nodes.Node {
+	return v.Obj["Path"]
+}
+
+// All returns the "All" field of the node.
+func (v ImportView) All() bool {
+	val, _ := v.Obj["All"].(nodes.Bool)
+	return bool(val)
+}
+
+// Names returns the "Names" field of the node.
+func (v ImportView) Names() nodes.Array {
+	val, _ := v.Obj["Names"].(nodes.Array)
+	return val
+}
+
+// Target returns the "Target" field of the node.
+func (v ImportView) Target() nodes.Node {
+	return v.Obj["Target"]
+}
+
+// InlineImportView is a typed view of a uast:InlineImport node.
+type InlineImportView struct {
+	Obj nodes.Object
+}
+
+// IsInlineImport checks if the node has a uast:InlineImport type.
+func IsInlineImport(n nodes.Node) bool {
+	_, ok := AsInlineImport(n)
+	return ok
+}
+
+// AsInlineImport returns a typed view of the node if it has a uast:InlineImport type.
+func AsInlineImport(n nodes.Node) (InlineImportView, bool) {
+	obj, ok := n.(nodes.Object)
+	if !ok || obj[uast.KeyType] != nodes.String(TypeInlineImport) {
+		return InlineImportView{}, false
+	}
+	return InlineImportView{Obj: obj}, true
+}
+
+// Positions returns the "@pos" field of the node.
+func (v InlineImportView) Positions() nodes.Object {
+	val, _ := v.Obj["@pos"].(nodes.Object)
+	return val
+}
+
+// Path returns the "Path" field of the node.
+func (v InlineImportView) Path() nodes.Node {
+	return v.Obj["Path"]
+}
+
+// All returns the "All" field of the node.
+func (v InlineImportView) All() bool {
+	val, _ := v.Obj["All"].(nodes.Bool)
+	return bool(val)
+}
+
+// Names returns the "Names" field of the node.
+func (v InlineImportView) Names() nodes.Array {
+	val, _ := v.Obj["Names"].(nodes.Array)
+	return val
+}
+
+// Target returns the "Target" field of the node.
+func (v InlineImportView) Target() nodes.Node {
+	return v.Obj["Target"]
+}
+
+// LiteralView is a typed view of a uast:Literal node.
+type LiteralView struct {
+	Obj nodes.Object
+}
+
+// IsLiteral checks if the node has a uast:Literal type.
+func IsLiteral(n nodes.Node) bool {
+	_, ok := AsLiteral(n)
+	return ok
+}
+
+// AsLiteral returns a typed view of the node if it has a uast:Literal type.
+func AsLiteral(n nodes.Node) (LiteralView, bool) {
+	obj, ok := n.(nodes.Object)
+	if !ok || obj[uast.KeyType] != nodes.String(TypeLiteral) {
+		return LiteralView{}, false
+	}
+	return LiteralView{Obj: obj}, true
+}
+
+// Positions returns the "@pos" field of the node.
+func (v LiteralView) Positions() nodes.Object {
+	val, _ := v.Obj["@pos"].(nodes.Object)
+	return val
+}
+
+// Value returns the "Value" field of the node.
+func (v LiteralView) Value() string {
+	val, _ := v.Obj["Value"].(nodes.String)
+	return string(val)
+}
+
+// Format returns the "Format" field of the node.
+func (v LiteralView) Format() string {
+	val, _ := v.Obj["Format"].(nodes.String)
+	return string(val)
+}
+
+// LoopView is a typed view of a uast:Loop node.
+type LoopView struct {
+	Obj nodes.Object
+}
+
+// IsLoop checks if the node has a uast:Loop type.
+func IsLoop(n nodes.Node) bool {
+	_, ok := AsLoop(n)
+	return ok
+}
+
+// AsLoop returns a typed view of the node if it has a uast:Loop type.
+func AsLoop(n nodes.Node) (LoopView, bool) {
+	obj, ok := n.(nodes.Object)
+	if !ok || obj[uast.KeyType] != nodes.String(TypeLoop) {
+		return LoopView{}, false
+	}
+	return LoopView{Obj: obj}, true
+}
+
+// Positions returns the "@pos" field of the node.
+func (v LoopView) Positions() nodes.Object {
+	val, _ := v.Obj["@pos"].(nodes.Object)
+	return val
+}
+
+// Kind returns the "Kind" field of the node.
+func (v LoopView) Kind() string {
+	val, _ := v.Obj["Kind"].(nodes.String)
+	return string(val)
+}
+
+// Init returns the "Init" field of the node.
+func (v LoopView) Init() nodes.Node {
+	return v.Obj["Init"]
+}
+
+// Cond returns the "Cond" field of the node.
+func (v LoopView) Cond() nodes.Node {
+	return v.Obj["Cond"]
+}
+
+// Post returns the "Post" field of the node.
+func (v LoopView) Post() nodes.Node {
+	return v.Obj["Post"]
+}
+
+// Var returns the "Var" field of the node.
+func (v LoopView) Var() nodes.Node {
+	return v.Obj["Var"]
+}
+
+// Iter returns the "Iter" field of the node.
+func (v LoopView) Iter() nodes.Node {
+	return v.Obj["Iter"]
+}
+
+// Body returns the "Body" field of the node.
+func (v LoopView) Body() nodes.Node {
+	return v.Obj["Body"]
+}
+
+// PositionView is a typed view of a uast:Position node.
+type PositionView struct {
+	Obj nodes.Object
+}
+
+// IsPosition checks if the node has a uast:Position type.
+func IsPosition(n nodes.Node) bool {
+	_, ok := AsPosition(n)
+	return ok
+}
+
+// AsPosition returns a typed view of the node if it has a uast:Position type.
+func AsPosition(n nodes.Node) (PositionView, bool) {
+	obj, ok := n.(nodes.Object)
+	if !ok || obj[uast.KeyType] != nodes.String(TypePosition) {
+		return PositionView{}, false
+	}
+	return PositionView{Obj: obj}, true
+}
+
+// Offset returns the "offset" field of the node.
+func (v PositionView) Offset() uint64 {
+	switch val := v.Obj["offset"].(type) {
+	case nodes.Int:
+		if val >= 0 && val <= 4294967295 {
+			return uint64(val)
+		}
+	case nodes.Uint:
+		if val <= 4294967295 {
+			return uint64(val)
+		}
+	}
+	return 0
+}
+
+// Line returns the "line" field of the node.
+func (v PositionView) Line() uint64 {
+	switch val := v.Obj["line"].(type) {
+	case nodes.Int:
+		if val >= 0 && val <= 4294967295 {
+			return uint64(val)
+		}
+	case nodes.Uint:
+		if val <= 4294967295 {
+			return uint64(val)
+		}
+	}
+	return 0
+}
+
+// Col returns the "col" field of the node.
+func (v PositionView) Col() uint64 {
+	switch val := v.Obj["col"].(type) {
+	case nodes.Int:
+		if val >= 0 && val <= 4294967295 {
+			return uint64(val)
+		}
+	case nodes.Uint:
+		if val <= 4294967295 {
+			return uint64(val)
+		}
+	}
+	return 0
+}
+
+// PositionsView is a typed view of a uast:Positions node.
+type PositionsView struct {
+	Obj nodes.Object
+}
+
+// IsPositions checks if the node has a uast:Positions type.
+func IsPositions(n nodes.Node) bool {
+	_, ok := AsPositions(n)
+	return ok
+}
+
+// AsPositions returns a typed view of the node if it has a uast:Positions type.
+func AsPositions(n nodes.Node) (PositionsView, bool) {
+	obj, ok := n.(nodes.Object)
+	if !ok || obj[uast.KeyType] != nodes.String(TypePositions) {
+		return PositionsView{}, false
+	}
+	return PositionsView{Obj: obj}, true
+}
+
+// QualifiedIdentifierView is a typed view of a uast:QualifiedIdentifier node.
+type QualifiedIdentifierView struct {
+	Obj nodes.Object
+}
+
+// IsQualifiedIdentifier checks if the node has a uast:QualifiedIdentifier type.
+func IsQualifiedIdentifier(n nodes.Node) bool {
+	_, ok := AsQualifiedIdentifier(n)
+	return ok
+}
+
+// AsQualifiedIdentifier returns a typed view of the node if it has a uast:QualifiedIdentifier type.
+func AsQualifiedIdentifier(n nodes.Node) (QualifiedIdentifierView, bool) {
+	obj, ok := n.(nodes.Object)
+	if !ok || obj[uast.KeyType] != nodes.String(TypeQualifiedIdentifier) {
+		return QualifiedIdentifierView{}, false
+	}
+	return QualifiedIdentifierView{Obj: obj}, true
+}
+
+// Positions returns the "@pos" field of the node.
+func (v QualifiedIdentifierView) Positions() nodes.Object {
+	val, _ := v.Obj["@pos"].(nodes.Object)
+	return val
+}
+
+// Names returns the "Names" field of the node.
+func (v QualifiedIdentifierView) Names() nodes.Array {
+	val, _ := v.Obj["Names"].(nodes.Array)
+	return val
+}
+
+// ReturnView is a typed view of a uast:Return node.
+type ReturnView struct {
+	Obj nodes.Object
+}
+
+// IsReturn checks if the node has a uast:Return type.
+func IsReturn(n nodes.Node) bool {
+	_, ok := AsReturn(n)
+	return ok
+}
+
+// AsReturn returns a typed view of the node if it has a uast:Return type.
+func AsReturn(n nodes.Node) (ReturnView, bool) {
+	obj, ok := n.(nodes.Object)
+	if !ok || obj[uast.KeyType] != nodes.String(TypeReturn) {
+		return ReturnView{}, false
+	}
+	return ReturnView{Obj: obj}, true
+}
+
+// Positions returns the "@pos" field of the node.
+func (v ReturnView) Positions() nodes.Object {
+	val, _ := v.Obj["@pos"].(nodes.Object)
+	return val
+}
+
+// Values returns the "Values" field of the node.
+func (v ReturnView) Values() nodes.Array {
+	val, _ := v.Obj["Values"].(nodes.Array)
+	return val
+}
+
+// RuntimeImportView is a typed view of a uast:RuntimeImport node.
+type RuntimeImportView struct {
+	Obj nodes.Object
+}
+
+// IsRuntimeImport checks if the node has a uast:RuntimeImport type.
+func IsRuntimeImport(n nodes.Node) bool {
+	_, ok := AsRuntimeImport(n)
+	return ok
+}
+
+// AsRuntimeImport returns a typed view of the node if it has a uast:RuntimeImport type.
+func AsRuntimeImport(n nodes.Node) (RuntimeImportView, bool) {
+	obj, ok := n.(nodes.Object)
+	if !ok || obj[uast.KeyType] != nodes.String(TypeRuntimeImport) {
+		return RuntimeImportView{}, false
+	}
+	return RuntimeImportView{Obj: obj}, true
+}
+
+// Positions returns the "@pos" field of the node.
+func (v RuntimeImportView) Positions() nodes.Object {
+	val, _ := v.Obj["@pos"].(nodes.Object)
+	return val
+}
+
+// Path returns the "Path" field of the node.
+func (v RuntimeImportView) Path() nodes.Node {
+	return v.Obj["Path"]
+}
+
+// All returns the "All" field of the node.
+func (v RuntimeImportView) All() bool {
+	val, _ := v.Obj["All"].(nodes.Bool)
+	return bool(val)
+}
+
+// Names returns the "Names" field of the node.
+func (v RuntimeImportView) Names() nodes.Array {
+	val, _ := v.Obj["Names"].(nodes.Array)
+	return val
+}
+
+// Target returns the "Target" field of the node.
+func (v RuntimeImportView) Target() nodes.Node {
+	return v.Obj["Target"]
+}
+
+// RuntimeReImportView is a typed view of a uast:RuntimeReImport node.
+type RuntimeReImportView struct {
+	Obj nodes.Object
+}
+
+// IsRuntimeReImport checks if the node has a uast:RuntimeReImport type.
+func IsRuntimeReImport(n nodes.Node) bool {
+	_, ok := AsRuntimeReImport(n)
+	return ok
+}
+
+// AsRuntimeReImport returns a typed view of the node if it has a uast:RuntimeReImport type.
+func AsRuntimeReImport(n nodes.Node) (RuntimeReImportView, bool) {
+	obj, ok := n.(nodes.Object)
+	if !ok || obj[uast.KeyType] != nodes.String(TypeRuntimeReImport) {
+		return RuntimeReImportView{}, false
+	}
+	return RuntimeReImportView{Obj: obj}, true
+}
+
+// Positions returns the "@pos" field of the node.
+func (v RuntimeReImportView) Positions() nodes.Object {
+	val, _ := v.Obj["@pos"].(nodes.Object)
+	return val
+}
+
+// Path returns the "Path" field of the node.
+func (v RuntimeReImportView) Path() nodes.Node {
+	return v.Obj["Path"]
+}
+
+// All returns the "All" field of the node.
+func (v RuntimeReImportView) All() bool {
+	val, _ := v.Obj["All"].(nodes.Bool)
+	return bool(val)
+}
+
+// Names returns the "Names" field of the node.
+func (v RuntimeReImportView) Names() nodes.Array {
+	val, _ := v.Obj["Names"].(nodes.Array)
+	return val
+}
+
+// Target returns the "Target" field of the node.
+func (v RuntimeReImportView) Target() nodes.Node {
+	return v.Obj["Target"]
+}
+
+// StringView is a typed view of a uast:String node.
+type StringView struct {
+	Obj nodes.Object
+}
+
+// IsString checks if the node has a uast:String type.
+func IsString(n nodes.Node) bool {
+	_, ok := AsString(n)
+	return ok
+}
+
+// AsString returns a typed view of the node if it has a uast:String type.
+func AsString(n nodes.Node) (StringView, bool) {
+	obj, ok := n.(nodes.Object)
+	if !ok || obj[uast.KeyType] != nodes.String(TypeString) {
+		return StringView{}, false
+	}
+	return StringView{Obj: obj}, true
+}
+
+// Positions returns the "@pos" field of the node.
+func (v StringView) Positions() nodes.Object {
+	val, _ := v.Obj["@pos"].(nodes.Object)
+	return val
+}
+
+// Value returns the "Value" field of the node.
+func (v StringView) Value() string {
+	val, _ := v.Obj["Value"].(nodes.String)
+	return string(val)
+}
+
+// Format returns the "Format" field of the node.
+func (v StringView) Format() string {
+	val, _ := v.Obj["Format"].(nodes.String)
+	return string(val)
+}
+
+// TypeDeclarationView is a typed view of a uast:TypeDeclaration node.
+type TypeDeclarationView struct {
+	Obj nodes.Object
+}
+
+// IsTypeDeclaration checks if the node has a uast:TypeDeclaration type.
+func IsTypeDeclaration(n nodes.Node) bool {
+	_, ok := AsTypeDeclaration(n)
+	return ok
+}
+
+// AsTypeDeclaration returns a typed view of the node if it has a uast:TypeDeclaration type.
+func AsTypeDeclaration(n nodes.Node) (TypeDeclarationView, bool) {
+	obj, ok := n.(nodes.Object)
+	if !ok || obj[uast.KeyType] != nodes.String(TypeTypeDeclaration) {
+		return TypeDeclarationView{}, false
+	}
+	return TypeDeclarationView{Obj: obj}, true
+}
+
+// Positions returns the "@pos" field of the node.
+func (v TypeDeclarationView) Positions() nodes.Object {
+	val, _ := v.Obj["@pos"].(nodes.Object)
+	return val
+}
+
+// Name returns the "Name" field of the node.
+func (v TypeDeclarationView) Name() IdentifierView {
+	obj, _ := v.Obj["Name"].(nodes.Object)
+	return IdentifierView{Obj: obj}
+}
+
+// Bases returns the "Bases" field of the node.
+func (v TypeDeclarationView) Bases() nodes.Array {
+	val, _ := v.Obj["Bases"].(nodes.Array)
+	return val
+}
+
+// Type returns the "Type" field of the node.
+func (v TypeDeclarationView) Type() nodes.Node {
+	return v.Obj["Type"]
+}
@@ -0,0 +1,197 @@
+package uastyaml
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bblfsh/sdk/v3/uast"
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+	"gopkg.in/yaml.v2"
+)
+
+// Error is returned by the Decoder when a YAML document cannot be decoded.
+//
+// The error only carries a line number, since the underlying YAML parser does not report columns.
+// The line is adjusted to be relative to the start of the stream, not the document. If the parser reports
+// multiple errors, Line refers to the first one, and lines of the other errors are kept in the message.
+type Error struct {
+	// Document is an index of the document in the stream, starting from 0.
+	Document int
+	// Line is a line number in the stream, starting from 1. Zero means the line is unknown.
+	Line int
+	// Msg is an error message without the position.
+	Msg string
+}
+
+func (e *Error) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("document %d: %s", e.Document, e.Msg)
+	}
+	return fmt.Sprintf("document %d: line %d: %s", e.Document, e.Line, e.Msg)
+}
+
+// NewDecoder creates a decoder that reads a stream of YAML documents separated by "---".
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r), doc: -1}
+}
+
+// Decoder reads successive YAML documents from a stream and decodes each of them as UAST.
+//
+// Only a single document is kept in memory at a time. Empty documents are skipped.
+// If a document cannot be decoded, the decoder returns an error and the next call to Decode
+// continues with the next document in the stream. Errors are reported as *Error with a line number
+// in the stream, but without a column. See Error for details.
+type Decoder struct {
+	r    *bufio.Reader
+	line int  // last line read from the stream
+	doc  int  // index of the last document
+	eof  bool // the stream ended
+	next []byte
+
+	buf   bytes.Buffer
+	start int  // stream line of the first line in buf
+	data  bool // buf contains something other than whitespace and comments
+}
+
+// Decode reads the next document from the stream. It returns io.EOF if there are no more documents.
+func (d *Decoder) Decode() (nodes.Node, error) {
+	for {
+		ok, err := d.readDocument()
+		if err != nil {
+			return nil, err
+		} else if !ok {
+			return nil, io.EOF
+		}
+		if !d.data {
+			// skip empty documents
+			continue
+		}
+		d.doc++
+		return d.decodeDocument()
+	}
+}
+
+func (d *Decoder) add(line []byte) {
+	if !d.data && hasData(line) {
+		d.data = true
+	}
+	d.buf.Write(line)
+}
+
+// readDocument reads lines of the next document to the buffer. It returns false if the stream ended.
+func (d *Decoder) readDocument() (bool, error) {
+	d.buf.Reset()
+	d.data = false
+	if d.next != nil {
+		// first line of the document was read with the previous one
+		d.start = d.line
+		d.add(d.next)
+		d.next = nil
+	} else if d.eof {
+		return false, nil
+	} else {
+		d.start = d.line + 1
+	}
+	for !d.eof {
+		line, err := d.r.ReadBytes('\n')
+		if err == io.EOF {
+			d.eof = true
+			if len(line) == 0 {
+				break
+			}
+		} else if err != nil {
+			return false, err
+		}
+		d.line++
+		switch {
+		case isMarker(line, "---"):
+			// replace the marker with spaces to keep the columns
+			line = blankMarker(line)
+			if d.data {
+				// start of the next document
+				d.next = line
+				return true, nil
+			}
+		case isMarker(line, "..."):
+			// end of the document
+			return true, nil
+		}
+		d.add(line)
+	}
+	return true, nil
+}
+
+// isMarker checks if the line starts with a document marker.
+func isMarker(line []byte, marker string) bool {
+	if !bytes.HasPrefix(line, []byte(marker)) {
+		return false
+	}
+	rest := line[len(marker):]
+	return len(rest) == 0 || rest[0] == ' ' || rest[0] == '\t' || rest[0] == '\r' || rest[0] == '\n'
+}
+
+// blankMarker replaces the document marker with spaces.
+func blankMarker(line []byte) []byte {
+	out := make([]byte, len(line))
+	copy(out, line)
+	copy(out, "   ")
+	return out
+}
+
+// hasData checks if the line contains anything other than whitespace and comments.
+func hasData(line []byte) bool {
+	line = bytes.TrimSpace(line)
+	return len(line) != 0 && line[0] != '#'
+}
+
+var (
+	reYAMLError = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+	reYAMLLine  = regexp.MustCompile(`^line (\d+): (.*)$`)
+)
+
+func (d *Decoder) errorf(err error) *Error {
+	e := &Error{Document: d.doc, Msg: err.Error()}
+	if terr, ok := err.(*yaml.TypeError); ok && len(terr.Errors) != 0 {
+		// unmarshal errors: report the line of the first one, and keep lines of others in the message
+		msgs := make([]string, 0, len(terr.Errors))
+		for i, msg := range terr.Errors {
+			if sub := reYAMLLine.FindStringSubmatch(msg); sub != nil {
+				line, _ := strconv.Atoi(sub[1])
+				line = d.start + line - 1
+				if i == 0 {
+					e.Line, msg = line, sub[2]
+				} else {
+					msg = fmt.Sprintf("line %d: %s", line, sub[2])
+				}
+			}
+			msgs = append(msgs, msg)
+		}
+		e.Msg = strings.Join(msgs, "; ")
+	} else if sub := reYAMLError.FindStringSubmatch(e.Msg); sub != nil {
+		line, _ := strconv.Atoi(sub[1])
+		e.Line = d.start + line - 1
+		e.Msg = sub[2]
+	}
+	return e
+}
+
+func (d *Decoder) decodeDocument() (nodes.Node, error) {
+	var o interface{}
+	if err := yaml.Unmarshal(d.buf.Bytes(), &o); err != nil {
+		return nil, d.errorf(err)
+	}
+	o, err := fixKeys(o)
+	if err != nil {
+		return nil, d.errorf(err)
+	}
+	n, err := uast.ToNode(o)
+	if err != nil {
+		return nil, d.errorf(err)
+	}
+	return n, nil
+}
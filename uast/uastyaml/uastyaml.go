@@ -16,7 +16,7 @@ import (
 )
 
 // Marshal encode the UAST to a human-readable YAML.
-func Marshal(n nodes.Node) ([]byte, error) {
+func Marshal(n nodes.External) ([]byte, error) {
 	buf := bytes.NewBuffer(nil)
 	enc := NewEncoder(buf)
 	if err := enc.Encode(n); err != nil {
@@ -47,7 +47,8 @@ func (enc *Encoder) ForceRoles(enable bool) {
 	enc.forceRoles = enable
 }
 
-func (enc *Encoder) Encode(n nodes.Node) error {
+// Encode writes the UAST to the stream. It accepts both generic nodes and external node implementations.
+func (enc *Encoder) Encode(n nodes.External) error {
 	enc.marshal(nil, n, false)
 	if enc.err != nil {
 		return enc.err
@@ -55,23 +56,33 @@ func (enc *Encoder) Encode(n nodes.Node) error {
 	return enc.w.Flush()
 }
 
-func (enc *Encoder) marshal(tabs []byte, n nodes.Node, field bool) {
-	switch n := n.(type) {
-	case nil:
+func (enc *Encoder) marshal(tabs []byte, n nodes.External, field bool) {
+	switch kind := nodes.KindOf(n); {
+	case kind == nodes.KindNil:
 		enc.writeString(null)
-	case nodes.Object:
-		enc.writeObject(tabs, n)
-	case nodes.Array:
-		enc.writeArray(tabs, n)
-	case nodes.Value:
-		enc.writeValue(n, field)
+	case kind == nodes.KindObject:
+		m, ok := n.(nodes.ExternalObject)
+		if !ok {
+			enc.err = fmt.Errorf("node is an object, but an interface implementation is missing: %T", n)
+			return
+		}
+		enc.writeObject(tabs, m)
+	case kind == nodes.KindArray:
+		m, ok := n.(nodes.ExternalArray)
+		if !ok {
+			enc.err = fmt.Errorf("node is an array, but an interface implementation is missing: %T", n)
+			return
+		}
+		enc.writeArray(tabs, m)
+	case kind.In(nodes.KindsValues):
+		enc.writeValue(n.Value(), field)
 	default:
 		enc.err = fmt.Errorf("unexpected type: %T", n)
 	}
 }
 
-func (enc *Encoder) writeObject(tabs []byte, m nodes.Object) {
-	if len(m) == 0 {
+func (enc *Encoder) writeObject(tabs []byte, m nodes.ExternalObject) {
+	if m.Size() == 0 {
 		enc.writeString("{}")
 		return
 	}
@@ -88,21 +99,30 @@ func (enc *Encoder) writeObject(tabs []byte, m nodes.Object) {
 		enc.marshalString(s, false)
 		enc.writeString(": ")
 	}
+	// valueOf returns a field value or nil if it has a different kind
+	valueOf := func(key string, kinds nodes.Kind) nodes.External {
+		v, _ := m.ValueAt(key)
+		if v == nil || !v.Kind().In(kinds) {
+			return nil
+		}
+		return v
+	}
 
 	typ := ""
-	if v, ok := m[uast.KeyType].(nodes.String); ok {
+	if v := valueOf(uast.KeyType, nodes.KindString); v != nil {
+		s, _ := v.Value().(nodes.String)
 		enc.writeString(" ")
 		writeSysKey(uast.KeyType, false)
-		enc.marshalString(string(v), true)
+		enc.marshalString(string(s), true)
 		enc.writeString(",")
-		typ = string(v)
+		typ = string(s)
 	}
-	if v, ok := m[uast.KeyToken].(nodes.Value); ok {
+	if v := valueOf(uast.KeyToken, nodes.KindsValues); v != nil {
 		writeSysKey(uast.KeyToken, true)
-		enc.writeValue(v, true)
+		enc.writeValue(v.Value(), true)
 		enc.writeString(",")
 	}
-	if enc.forceRoles || m[uast.KeyRoles] != nil {
+	if enc.forceRoles || valueOf(uast.KeyRoles, nodes.KindsNotNil) != nil {
 		if v := uast.RolesOf(m); len(v) != 0 {
 			writeSysKey(uast.KeyRoles, true)
 			sort.Slice(v, func(i, j int) bool {
@@ -114,18 +134,14 @@ func (enc *Encoder) writeObject(tabs []byte, m nodes.Object) {
 	}
 	// enforce specific sorting for known types
 	emitObj := func(key string) {
-		if v, ok := m[key].(nodes.Object); ok {
+		if v := valueOf(key, nodes.KindObject); v != nil {
 			writeSysKey(key, true)
 			enc.marshal(ntabs, v, true)
 			enc.writeString(",")
 		}
 	}
 	emitInt := func(key string) {
-		if v, ok := m[key].(nodes.Int); ok {
-			writeSysKey(key, true)
-			enc.marshal(ntabs, v, true)
-			enc.writeString(",")
-		} else if v, ok := m[key].(nodes.Uint); ok {
+		if v := valueOf(key, nodes.KindInt|nodes.KindUint); v != nil {
 			writeSysKey(key, true)
 			enc.marshal(ntabs, v, true)
 			enc.writeString(",")
@@ -146,7 +162,7 @@ func (enc *Encoder) writeObject(tabs []byte, m nodes.Object) {
 		if _, ok := written[k]; ok {
 			continue
 		}
-		v := m[k]
+		v, _ := m.ValueAt(k)
 		enc.writeString("\n")
 		enc.write(ntabs)
 		enc.marshalString(k, false)
@@ -159,14 +175,15 @@ func (enc *Encoder) writeObject(tabs []byte, m nodes.Object) {
 	enc.writeString("}")
 }
 
-func (enc *Encoder) writeArray(tabs []byte, m nodes.Array) {
-	if len(m) == 0 {
+func (enc *Encoder) writeArray(tabs []byte, m nodes.ExternalArray) {
+	sz := m.Size()
+	if sz == 0 {
 		enc.writeString("[]")
 		return
 	}
 	small := true
-	for _, o := range m {
-		if _, ok := o.(nodes.Value); !ok {
+	for i := 0; i < sz; i++ {
+		if !nodes.KindOf(m.ValueAt(i)).In(nodes.KindsValues) {
 			small = false
 			break
 		}
@@ -177,7 +194,7 @@ func (enc *Encoder) writeArray(tabs []byte, m nodes.Array) {
 		enc.writeString("\n")
 		enc.write(tabs)
 	}
-	for i, o := range m {
+	for i := 0; i < sz; i++ {
 		if small {
 			if i != 0 {
 				enc.writeString(", ")
@@ -185,7 +202,7 @@ func (enc *Encoder) writeArray(tabs []byte, m nodes.Array) {
 		} else {
 			enc.writeString(tab)
 		}
-		enc.marshal(ntabs, o, false)
+		enc.marshal(ntabs, m.ValueAt(i), false)
 		if !small {
 			enc.writeString(",\n")
 			enc.write(tabs)
@@ -318,28 +335,44 @@ func bestStringFormat(s string) stringFormat {
 	return stringPlain
 }
 
-// Unmarshal decodes YAML to a UAST.
+// Unmarshal decodes YAML to a UAST. Only the first document is decoded; see Decoder for multi-document streams.
 func Unmarshal(data []byte) (nodes.Node, error) {
 	var o interface{}
 	if err := yaml.Unmarshal(data, &o); err != nil {
 		return nil, err
 	}
-	var fix func(interface{}) interface{}
-	fix = func(o interface{}) interface{} {
-		switch o := o.(type) {
-		case map[interface{}]interface{}:
-			m := make(map[string]interface{}, len(o))
-			for k, v := range o {
-				m[k.(string)] = fix(v)
+	o, err := fixKeys(o)
+	if err != nil {
+		return nil, err
+	}
+	return uast.ToNode(o)
+}
+
+// fixKeys converts maps decoded by the YAML library to maps with string keys.
+func fixKeys(o interface{}) (interface{}, error) {
+	switch o := o.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(o))
+		for k, v := range o {
+			ks, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("only string keys are supported, got: %v", k)
+			}
+			v, err := fixKeys(v)
+			if err != nil {
+				return nil, err
 			}
-			return m
-		case []interface{}:
-			for i := range o {
-				o[i] = fix(o[i])
+			m[ks] = v
+		}
+		return m, nil
+	case []interface{}:
+		for i := range o {
+			v, err := fixKeys(o[i])
+			if err != nil {
+				return nil, err
 			}
+			o[i] = v
 		}
-		return o
 	}
-	o = fix(o)
-	return uast.ToNode(o)
+	return o, nil
 }
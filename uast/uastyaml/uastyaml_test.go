@@ -1,12 +1,15 @@
 package uastyaml
 
 import (
+	"io"
+	"strings"
 	"testing"
 
 	"github.com/bblfsh/sdk/v3/uast"
 	. "github.com/bblfsh/sdk/v3/uast/nodes"
 	"github.com/bblfsh/sdk/v3/uast/role"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
 )
 
 var casesYML = []struct {
@@ -167,3 +170,77 @@ func TestStringKind(t *testing.T) {
 		})
 	}
 }
+
+func TestDecoder(t *testing.T) {
+	const stream = `# leading comment
+---
+a: 1
+--- {b: 2}
+---
+# only a comment
+---
+c: [
+...
+d: 4
+---
+- 5
+`
+	dec := NewDecoder(strings.NewReader(stream))
+
+	n, err := dec.Decode()
+	require.NoError(t, err)
+	require.Equal(t, Object{"a": Int(1)}, n)
+
+	n, err = dec.Decode()
+	require.NoError(t, err)
+	require.Equal(t, Object{"b": Int(2)}, n)
+
+	_, err = dec.Decode()
+	require.Error(t, err)
+	e, ok := err.(*Error)
+	require.True(t, ok, "%T", err)
+	require.Equal(t, 2, e.Document)
+	require.Equal(t, 8, e.Line)
+
+	// decoder continues with the next document after an error
+	n, err = dec.Decode()
+	require.NoError(t, err)
+	require.Equal(t, Object{"d": Int(4)}, n)
+
+	n, err = dec.Decode()
+	require.NoError(t, err)
+	require.Equal(t, Array{Int(5)}, n)
+
+	_, err = dec.Decode()
+	require.Equal(t, io.EOF, err)
+}
+
+func TestDecoderKeys(t *testing.T) {
+	dec := NewDecoder(strings.NewReader("a: 1\n---\n1: 2\n"))
+	_, err := dec.Decode()
+	require.NoError(t, err)
+	_, err = dec.Decode()
+	require.Error(t, err)
+	require.Equal(t, 1, err.(*Error).Document)
+
+	_, err = Unmarshal([]byte("1: 2\n"))
+	require.Error(t, err)
+}
+
+func TestDecoderTypeError(t *testing.T) {
+	dec := NewDecoder(strings.NewReader("a: 1\n---\nb: 2\n"))
+	_, err := dec.Decode()
+	require.NoError(t, err)
+	_, err = dec.Decode()
+	require.NoError(t, err)
+
+	// the second document starts at line 2 with a blanked marker
+	e := dec.errorf(&yaml.TypeError{Errors: []string{
+		"line 2: cannot unmarshal !!str `x` into int",
+		"line 4: cannot unmarshal !!seq into string",
+	}})
+	require.Equal(t, &Error{
+		Document: 1, Line: 3,
+		Msg: "cannot unmarshal !!str `x` into int; line 5: cannot unmarshal !!seq into string",
+	}, e)
+}
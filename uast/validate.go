@@ -0,0 +1,216 @@
+package uast
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+)
+
+// ValidationError describes a node that does not match the schema of a registered UAST type. See Validate.
+type ValidationError struct {
+	// Path is a location of the invalid node in the tree.
+	Path nodes.Path
+	// Type is a UAST type of the object that contains the invalid node.
+	Type string
+	// Msg is a description of the problem.
+	Msg string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s: %s", e.Path, e.Type, e.Msg)
+}
+
+// Validate checks all objects of the tree that have a type registered via RegisterPackage against the
+// corresponding Go type. It reports missing and unknown fields, fields of an incorrect kind, nested objects
+// of an incorrect type, and numeric values that overflow the Go field.
+//
+// Objects with unregistered types are not checked, but their children are still validated.
+// Validate returns an empty slice if the tree is valid. All errors are of type *ValidationError.
+func Validate(root nodes.External) []error {
+	v := &validator{}
+	v.node(nil, root)
+	return v.errs
+}
+
+type validator struct {
+	errs []error
+}
+
+func (v *validator) errorf(path nodes.Path, typ string, format string, args ...interface{}) {
+	v.errs = append(v.errs, &ValidationError{
+		Path: path, Type: typ,
+		Msg: fmt.Sprintf(format, args...),
+	})
+}
+
+// node validates a node of an unknown type.
+func (v *validator) node(path nodes.Path, n nodes.External) {
+	switch nodes.KindOf(n) {
+	case nodes.KindObject:
+		obj, ok := n.(nodes.ExternalObject)
+		if !ok {
+			return
+		}
+		typ := TypeOf(obj)
+		if rt, ok := LookupType(typ); ok {
+			v.object(path, typ, obj, rt)
+			return
+		}
+		for _, k := range obj.Keys() {
+			sub, _ := obj.ValueAt(k)
+			v.node(path.Join(k), sub)
+		}
+	case nodes.KindArray:
+		arr, ok := n.(nodes.ExternalArray)
+		if !ok {
+			return
+		}
+		for i, sz := 0, arr.Size(); i < sz; i++ {
+			v.node(path.Join(i), arr.ValueAt(i))
+		}
+	}
+}
+
+// object validates an object of a registered type.
+func (v *validator) object(path nodes.Path, typ string, obj nodes.ExternalObject, rt reflect.Type) {
+	if rt.Kind() == reflect.Map {
+		for _, k := range obj.Keys() {
+			if k == KeyType {
+				continue
+			}
+			sub, _ := obj.ValueAt(k)
+			v.value(path.Join(k), typ, sub, rt.Elem())
+		}
+		return
+	}
+	fields, err := appendFields(nil, rt)
+	if err != nil {
+		v.errorf(path, typ, "%v", err)
+		return
+	}
+	known := make(map[string]struct{}, len(fields))
+	for _, f := range fields {
+		known[f.Name] = struct{}{}
+		sub, ok := obj.ValueAt(f.Name)
+		if !ok {
+			if !f.Optional {
+				v.errorf(path, typ, "missing field %q", f.Name)
+			}
+			continue
+		}
+		v.value(path.Join(f.Name), typ, sub, f.Type)
+	}
+	for _, k := range obj.Keys() {
+		switch k {
+		case KeyType, KeyRoles, KeyToken:
+			continue
+		}
+		if _, ok := known[k]; !ok {
+			v.errorf(path, typ, "unknown field %q", k)
+		}
+	}
+}
+
+// value validates a field value of a registered type typ against a Go type of the field.
+func (v *validator) value(path nodes.Path, typ string, n nodes.External, rt reflect.Type) {
+	kind := nodes.KindOf(n)
+	switch rt.Kind() {
+	case reflect.Ptr:
+		if kind != nodes.KindNil {
+			v.value(path, typ, n, rt.Elem())
+		}
+	case reflect.Interface:
+		// any node
+		v.node(path, n)
+	case reflect.String:
+		if kind != nodes.KindString {
+			v.errorf(path, typ, "expected string, got %v", kind)
+		}
+	case reflect.Bool:
+		if kind != nodes.KindBool {
+			v.errorf(path, typ, "expected bool, got %v", kind)
+		}
+	case reflect.Int, reflect.Int64, reflect.Int32, reflect.Int16, reflect.Int8:
+		if kind != nodes.KindInt && kind != nodes.KindUint {
+			v.errorf(path, typ, "expected int, got %v", kind)
+		} else if !fitsInt(n.Value(), rt) {
+			v.errorf(path, typ, "value %v overflows %v", n.Value(), rt)
+		}
+	case reflect.Uint, reflect.Uint64, reflect.Uint32, reflect.Uint16, reflect.Uint8:
+		if kind != nodes.KindInt && kind != nodes.KindUint {
+			v.errorf(path, typ, "expected uint, got %v", kind)
+		} else if !fitsUint(n.Value(), rt) {
+			v.errorf(path, typ, "value %v overflows %v", n.Value(), rt)
+		}
+	case reflect.Float32, reflect.Float64:
+		switch kind {
+		case nodes.KindFloat, nodes.KindInt, nodes.KindUint:
+		default:
+			v.errorf(path, typ, "expected float, got %v", kind)
+		}
+	case reflect.Slice, reflect.Array:
+		if kind == nodes.KindNil {
+			return
+		} else if kind != nodes.KindArray {
+			v.errorf(path, typ, "expected array, got %v", kind)
+			return
+		}
+		arr, ok := n.(nodes.ExternalArray)
+		if !ok {
+			return
+		}
+		for i, sz := 0, arr.Size(); i < sz; i++ {
+			v.value(path.Join(i), typ, arr.ValueAt(i), rt.Elem())
+		}
+	case reflect.Struct, reflect.Map:
+		if kind == nodes.KindNil {
+			// zero value of a struct or a map, the same as in NodeAs
+			return
+		} else if kind != nodes.KindObject {
+			v.errorf(path, typ, "expected object, got %v", kind)
+			return
+		}
+		obj, ok := n.(nodes.ExternalObject)
+		if !ok {
+			return
+		}
+		id := typeOf(rt)
+		if _, ok := name2type[id]; !ok {
+			// unregistered types cannot be checked
+			v.node(path, n)
+			return
+		}
+		etyp := id.String()
+		if got := TypeOf(obj); got != etyp {
+			v.errorf(path, typ, "expected %s object, got %q", etyp, got)
+			return
+		}
+		v.object(path, etyp, obj, rt)
+	default:
+		v.errorf(path, typ, "unsupported field type: %v", rt)
+	}
+}
+
+// fitsInt checks if an integer value can be stored in a signed Go type.
+func fitsInt(val nodes.Value, rt reflect.Type) bool {
+	switch val := val.(type) {
+	case nodes.Int:
+		return !reflect.Zero(rt).OverflowInt(int64(val))
+	case nodes.Uint:
+		return val <= math.MaxInt64 && !reflect.Zero(rt).OverflowInt(int64(val))
+	}
+	return false
+}
+
+// fitsUint checks if an integer value can be stored in an unsigned Go type.
+func fitsUint(val nodes.Value, rt reflect.Type) bool {
+	switch val := val.(type) {
+	case nodes.Int:
+		return val >= 0 && !reflect.Zero(rt).OverflowUint(uint64(val))
+	case nodes.Uint:
+		return !reflect.Zero(rt).OverflowUint(uint64(val))
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package uast_test
+
+import (
+	"testing"
+
+	. "github.com/bblfsh/sdk/v3/uast"
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+	"github.com/stretchr/testify/require"
+)
+
+func validFunction(t testing.TB) nodes.Object {
+	x, err := ToNode(Identifier{Name: "x"})
+	require.NoError(t, err)
+	n, err := ToNode(Alias{
+		Name: Identifier{Name: "f"},
+		Node: Function{
+			Type: FunctionType{
+				Arguments: []Argument{
+					{Name: &Identifier{Name: "a"}},
+				},
+			},
+			Body: &Block{Statements: []Any{
+				nodes.Object{KeyType: nodes.String("native"), "x": x},
+			}},
+		},
+		GenNode: GenNode{Positions: Positions{
+			KeyStart: {Offset: 0, Line: 1, Col: 1},
+		}},
+	})
+	require.NoError(t, err)
+	return n.(nodes.Object)
+}
+
+var casesValidate = []struct {
+	name string
+	path string
+	val  nodes.Node
+	del  bool
+	errs []string
+}{
+	{
+		name: "missing field",
+		path: "/Name/Name", del: true,
+		errs: []string{`/Name: uast:Identifier: missing field "Name"`},
+	},
+	{
+		name: "wrong kind",
+		path: "/Node/Type/Arguments/0/Variadic", val: nodes.String("yes"),
+		errs: []string{`/Node/Type/Arguments/0/Variadic: uast:Argument: expected bool, got String`},
+	},
+	{
+		name: "wrong nested type",
+		path: "/Node/Body", val: nodes.Object{KeyType: nodes.String("uast:Group")},
+		errs: []string{`/Node/Body: uast:Function: expected uast:Block object, got "uast:Group"`},
+	},
+	{
+		name: "nil struct",
+		path: "/Node/Type", val: nil,
+	},
+	{
+		name: "struct as array",
+		path: "/Node/Type", val: nodes.Array{},
+		errs: []string{`/Node/Type: uast:Function: expected object, got Array`},
+	},
+	{
+		name: "unknown field",
+		path: "/Node/Body/Stmts", val: nodes.Array{},
+		errs: []string{`/Node/Body: uast:Block: unknown field "Stmts"`},
+	},
+	{
+		name: "negative position",
+		path: "/@pos/start/line", val: nodes.Int(-1),
+		errs: []string{`/@pos/start/line: uast:Position: value -1 overflows uint32`},
+	},
+	{
+		name: "in native node",
+		path: "/Node/Body/Statements/0/x/Name", val: nodes.Int(1),
+		errs: []string{`/Node/Body/Statements/0/x/Name: uast:Identifier: expected string, got Int`},
+	},
+	{
+		name: "nil pointer",
+		path: "/Node/Body", val: nil,
+	},
+}
+
+func TestValidate(t *testing.T) {
+	require.Empty(t, Validate(validFunction(t)))
+
+	for _, c := range casesValidate {
+		t.Run(c.name, func(t *testing.T) {
+			p, err := nodes.ParsePath(c.path)
+			require.NoError(t, err)
+			n := validFunction(t)
+			if c.del {
+				n, err = n.DeletePath(p)
+			} else {
+				n, err = n.SetPath(p, c.val)
+			}
+			require.NoError(t, err)
+
+			var errs []string
+			for _, err := range Validate(n) {
+				_, ok := err.(*ValidationError)
+				require.True(t, ok)
+				errs = append(errs, err.Error())
+			}
+			require.Equal(t, c.errs, errs)
+		})
+	}
+}
+
+func TestValidateZero(t *testing.T) {
+	for _, ns := range Namespaces() {
+		for _, typ := range TypesOf(ns) {
+			obj := NewObjectByType(typ)
+			require.Empty(t, Validate(obj), "%s: %v", typ, obj)
+		}
+	}
+}